@@ -1,18 +1,30 @@
 package ast
 
 // Assignable returns if the given expr is assignable.
-//    x = b
-//    x.a = b
+//
+//	x = b
+//	x.a = b
+//	[x, y] = b
 func Assignable(node Node, isDeclaration bool) (Node, bool) {
 	switch node := node.(type) {
 	case *AssignmentExpression:
 		return Assignable(node.Left, isDeclaration)
+	case *ParenExpression:
+		return Assignable(node.Expr, isDeclaration)
 	case *AttrExpression:
 		return node, !isDeclaration
 	case *IndexExpression:
 		return node, !isDeclaration
 	case *IdentifierLiteral:
 		return node, true
+	case *ArrayLiteral:
+		// destructuring -- every element has to be assignable.
+		for _, elem := range node.Elems {
+			if reason, ok := Assignable(elem, isDeclaration); !ok {
+				return reason, false
+			}
+		}
+		return node, true
 	}
 	return node, false
 }
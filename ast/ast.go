@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"jingle/scanner"
+	"math/big"
+	"strconv"
 	"strings"
 )
 
@@ -13,6 +15,7 @@ type Node interface {
 	Type() NodeType
 	String() string // used for debugging
 	GetToken() scanner.Token
+	Span() scanner.Span // the part of the input the node was parsed from
 }
 
 type Statement interface {
@@ -31,12 +34,19 @@ type Expression interface {
 
 type Program struct {
 	Token      scanner.Token
+	Filename   string
 	Statements []Statement
 }
 
 func (node *Program) statementNode()          {}
 func (node *Program) Type() NodeType          { return PROGRAM }
 func (node *Program) GetToken() scanner.Token { return node.Token }
+func (node *Program) Span() scanner.Span {
+	if len(node.Statements) == 0 {
+		return node.Token.Span()
+	}
+	return node.Statements[0].Span().Join(node.Statements[len(node.Statements)-1].Span())
+}
 func (node *Program) String() string {
 	var out bytes.Buffer
 	for _, stmt := range node.Statements {
@@ -50,6 +60,7 @@ type ExpressionStatement struct{ Expr Expression }
 func (node *ExpressionStatement) statementNode()          {}
 func (node *ExpressionStatement) Type() NodeType          { return EXPRESSION_STATEMENT }
 func (node *ExpressionStatement) GetToken() scanner.Token { return node.Expr.GetToken() }
+func (node *ExpressionStatement) Span() scanner.Span      { return node.Expr.Span() }
 func (node *ExpressionStatement) String() string {
 	return node.Expr.String() + ";"
 }
@@ -62,6 +73,7 @@ type LetStatement struct {
 func (node *LetStatement) statementNode()          {}
 func (node *LetStatement) Type() NodeType          { return LET_STATEMENT }
 func (node *LetStatement) GetToken() scanner.Token { return node.Token }
+func (node *LetStatement) Span() scanner.Span      { return node.Token.Span().Join(node.Binding.Span()) }
 func (node *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(node.Token.Value)
@@ -71,7 +83,8 @@ func (node *LetStatement) String() string {
 }
 
 type ForStatement struct {
-	Token    scanner.Token // the 'for' token
+	Token    scanner.Token      // the 'for' token
+	Label    *IdentifierLiteral // nil if the loop is not labeled
 	Binding  Expression
 	Iterable Expression
 	Body     *Block
@@ -80,8 +93,16 @@ type ForStatement struct {
 func (node *ForStatement) statementNode()          {}
 func (node *ForStatement) Type() NodeType          { return FOR_STATEMENT }
 func (node *ForStatement) GetToken() scanner.Token { return node.Token }
+func (node *ForStatement) Span() scanner.Span {
+	span := node.Token.Span().Join(node.Body.Span())
+	if node.Label != nil {
+		span = span.Join(node.Label.Span())
+	}
+	return span
+}
 func (node *ForStatement) String() string {
 	var out bytes.Buffer
+	writeLabel(&out, node.Label)
 	out.WriteString(node.Token.Value)
 	out.WriteString(" ")
 	out.WriteString(node.Binding.String())
@@ -93,7 +114,8 @@ func (node *ForStatement) String() string {
 }
 
 type WhileStatement struct {
-	Token     scanner.Token // the 'while' token
+	Token     scanner.Token      // the 'while' token
+	Label     *IdentifierLiteral // nil if the loop is not labeled
 	Condition Expression
 	Body      *Block
 }
@@ -101,12 +123,20 @@ type WhileStatement struct {
 func (node *WhileStatement) statementNode()          {}
 func (node *WhileStatement) Type() NodeType          { return WHILE_STATEMENT }
 func (node *WhileStatement) GetToken() scanner.Token { return node.Token }
+func (node *WhileStatement) Span() scanner.Span {
+	span := node.Token.Span().Join(node.Body.Span())
+	if node.Label != nil {
+		span = span.Join(node.Label.Span())
+	}
+	return span
+}
 func (node *WhileStatement) String() string {
 	var out bytes.Buffer
+	writeLabel(&out, node.Label)
 	out.WriteString(node.Token.Value)
 	out.WriteString(" ")
 	out.WriteString(node.Condition.String())
-	out.WriteString(" ")
+	out.WriteString(" do")
 	out.WriteString(node.Body.String())
 	return out.String()
 }
@@ -119,6 +149,13 @@ type Block struct {
 func (node *Block) statementNode()          {}
 func (node *Block) Type() NodeType          { return BLOCK_STATEMENT }
 func (node *Block) GetToken() scanner.Token { return node.Terminal }
+func (node *Block) Span() scanner.Span {
+	span := node.Terminal.Span()
+	if len(node.Statements) > 0 {
+		span = span.Join(node.Statements[0].Span())
+	}
+	return span
+}
 func (node *Block) String() string {
 	var out bytes.Buffer
 	out.WriteString(" ")
@@ -140,6 +177,13 @@ type IfStatement struct {
 func (node *IfStatement) statementNode()          {}
 func (node *IfStatement) Type() NodeType          { return IF_STATEMENT }
 func (node *IfStatement) GetToken() scanner.Token { return node.Token }
+func (node *IfStatement) Span() scanner.Span {
+	span := node.Token.Span().Join(node.Then.Span())
+	if node.Else != nil {
+		span = span.Join(node.Else.Span())
+	}
+	return span
+}
 func (node *IfStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(node.Token.Value)
@@ -162,11 +206,13 @@ type MethodDeclaration struct {
 	MethodName MethodName
 	Params     []*IdentifierLiteral
 	Body       *Block
+	Doc        string // text of the preceding /// comments
 }
 
 func (node *MethodDeclaration) statementNode()          {}
 func (node *MethodDeclaration) Type() NodeType          { return METHOD_DECLARATION }
 func (node *MethodDeclaration) GetToken() scanner.Token { return node.Token }
+func (node *MethodDeclaration) Span() scanner.Span      { return node.Token.Span().Join(node.Body.Span()) }
 func (node *MethodDeclaration) String() string {
 	var out bytes.Buffer
 	params := []string{}
@@ -188,11 +234,13 @@ type ClassStatement struct {
 	Name       *IdentifierLiteral
 	SuperClass Expression
 	Body       *Block
+	Doc        string // text of the preceding /// comments
 }
 
 func (node *ClassStatement) statementNode()          {}
 func (node *ClassStatement) Type() NodeType          { return CLASS_STATEMENT }
 func (node *ClassStatement) GetToken() scanner.Token { return node.Token }
+func (node *ClassStatement) Span() scanner.Span      { return node.Token.Span().Join(node.Body.Span()) }
 func (node *ClassStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(node.Token.Value)
@@ -214,6 +262,7 @@ type ReturnStatement struct {
 func (node *ReturnStatement) statementNode()          {}
 func (node *ReturnStatement) Type() NodeType          { return RETURN_STATEMENT }
 func (node *ReturnStatement) GetToken() scanner.Token { return node.Token }
+func (node *ReturnStatement) Span() scanner.Span      { return node.Token.Span().Join(node.Expr.Span()) }
 func (node *ReturnStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(node.Token.Value)
@@ -222,6 +271,151 @@ func (node *ReturnStatement) String() string {
 	return out.String()
 }
 
+// CatchClause is a single `catch` of a try statement. Both the
+// binding and the class filter are optional:
+//
+//	catch e: IOError
+type CatchClause struct {
+	Token   scanner.Token      // the 'catch' token
+	Binding *IdentifierLiteral // nil if the error is not bound
+	Class   Expression         // nil if every error is caught
+	Body    *Block
+}
+
+func (c *CatchClause) String() string {
+	var out bytes.Buffer
+	out.WriteString(c.Token.Value)
+	if c.Binding != nil {
+		out.WriteString(" ")
+		out.WriteString(c.Binding.String())
+	}
+	if c.Class != nil {
+		out.WriteString(": ")
+		out.WriteString(c.Class.String())
+	}
+	out.WriteString(c.Body.String())
+	return out.String()
+}
+
+type TryStatement struct {
+	Token   scanner.Token // the 'try' token
+	Body    *Block
+	Catches []*CatchClause
+	Finally *Block // nil if there is no finally block
+}
+
+func (node *TryStatement) statementNode()          {}
+func (node *TryStatement) Type() NodeType          { return TRY_STATEMENT }
+func (node *TryStatement) GetToken() scanner.Token { return node.Token }
+func (node *TryStatement) Span() scanner.Span {
+	span := node.Token.Span().Join(node.Body.Span())
+	for _, c := range node.Catches {
+		span = span.Join(c.Body.Span())
+	}
+	if node.Finally != nil {
+		span = span.Join(node.Finally.Span())
+	}
+	return span
+}
+func (node *TryStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(node.Token.Value)
+	out.WriteString(node.Body.String())
+	for _, c := range node.Catches {
+		out.WriteString(" ")
+		out.WriteString(c.String())
+	}
+	if node.Finally != nil {
+		out.WriteString(node.Finally.String())
+	}
+	return out.String()
+}
+
+type RaiseStatement struct {
+	Token scanner.Token // the 'raise' token
+	Expr  Expression
+}
+
+func (node *RaiseStatement) statementNode()          {}
+func (node *RaiseStatement) Type() NodeType          { return RAISE_STATEMENT }
+func (node *RaiseStatement) GetToken() scanner.Token { return node.Token }
+func (node *RaiseStatement) Span() scanner.Span      { return node.Token.Span().Join(node.Expr.Span()) }
+func (node *RaiseStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(node.Token.Value)
+	out.WriteString(" ")
+	out.WriteString(node.Expr.String())
+	return out.String()
+}
+
+// BreakStatement exits the innermost loop, or the loop with the
+// given label.
+type BreakStatement struct {
+	Token scanner.Token      // the 'break' token
+	Label *IdentifierLiteral // nil for the innermost loop
+}
+
+func (node *BreakStatement) statementNode()          {}
+func (node *BreakStatement) Type() NodeType          { return BREAK_STATEMENT }
+func (node *BreakStatement) GetToken() scanner.Token { return node.Token }
+func (node *BreakStatement) Span() scanner.Span {
+	if node.Label == nil {
+		return node.Token.Span()
+	}
+	return node.Token.Span().Join(node.Label.Span())
+}
+func (node *BreakStatement) String() string {
+	if node.Label == nil {
+		return node.Token.Value
+	}
+	return node.Token.Value + " " + node.Label.String()
+}
+
+// ContinueStatement skips to the next iteration of the innermost
+// loop, or of the loop with the given label.
+type ContinueStatement struct {
+	Token scanner.Token      // the 'continue' token
+	Label *IdentifierLiteral // nil for the innermost loop
+}
+
+func (node *ContinueStatement) statementNode()          {}
+func (node *ContinueStatement) Type() NodeType          { return CONTINUE_STATEMENT }
+func (node *ContinueStatement) GetToken() scanner.Token { return node.Token }
+func (node *ContinueStatement) Span() scanner.Span {
+	if node.Label == nil {
+		return node.Token.Span()
+	}
+	return node.Token.Span().Join(node.Label.Span())
+}
+func (node *ContinueStatement) String() string {
+	if node.Label == nil {
+		return node.Token.Value
+	}
+	return node.Token.Value + " " + node.Label.String()
+}
+
+// writeLabel writes the `label: ` prefix of a loop, if it has one.
+func writeLabel(out *bytes.Buffer, label *IdentifierLiteral) {
+	if label != nil {
+		out.WriteString(label.String())
+		out.WriteString(": ")
+	}
+}
+
+// ErrorStatement stands in for a statement that could not be
+// parsed. The parser resynchronises after it, so the rest of the
+// program can still be walked.
+type ErrorStatement struct {
+	Token scanner.Token // the first token of the broken statement
+	Msg   string        // the error that caused this statement to be dropped
+}
+
+func (node *ErrorStatement) statementNode()          {}
+func (node *ErrorStatement) Type() NodeType          { return ERROR_STATEMENT }
+func (node *ErrorStatement) GetToken() scanner.Token { return node.Token }
+func (node *ErrorStatement) Span() scanner.Span      { return node.Token.Span() }
+func (node *ErrorStatement) String() string          { return "<error>" }
+
 // ===========================
 // Expressions
 // ===========================
@@ -235,6 +429,7 @@ type PrefixExpression struct {
 func (node *PrefixExpression) expressionNode()         {}
 func (node *PrefixExpression) Type() NodeType          { return PREFIX_EXPRESSION }
 func (node *PrefixExpression) GetToken() scanner.Token { return node.Token }
+func (node *PrefixExpression) Span() scanner.Span      { return node.Token.Span().Join(node.Expr.Span()) }
 func (node *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -254,6 +449,7 @@ type InfixExpression struct {
 func (node *InfixExpression) expressionNode()         {}
 func (node *InfixExpression) Type() NodeType          { return INFIX_EXPRESSION }
 func (node *InfixExpression) GetToken() scanner.Token { return node.Token }
+func (node *InfixExpression) Span() scanner.Span      { return node.Left.Span().Join(node.Right.Span()) }
 func (node *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -273,6 +469,9 @@ type AssignmentExpression struct {
 func (node *AssignmentExpression) expressionNode()         {}
 func (node *AssignmentExpression) Type() NodeType          { return ASSIGNMENT_EXPRESSION }
 func (node *AssignmentExpression) GetToken() scanner.Token { return node.Token }
+func (node *AssignmentExpression) Span() scanner.Span {
+	return node.Left.Span().Join(node.Right.Span())
+}
 func (node *AssignmentExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -295,6 +494,7 @@ type OrExpression struct {
 func (node *OrExpression) expressionNode()         {}
 func (node *OrExpression) Type() NodeType          { return OR_EXPRESSION }
 func (node *OrExpression) GetToken() scanner.Token { return node.Token }
+func (node *OrExpression) Span() scanner.Span      { return node.Left.Span().Join(node.Right.Span()) }
 func (node *OrExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -315,6 +515,7 @@ type AndExpression struct {
 func (node *AndExpression) expressionNode()         {}
 func (node *AndExpression) Type() NodeType          { return AND_EXPRESSION }
 func (node *AndExpression) GetToken() scanner.Token { return node.Token }
+func (node *AndExpression) Span() scanner.Span      { return node.Left.Span().Join(node.Right.Span()) }
 func (node *AndExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -334,6 +535,7 @@ type AttrExpression struct {
 func (node *AttrExpression) expressionNode()         {}
 func (node *AttrExpression) Type() NodeType          { return ATTR_EXPRESSION }
 func (node *AttrExpression) GetToken() scanner.Token { return node.Token }
+func (node *AttrExpression) Span() scanner.Span      { return node.Target.Span().Join(node.Name.Span()) }
 func (node *AttrExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -345,14 +547,18 @@ func (node *AttrExpression) String() string {
 }
 
 type IndexExpression struct {
-	Token  scanner.Token // the '[' token
-	Target Expression
-	Args   []Expression
+	Token    scanner.Token // the '[' token
+	Target   Expression
+	Args     []Expression
+	RBracket scanner.Token // the ']' token
 }
 
 func (node *IndexExpression) expressionNode()         {}
 func (node *IndexExpression) Type() NodeType          { return INDEX_EXPRESSION }
 func (node *IndexExpression) GetToken() scanner.Token { return node.Token }
+func (node *IndexExpression) Span() scanner.Span {
+	return node.Target.Span().Join(node.RBracket.Span())
+}
 func (node *IndexExpression) String() string {
 	var out bytes.Buffer
 	args := []string{}
@@ -372,11 +578,13 @@ type CallExpression struct {
 	Token  scanner.Token // the '(' token
 	Target Expression
 	Args   []Expression
+	RParen scanner.Token // the ')' token
 }
 
 func (node *CallExpression) expressionNode()         {}
 func (node *CallExpression) Type() NodeType          { return CALL_EXPRESSION }
 func (node *CallExpression) GetToken() scanner.Token { return node.Token }
+func (node *CallExpression) Span() scanner.Span      { return node.Target.Span().Join(node.RParen.Span()) }
 func (node *CallExpression) String() string {
 	var out bytes.Buffer
 	args := []string{}
@@ -390,6 +598,31 @@ func (node *CallExpression) String() string {
 	return out.String()
 }
 
+// ParenExpression is an expression in parentheses. It evaluates to
+// the same value as Expr, but keeps the span of the parentheses.
+type ParenExpression struct {
+	Token  scanner.Token // the '(' token
+	Expr   Expression
+	RParen scanner.Token // the ')' token
+}
+
+func (node *ParenExpression) expressionNode()         {}
+func (node *ParenExpression) Type() NodeType          { return PAREN_EXPRESSION }
+func (node *ParenExpression) GetToken() scanner.Token { return node.Token }
+func (node *ParenExpression) Span() scanner.Span      { return node.Token.Span().Join(node.RParen.Span()) }
+func (node *ParenExpression) String() string          { return node.Expr.String() }
+
+// Unparen returns expr without any enclosing parentheses.
+func Unparen(expr Expression) Expression {
+	for {
+		paren, ok := expr.(*ParenExpression)
+		if !ok {
+			return expr
+		}
+		expr = paren.Expr
+	}
+}
+
 type IfElseExpression struct {
 	Token scanner.Token // the 'if' token
 	Cond  Expression
@@ -400,6 +633,13 @@ type IfElseExpression struct {
 func (node *IfElseExpression) expressionNode()         {}
 func (node *IfElseExpression) Type() NodeType          { return IF_ELSE_EXPRESSION }
 func (node *IfElseExpression) GetToken() scanner.Token { return node.Token }
+func (node *IfElseExpression) Span() scanner.Span {
+	span := node.Then.Span().Join(node.Cond.Span())
+	if node.Else != nil {
+		span = span.Join(node.Else.Span())
+	}
+	return span
+}
 func (node *IfElseExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -427,6 +667,7 @@ type NilLiteral struct {
 func (node *NilLiteral) expressionNode()         {}
 func (node *NilLiteral) Type() NodeType          { return NIL_LITERAL }
 func (node *NilLiteral) GetToken() scanner.Token { return node.Token }
+func (node *NilLiteral) Span() scanner.Span      { return node.Token.Span() }
 func (node *NilLiteral) String() string          { return node.Token.Value }
 
 type BooleanLiteral struct {
@@ -437,6 +678,7 @@ type BooleanLiteral struct {
 func (node *BooleanLiteral) expressionNode()         {}
 func (node *BooleanLiteral) Type() NodeType          { return BOOLEAN_LITERAL }
 func (node *BooleanLiteral) GetToken() scanner.Token { return node.Token }
+func (node *BooleanLiteral) Span() scanner.Span      { return node.Token.Span() }
 func (node *BooleanLiteral) String() string          { return node.Token.Value }
 
 type IdentifierLiteral struct {
@@ -446,20 +688,33 @@ type IdentifierLiteral struct {
 func (node *IdentifierLiteral) expressionNode()         {}
 func (node *IdentifierLiteral) Type() NodeType          { return IDENTIFIER_LITERAL }
 func (node *IdentifierLiteral) GetToken() scanner.Token { return node.Token }
+func (node *IdentifierLiteral) Span() scanner.Span      { return node.Token.Span() }
 func (node *IdentifierLiteral) String() string          { return node.Token.Value }
 func (node *IdentifierLiteral) Name() string {
 	return node.Token.Value
 }
 
-type NumberLiteral struct {
-	Token scanner.Token // number token
+type IntegerLiteral struct {
+	Token scanner.Token // integer token
+	Value *big.Int
+}
+
+func (node *IntegerLiteral) expressionNode()         {}
+func (node *IntegerLiteral) Type() NodeType          { return INTEGER_LITERAL }
+func (node *IntegerLiteral) GetToken() scanner.Token { return node.Token }
+func (node *IntegerLiteral) Span() scanner.Span      { return node.Token.Span() }
+func (node *IntegerLiteral) String() string          { return node.Token.Value }
+
+type FloatLiteral struct {
+	Token scanner.Token // float token
 	Value float64
 }
 
-func (node *NumberLiteral) expressionNode()         {}
-func (node *NumberLiteral) Type() NodeType          { return NUMBER_LITERAL }
-func (node *NumberLiteral) GetToken() scanner.Token { return node.Token }
-func (node *NumberLiteral) String() string          { return node.Token.Value }
+func (node *FloatLiteral) expressionNode()         {}
+func (node *FloatLiteral) Type() NodeType          { return FLOAT_LITERAL }
+func (node *FloatLiteral) GetToken() scanner.Token { return node.Token }
+func (node *FloatLiteral) Span() scanner.Span      { return node.Token.Span() }
+func (node *FloatLiteral) String() string          { return node.Token.Value }
 
 type StringLiteral struct {
 	Token scanner.Token // string token
@@ -469,17 +724,53 @@ type StringLiteral struct {
 func (node *StringLiteral) expressionNode()         {}
 func (node *StringLiteral) Type() NodeType          { return STRING_LITERAL }
 func (node *StringLiteral) GetToken() scanner.Token { return node.Token }
+func (node *StringLiteral) Span() scanner.Span      { return node.Token.Span() }
 func (node *StringLiteral) String() string          { return fmt.Sprintf("%q", node.Value) }
 
+// InterpolatedString is a string literal with embedded expressions:
+//
+//	"a ${x} b ${y} c"
+//
+// has Strings "a ", " b ", " c" and Exprs x, y. There is always one
+// more string than there are expressions.
+type InterpolatedString struct {
+	Token   scanner.Token // the TokenStringStart token
+	Strings []string
+	Exprs   []Expression
+	End     scanner.Token // the TokenStringEnd token
+}
+
+func (node *InterpolatedString) expressionNode()         {}
+func (node *InterpolatedString) Type() NodeType          { return INTERPOLATED_STRING }
+func (node *InterpolatedString) GetToken() scanner.Token { return node.Token }
+func (node *InterpolatedString) Span() scanner.Span      { return node.Token.Span().Join(node.End.Span()) }
+func (node *InterpolatedString) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(`"`)
+	for i, str := range node.Strings {
+		quoted := strconv.Quote(str)
+		buf.WriteString(strings.ReplaceAll(quoted[1:len(quoted)-1], "${", `\${`))
+		if i < len(node.Exprs) {
+			buf.WriteString("${")
+			buf.WriteString(node.Exprs[i].String())
+			buf.WriteString("}")
+		}
+	}
+	buf.WriteString(`"`)
+	return buf.String()
+}
+
 type FunctionLiteral struct {
 	Token  scanner.Token // the 'fn' token
 	Params []*IdentifierLiteral
 	Body   *Block
+	Doc    string // text of the /// comments before its let statement
 }
 
 func (node *FunctionLiteral) expressionNode()         {}
 func (node *FunctionLiteral) Type() NodeType          { return FUNCTION_LITERAL }
 func (node *FunctionLiteral) GetToken() scanner.Token { return node.Token }
+func (node *FunctionLiteral) Span() scanner.Span      { return node.Token.Span().Join(node.Body.Span()) }
 func (node *FunctionLiteral) String() string {
 	var buf bytes.Buffer
 	params := []string{}
@@ -496,13 +787,15 @@ func (node *FunctionLiteral) String() string {
 }
 
 type ArrayLiteral struct {
-	Token scanner.Token // the 'fn' token
-	Elems []Expression
+	Token    scanner.Token // the '[' token
+	Elems    []Expression
+	RBracket scanner.Token // the ']' token
 }
 
 func (node *ArrayLiteral) expressionNode()         {}
 func (node *ArrayLiteral) Type() NodeType          { return ARRAY_LITERAL }
 func (node *ArrayLiteral) GetToken() scanner.Token { return node.Token }
+func (node *ArrayLiteral) Span() scanner.Span      { return node.Token.Span().Join(node.RBracket.Span()) }
 func (node *ArrayLiteral) String() string {
 	var buf bytes.Buffer
 	elems := []string{}
@@ -515,3 +808,49 @@ func (node *ArrayLiteral) String() string {
 	buf.WriteString("]")
 	return buf.String()
 }
+
+// MapEntry is a `key: value` pair in a MapLiteral. Keys are
+// identifiers (standing for the string of the same name), string
+// literals, or arbitrary expressions in brackets if Computed.
+type MapEntry struct {
+	Key      Expression
+	Value    Expression
+	Computed bool
+}
+
+func (entry *MapEntry) String() string {
+	var buf bytes.Buffer
+	if entry.Computed {
+		buf.WriteString("[")
+		buf.WriteString(entry.Key.String())
+		buf.WriteString("]")
+	} else {
+		buf.WriteString(entry.Key.String())
+	}
+	buf.WriteString(": ")
+	buf.WriteString(entry.Value.String())
+	return buf.String()
+}
+
+type MapLiteral struct {
+	Token   scanner.Token // the '{' token
+	Entries []*MapEntry
+	RBrace  scanner.Token // the '}' token
+}
+
+func (node *MapLiteral) expressionNode()         {}
+func (node *MapLiteral) Type() NodeType          { return MAP_LITERAL }
+func (node *MapLiteral) GetToken() scanner.Token { return node.Token }
+func (node *MapLiteral) Span() scanner.Span      { return node.Token.Span().Join(node.RBrace.Span()) }
+func (node *MapLiteral) String() string {
+	var buf bytes.Buffer
+	entries := []string{}
+	for _, entry := range node.Entries {
+		entries = append(entries, entry.String())
+	}
+
+	buf.WriteString(node.Token.Value)
+	buf.WriteString(strings.Join(entries, ", "))
+	buf.WriteString("}")
+	return buf.String()
+}
@@ -1,6 +1,6 @@
 package ast
 
-//go:generate stringer -type=NodeType
+//go:generate go run golang.org/x/tools/cmd/stringer@v0.47.0 -type=NodeType
 
 type NodeType uint
 
@@ -17,6 +17,11 @@ const (
 	RETURN_STATEMENT
 	METHOD_DECLARATION
 	WHILE_STATEMENT
+	TRY_STATEMENT
+	RAISE_STATEMENT
+	BREAK_STATEMENT
+	CONTINUE_STATEMENT
+	ERROR_STATEMENT
 
 	// Expressions
 	PREFIX_EXPRESSION
@@ -28,13 +33,17 @@ const (
 	INDEX_EXPRESSION
 	CALL_EXPRESSION
 	IF_ELSE_EXPRESSION
+	PAREN_EXPRESSION
 
 	// Literals
 	NIL_LITERAL
 	BOOLEAN_LITERAL
 	IDENTIFIER_LITERAL
-	NUMBER_LITERAL
+	INTEGER_LITERAL
+	FLOAT_LITERAL
 	STRING_LITERAL
 	FUNCTION_LITERAL
 	ARRAY_LITERAL
+	MAP_LITERAL
+	INTERPOLATED_STRING
 )
@@ -0,0 +1,75 @@
+package ast
+
+import (
+	"jingle/scanner"
+	"math/big"
+	"reflect"
+)
+
+// Equal reports whether a and b are the same tree. Tokens are
+// compared by their type and value only, so trees parsed from
+// differently laid out inputs can be equal.
+func Equal(a, b Node) bool {
+	return equal(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+var (
+	tokenType  = reflect.TypeOf(scanner.Token{})
+	bigIntType = reflect.TypeOf((*big.Int)(nil))
+)
+
+func equal(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a.Type() {
+	case tokenType:
+		ta, tb := a.Interface().(scanner.Token), b.Interface().(scanner.Token)
+		return ta.Type == tb.Type && ta.Value == tb.Value
+	case bigIntType:
+		ia, ib := a.Interface().(*big.Int), b.Interface().(*big.Int)
+		if ia == nil || ib == nil {
+			return ia == ib
+		}
+		return ia.Cmp(ib) == 0
+	}
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return equal(a.Elem(), b.Elem())
+	case reflect.Slice:
+		// nil and empty slices are the same.
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equal(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !equal(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.String:
+		return a.String() == b.String()
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() == b.Uint()
+	}
+	return false
+}
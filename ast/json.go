@@ -0,0 +1,340 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"jingle/scanner"
+	"math/big"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The JSON encoding of a tree. Every node is an object with its kind,
+// the NodeType name, and its span, followed by its fields, in the
+// order they are declared, under their names with a lowercase first
+// letter:
+//
+//	{"kind": "LET_STATEMENT", "span": SPAN, "token": TOKEN, "binding": NODE}
+//
+// Missing nodes are null. Tokens are objects with their type, the
+// TokenType name, their value and their span; their comments are left
+// out. Spans are {"start": POS, "end": POS}, and positions are
+// {"offset": 0, "line": 1, "column": 1}. Integers are strings of
+// decimal digits, to keep their precision. Map entries, catch clauses
+// and method names are objects of their fields, without kind and span.
+
+var nodes = []Node{
+	&Program{},
+	&LetStatement{},
+	&ForStatement{},
+	&ExpressionStatement{},
+	&IfStatement{},
+	&Block{},
+	&ClassStatement{},
+	&ReturnStatement{},
+	&MethodDeclaration{},
+	&WhileStatement{},
+	&TryStatement{},
+	&RaiseStatement{},
+	&BreakStatement{},
+	&ContinueStatement{},
+	&ErrorStatement{},
+	&PrefixExpression{},
+	&InfixExpression{},
+	&AssignmentExpression{},
+	&OrExpression{},
+	&AndExpression{},
+	&AttrExpression{},
+	&IndexExpression{},
+	&CallExpression{},
+	&IfElseExpression{},
+	&ParenExpression{},
+	&NilLiteral{},
+	&BooleanLiteral{},
+	&IdentifierLiteral{},
+	&IntegerLiteral{},
+	&FloatLiteral{},
+	&StringLiteral{},
+	&FunctionLiteral{},
+	&ArrayLiteral{},
+	&MapLiteral{},
+	&InterpolatedString{},
+}
+
+var (
+	nodeType   = reflect.TypeOf((*Node)(nil)).Elem()
+	nodeKinds  = map[string]reflect.Type{} // NodeType name -> node pointer type
+	tokenTypes = map[string]scanner.TokenType{}
+)
+
+func init() {
+	for _, node := range nodes {
+		nodeKinds[node.Type().String()] = reflect.TypeOf(node)
+	}
+	for typ := scanner.TokenType(0); !strings.HasPrefix(typ.String(), "TokenType("); typ++ {
+		tokenTypes[typ.String()] = typ
+	}
+}
+
+type jsonPos struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonSpan struct {
+	Start jsonPos `json:"start"`
+	End   jsonPos `json:"end"`
+}
+
+type jsonToken struct {
+	Type  string   `json:"type"`
+	Value string   `json:"value"`
+	Span  jsonSpan `json:"span"`
+}
+
+func toJSONSpan(span scanner.Span) jsonSpan {
+	return jsonSpan{
+		Start: jsonPos{span.Start.Offset, span.Start.LineNo, span.Start.Column},
+		End:   jsonPos{span.End.Offset, span.End.LineNo, span.End.Column},
+	}
+}
+
+// jsonName returns the key of the field called name.
+func jsonName(name string) string {
+	r, n := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[n:]
+}
+
+// MarshalJSON returns the JSON encoding of node, see above.
+func MarshalJSON(node Node) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, reflect.ValueOf(node)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeJSON(buf *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+	switch v.Type() {
+	case tokenType:
+		tok := v.Interface().(scanner.Token)
+		return writeJSON(buf, jsonToken{tok.Type.String(), tok.Value, toJSONSpan(tok.Span())})
+	case bigIntType:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeJSON(buf, v.Interface().(*big.Int).String())
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			return encodeJSON(buf, v.Elem())
+		}
+		if node, ok := v.Interface().(Node); ok {
+			buf.WriteString(`{"kind":`)
+			writeJSON(buf, node.Type().String())
+			buf.WriteString(`,"span":`)
+			writeJSON(buf, toJSONSpan(node.Span()))
+			return encodeFields(buf, v.Elem(), true)
+		}
+		return encodeJSON(buf, v.Elem())
+	case reflect.Struct:
+		buf.WriteString("{")
+		return encodeFields(buf, v, false)
+	case reflect.Slice:
+		buf.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if err := encodeJSON(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("]")
+		return nil
+	}
+	return writeJSON(buf, v.Interface())
+}
+
+// encodeFields writes the exported fields of the struct v, and the
+// closing brace of its object. more tells whether there are keys
+// before them.
+func encodeFields(buf *bytes.Buffer, v reflect.Value, more bool) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		if more {
+			buf.WriteString(",")
+		}
+		more = true
+		writeJSON(buf, jsonName(field.Name))
+		buf.WriteString(":")
+		if err := encodeJSON(buf, v.Field(i)); err != nil {
+			return fmt.Errorf("%s: %w", field.Name, err)
+		}
+	}
+	buf.WriteString("}")
+	return nil
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	return nil
+}
+
+// UnmarshalJSON returns the node encoded in data by MarshalJSON.
+// Spans of nodes are ignored, as they follow from those of their
+// tokens.
+func UnmarshalJSON(data []byte) (Node, error) {
+	var node Node
+	if err := decodeJSON(data, reflect.ValueOf(&node).Elem()); err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, fmt.Errorf("expected a node, got null")
+	}
+	return node, nil
+}
+
+// decodeJSON decodes data into v, which has to be settable.
+func decodeJSON(data []byte, v reflect.Value) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	switch v.Type() {
+	case tokenType:
+		var tok jsonToken
+		if err := json.Unmarshal(data, &tok); err != nil {
+			return err
+		}
+		typ, ok := tokenTypes[tok.Type]
+		if !ok {
+			return fmt.Errorf("unknown token type %q", tok.Type)
+		}
+		v.Set(reflect.ValueOf(scanner.Token{
+			Type:      typ,
+			Value:     tok.Value,
+			LineNo:    tok.Span.Start.Line,
+			Column:    tok.Span.Start.Column,
+			Offset:    tok.Span.Start.Offset,
+			EndLineNo: tok.Span.End.Line,
+			EndColumn: tok.Span.End.Column,
+			EndOffset: tok.Span.End.Offset,
+		}))
+		return nil
+	case bigIntType:
+		var digits string
+		if err := json.Unmarshal(data, &digits); err != nil {
+			return err
+		}
+		n, ok := new(big.Int).SetString(digits, 10)
+		if !ok {
+			return fmt.Errorf("invalid integer %q", digits)
+		}
+		v.Set(reflect.ValueOf(n))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		var object struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return err
+		}
+		typ, ok := nodeKinds[object.Kind]
+		if !ok {
+			return fmt.Errorf("unknown node kind %q", object.Kind)
+		}
+		if !typ.Implements(v.Type()) {
+			return fmt.Errorf("expected %s, got %s", strings.ToLower(v.Type().Name()), object.Kind)
+		}
+		node := reflect.New(typ.Elem())
+		if err := decodeFields(data, node.Elem()); err != nil {
+			return err
+		}
+		v.Set(node)
+		return nil
+	case reflect.Ptr:
+		if v.Type().Implements(nodeType) {
+			// check the kind, by way of the interface.
+			var node Node
+			nv := reflect.ValueOf(&node).Elem()
+			if err := decodeJSON(data, nv); err != nil {
+				return err
+			}
+			if nv.Elem().Type() != v.Type() {
+				return fmt.Errorf("expected %s, got %s",
+					v.Interface().(Node).Type(), node.Type())
+			}
+			v.Set(nv.Elem())
+			return nil
+		}
+		ptr := reflect.New(v.Type().Elem())
+		if err := decodeJSON(data, ptr.Elem()); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	case reflect.Struct:
+		return decodeFields(data, v)
+	case reflect.Slice:
+		var elems []json.RawMessage
+		if err := json.Unmarshal(data, &elems); err != nil {
+			return err
+		}
+		slice := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+		for i, elem := range elems {
+			if err := decodeJSON(elem, slice.Index(i)); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return json.Unmarshal(data, v.Addr().Interface())
+}
+
+// decodeFields decodes the object in data into the exported fields
+// of the struct v. Missing fields are left alone.
+func decodeFields(data []byte, v reflect.Value) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		value, ok := object[jsonName(field.Name)]
+		if !ok {
+			continue
+		}
+		if err := decodeJSON(value, v.Field(i)); err != nil {
+			return fmt.Errorf("%s: %w", jsonName(field.Name), err)
+		}
+	}
+	return nil
+}
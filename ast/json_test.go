@@ -0,0 +1,114 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"jingle/ast"
+	"jingle/parser"
+	"jingle/scanner"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	inputs := []string{
+		"let x = 1",
+		"let [a, b] = [1, 2 ** 3]",
+		"for a, b in c do print(a, b) end",
+		"outer: while x < 10 do x = x + 1; break outer end",
+		"if a then b else c end",
+		"class A < B\n  def init(x) self.x = x end\n  def []=(i, v) nil end\nend",
+		"try raise E() catch e: E print(e) finally done() end",
+		"f = fn(a, b) return a and b or !c end",
+		"m = {a: 1, \"b\": -2.5, [k]: 100000000000000000000000}",
+		"s = \"x${y}z${w[0]}\"",
+		"v = a.b(1)[2] if (c) else nil",
+		"while x do break; continue end",
+		"outer: for i in 0..3 do inner: while true do continue outer; break inner end end",
+		"s = \"a${f(\"b${x + 1}c\")}d${[y, {k: z}][0]}e\"",
+		"",
+	}
+	for i, input := range inputs {
+		prog, errs := parser.NewSource("<test>", scanner.New("<test>", input)).Parse()
+		if errs != nil {
+			t.Fatalf("test[%d] failed to parse: %v", i, errs)
+		}
+		data, err := ast.MarshalJSON(prog)
+		if err != nil {
+			t.Fatalf("test[%d] failed to marshal: %v", i, err)
+		}
+		if !json.Valid(data) {
+			t.Fatalf("test[%d] invalid JSON: %s", i, data)
+		}
+		node, err := ast.UnmarshalJSON(data)
+		if err != nil {
+			t.Fatalf("test[%d] failed to unmarshal: %v", i, err)
+		}
+		if !ast.Equal(prog, node) {
+			t.Fatalf("test[%d] expected %s, got %s", i, prog, node)
+		}
+		again, err := ast.MarshalJSON(node)
+		if err != nil {
+			t.Fatalf("test[%d] failed to marshal again: %v", i, err)
+		}
+		if string(again) != string(data) {
+			t.Fatalf("test[%d] expected %s, got %s", i, data, again)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	prog, errs := parser.NewSource("<test>", scanner.New("<test>", "let x = 42")).Parse()
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	data, err := ast.MarshalJSON(prog.Statements[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"kind":"LET_STATEMENT",` +
+		`"span":{"start":{"offset":0,"line":1,"column":1},"end":{"offset":10,"line":1,"column":11}},` +
+		`"token":{"type":"TokenLet","value":"let","span":{"start":{"offset":0,"line":1,"column":1},"end":{"offset":3,"line":1,"column":4}}},` +
+		`"binding":{"kind":"ASSIGNMENT_EXPRESSION",` +
+		`"span":{"start":{"offset":4,"line":1,"column":5},"end":{"offset":10,"line":1,"column":11}},` +
+		`"token":{"type":"TokenSet","value":"=","span":{"start":{"offset":6,"line":1,"column":7},"end":{"offset":7,"line":1,"column":8}}},` +
+		`"left":{"kind":"IDENTIFIER_LITERAL",` +
+		`"span":{"start":{"offset":4,"line":1,"column":5},"end":{"offset":5,"line":1,"column":6}},` +
+		`"token":{"type":"TokenIdent","value":"x","span":{"start":{"offset":4,"line":1,"column":5},"end":{"offset":5,"line":1,"column":6}}}},` +
+		`"right":{"kind":"INTEGER_LITERAL",` +
+		`"span":{"start":{"offset":8,"line":1,"column":9},"end":{"offset":10,"line":1,"column":11}},` +
+		`"token":{"type":"TokenInteger","value":"42","span":{"start":{"offset":8,"line":1,"column":9},"end":{"offset":10,"line":1,"column":11}}},` +
+		`"value":"42"}}}`
+	if string(data) != expected {
+		t.Fatalf("expected %s, got %s", expected, data)
+	}
+}
+
+func TestJSONNodeKinds(t *testing.T) {
+	// every NodeType has to be decodable, and decode to its node.
+	for typ := ast.NodeType(1); !strings.HasPrefix(typ.String(), "NodeType("); typ++ {
+		node, err := ast.UnmarshalJSON([]byte(`{"kind": "` + typ.String() + `"}`))
+		if err != nil {
+			t.Fatalf("%s: %v", typ, err)
+		}
+		if node.Type() != typ {
+			t.Fatalf("%s: got %s", typ, node.Type())
+		}
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	inputs := []string{
+		`null`,
+		`{"kind": "NO_SUCH_NODE"}`,
+		`{"kind": "LET_STATEMENT", "binding": {"kind": "PROGRAM"}}`,
+		`{"kind": "FOR_STATEMENT", "body": {"kind": "NIL_LITERAL"}}`,
+		`{"kind": "NIL_LITERAL", "token": {"type": "TokenNope"}}`,
+		`{"kind": "INTEGER_LITERAL", "value": "12x"}`,
+		`[]`,
+	}
+	for i, input := range inputs {
+		if _, err := ast.UnmarshalJSON([]byte(input)); err == nil {
+			t.Fatalf("test[%d] expected an error", i)
+		}
+	}
+}
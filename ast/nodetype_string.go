@@ -18,32 +18,41 @@ func _() {
 	_ = x[RETURN_STATEMENT-8]
 	_ = x[METHOD_DECLARATION-9]
 	_ = x[WHILE_STATEMENT-10]
-	_ = x[PREFIX_EXPRESSION-11]
-	_ = x[INFIX_EXPRESSION-12]
-	_ = x[ASSIGNMENT_EXPRESSION-13]
-	_ = x[OR_EXPRESSION-14]
-	_ = x[AND_EXPRESSION-15]
-	_ = x[ATTR_EXPRESSION-16]
-	_ = x[INDEX_EXPRESSION-17]
-	_ = x[CALL_EXPRESSION-18]
-	_ = x[IF_ELSE_EXPRESSION-19]
-	_ = x[NIL_LITERAL-20]
-	_ = x[BOOLEAN_LITERAL-21]
-	_ = x[IDENTIFIER_LITERAL-22]
-	_ = x[NUMBER_LITERAL-23]
-	_ = x[STRING_LITERAL-24]
-	_ = x[FUNCTION_LITERAL-25]
-	_ = x[ARRAY_LITERAL-26]
+	_ = x[TRY_STATEMENT-11]
+	_ = x[RAISE_STATEMENT-12]
+	_ = x[BREAK_STATEMENT-13]
+	_ = x[CONTINUE_STATEMENT-14]
+	_ = x[ERROR_STATEMENT-15]
+	_ = x[PREFIX_EXPRESSION-16]
+	_ = x[INFIX_EXPRESSION-17]
+	_ = x[ASSIGNMENT_EXPRESSION-18]
+	_ = x[OR_EXPRESSION-19]
+	_ = x[AND_EXPRESSION-20]
+	_ = x[ATTR_EXPRESSION-21]
+	_ = x[INDEX_EXPRESSION-22]
+	_ = x[CALL_EXPRESSION-23]
+	_ = x[IF_ELSE_EXPRESSION-24]
+	_ = x[PAREN_EXPRESSION-25]
+	_ = x[NIL_LITERAL-26]
+	_ = x[BOOLEAN_LITERAL-27]
+	_ = x[IDENTIFIER_LITERAL-28]
+	_ = x[INTEGER_LITERAL-29]
+	_ = x[FLOAT_LITERAL-30]
+	_ = x[STRING_LITERAL-31]
+	_ = x[FUNCTION_LITERAL-32]
+	_ = x[ARRAY_LITERAL-33]
+	_ = x[MAP_LITERAL-34]
+	_ = x[INTERPOLATED_STRING-35]
 }
 
-const _NodeType_name = "PROGRAMLET_STATEMENTFOR_STATEMENTEXPRESSION_STATEMENTIF_STATEMENTBLOCK_STATEMENTCLASS_STATEMENTRETURN_STATEMENTMETHOD_DECLARATIONWHILE_STATEMENTPREFIX_EXPRESSIONINFIX_EXPRESSIONASSIGNMENT_EXPRESSIONOR_EXPRESSIONAND_EXPRESSIONATTR_EXPRESSIONINDEX_EXPRESSIONCALL_EXPRESSIONIF_ELSE_EXPRESSIONNIL_LITERALBOOLEAN_LITERALIDENTIFIER_LITERALNUMBER_LITERALSTRING_LITERALFUNCTION_LITERALARRAY_LITERAL"
+const _NodeType_name = "PROGRAMLET_STATEMENTFOR_STATEMENTEXPRESSION_STATEMENTIF_STATEMENTBLOCK_STATEMENTCLASS_STATEMENTRETURN_STATEMENTMETHOD_DECLARATIONWHILE_STATEMENTTRY_STATEMENTRAISE_STATEMENTBREAK_STATEMENTCONTINUE_STATEMENTERROR_STATEMENTPREFIX_EXPRESSIONINFIX_EXPRESSIONASSIGNMENT_EXPRESSIONOR_EXPRESSIONAND_EXPRESSIONATTR_EXPRESSIONINDEX_EXPRESSIONCALL_EXPRESSIONIF_ELSE_EXPRESSIONPAREN_EXPRESSIONNIL_LITERALBOOLEAN_LITERALIDENTIFIER_LITERALINTEGER_LITERALFLOAT_LITERALSTRING_LITERALFUNCTION_LITERALARRAY_LITERALMAP_LITERALINTERPOLATED_STRING"
 
-var _NodeType_index = [...]uint16{0, 7, 20, 33, 53, 65, 80, 95, 111, 129, 144, 161, 177, 198, 211, 225, 240, 256, 271, 289, 300, 315, 333, 347, 361, 377, 390}
+var _NodeType_index = [...]uint16{0, 7, 20, 33, 53, 65, 80, 95, 111, 129, 144, 157, 172, 187, 205, 220, 237, 253, 274, 287, 301, 316, 332, 347, 365, 381, 392, 407, 425, 440, 453, 467, 483, 496, 507, 526}
 
 func (i NodeType) String() string {
-	i -= 1
-	if i >= NodeType(len(_NodeType_index)-1) {
-		return "NodeType(" + strconv.FormatInt(int64(i+1), 10) + ")"
+	idx := int(i) - 1
+	if i < 1 || idx >= len(_NodeType_index)-1 {
+		return "NodeType(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _NodeType_name[_NodeType_index[i]:_NodeType_index[i+1]]
+	return _NodeType_name[_NodeType_index[idx]:_NodeType_index[idx+1]]
 }
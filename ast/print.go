@@ -0,0 +1,89 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"jingle/scanner"
+	"reflect"
+	"strings"
+)
+
+// Frprint recursively prints value, usually a node, with one field
+// per line, indented by level.
+func Frprint(out io.Writer, value interface{}, level int) {
+	frprint(out, reflect.ValueOf(value), level)
+}
+
+func frprint(out io.Writer, v reflect.Value, level int) {
+	if !v.IsValid() {
+		io.WriteString(out, "nil")
+		return
+	}
+	if v.Type() == reflect.TypeOf(scanner.Token{}) && v.CanInterface() {
+		// compact formatting for token
+		fmt.Fprintf(out, "%s", v.Interface())
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			io.WriteString(out, "nil")
+			return
+		}
+		if v.Kind() == reflect.Ptr {
+			if opaque(v.Type().Elem()) && v.CanInterface() {
+				// e.g. *big.Int, which is best shown by its String method.
+				fmt.Fprint(out, v.Interface())
+				return
+			}
+			io.WriteString(out, "&")
+		}
+		frprint(out, v.Elem(), level)
+	case reflect.Slice:
+		indent := strings.Repeat("  ", level+1)
+		fmt.Fprintf(out, "%s[", v.Type())
+		last := v.Len() - 1
+		for i := 0; i < v.Len(); i++ {
+			fmt.Fprintf(out, "\n%s", indent)
+			frprint(out, v.Index(i), level+1)
+			if i != last {
+				io.WriteString(out, ",")
+			}
+		}
+		fmt.Fprintf(out, "\n%s]", strings.Repeat("  ", level))
+	case reflect.Struct:
+		indent := strings.Repeat("  ", level+1)
+		fmt.Fprintf(out, "%s{", v.Type())
+		t := v.Type()
+		first := true
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			// embedded and unexported fields are implementation details.
+			if field.Anonymous || field.PkgPath != "" {
+				continue
+			}
+			if !first {
+				io.WriteString(out, ",") // from the previous iteration
+			}
+			first = false
+			fmt.Fprintf(out, "\n%s%s: ", indent, field.Name)
+			frprint(out, v.Field(i), level+1)
+		}
+		fmt.Fprintf(out, "\n%s}", strings.Repeat("  ", level))
+	default:
+		fmt.Fprint(out, v)
+	}
+}
+
+// opaque reports whether t is a struct without exported fields.
+func opaque(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// complete returns the partial name at the end of prefix, and the
+// names it can be completed to. After a dot, these are the attributes
+// of the value of the dotted names before it, e.g. `a.b.c` completes
+// to the attributes of `a.b` that start with c; otherwise they are
+// the names in scope.
+func (r *repl) complete(prefix string) (string, []string) {
+	runes := []rune(prefix)
+	end := len(runes)
+	start := identStart(runes, end)
+	word := string(runes[start:end])
+
+	// collect the dotted names before the word, innermost first.
+	var path []string
+	for start > 0 && runes[start-1] == '.' {
+		end = start - 1
+		start = identStart(runes, end)
+		if start == end {
+			return word, nil // e.g. `"str".` or `f().`
+		}
+		path = append(path, string(runes[start:end]))
+	}
+
+	var names []string
+	if len(path) == 0 {
+		names = r.ev.Scope().Names()
+	} else {
+		val, ok := r.ev.Scope().Get(path[len(path)-1])
+		for i := len(path) - 2; ok && i >= 0; i-- {
+			val, ok = r.ev.LookupAttr(val, path[i])
+		}
+		if !ok {
+			return word, nil
+		}
+		names = r.ev.Attrs(val)
+	}
+	return word, filterPrefix(names, word)
+}
+
+// identStart returns the index where the identifier ending at end
+// starts, which is end itself if there is none. As in the scanner,
+// identifiers may end in primes, as in x'.
+func identStart(runes []rune, end int) int {
+	start := end
+	for start > 0 && runes[start-1] == '\'' {
+		start--
+	}
+	primes := start
+	for start > 0 && isIdentRune(runes[start-1]) {
+		start--
+	}
+	// identifiers cannot start with a digit.
+	for start < primes && unicode.IsDigit(runes[start]) {
+		start++
+	}
+	if start == primes {
+		return end
+	}
+	return start
+}
+
+func isIdentRune(ch rune) bool {
+	return ch == '_' || unicode.IsLetter(ch) || unicode.IsDigit(ch) || unicode.IsMark(ch)
+}
+
+func filterPrefix(names []string, prefix string) []string {
+	var matches []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"jingle/eval"
+	"strings"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	r := &repl{ev: eval.NewContext()}
+	src := "let apple = 1; let apricot = 2; let x' = 3; let x'' = 4; let café = 5\n" +
+		"class P def init() self.width = 1; self.weight = 2 end; def walk() nil end end\n" +
+		"let p = P.new(); let q = {inner: p}"
+	prog, errs := parseEntry("<test>", src)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	if err, ok := r.ev.Eval(prog).(*eval.Error); ok {
+		t.Fatal(r.ev.FormatError(err))
+	}
+	tests := []struct {
+		prefix     string
+		word       string
+		candidates []string
+	}{
+		{"ap", "ap", []string{"apple", "apricot"}},
+		{"1 + apr", "apr", []string{"apricot"}},
+		{"x'", "x'", []string{"x'", "x''"}},
+		{"x''", "x''", []string{"x''"}},
+		{"caf", "caf", []string{"café"}},
+		{"p.w", "w", []string{"walk", "weight", "width"}},
+		{"p.wi", "wi", []string{"width"}},
+		{"zz", "zz", nil},
+		{"9ap", "ap", []string{"apple", "apricot"}},
+		{"nope.w", "w", nil},
+		{`"str".l`, "l", nil},
+	}
+	for i, tt := range tests {
+		word, candidates := r.complete(tt.prefix)
+		if word != tt.word {
+			t.Fatalf("test[%d] expected word %q, got %q", i, tt.word, word)
+		}
+		if strings.Join(candidates, " ") != strings.Join(tt.candidates, " ") {
+			t.Fatalf("test[%d] expected %v, got %v", i, tt.candidates, candidates)
+		}
+	}
+}
+
+func TestIdentStart(t *testing.T) {
+	tests := []struct {
+		input string
+		start int
+	}{
+		{"abc", 0},
+		{"a.bc", 2},
+		{"f(x'", 2},
+		{"f(x''", 2},
+		{"a '", 3},
+		{"12", 2},
+		{"1ab", 1},
+		{"café", 0},
+		{"", 0},
+	}
+	for i, tt := range tests {
+		runes := []rune(tt.input)
+		if start := identStart(runes, len(runes)); start != tt.start {
+			t.Fatalf("test[%d] expected %d, got %d", i, tt.start, start)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes.
+const diffContext = 3
+
+// edit is a line of a diff: kept (' '), deleted ('-') or inserted ('+').
+type edit struct {
+	op   byte
+	line string
+}
+
+// unifiedDiff returns the changes from a to b in the unified format,
+// or "" if there are none.
+func unifiedDiff(aName string, bName string, a string, b string) string {
+	if a == b {
+		return ""
+	}
+	edits := diffLines(splitLines(a), splitLines(b))
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+		// a hunk goes from diffContext lines before a change, to
+		// diffContext lines after the last change closer than twice
+		// that to the one before.
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(edits) && edits[next].op == ' ' {
+				next++
+			}
+			if next == len(edits) || next-end > 2*diffContext {
+				break
+			}
+			end = next
+		}
+		stop := end + diffContext
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+		writeHunk(&out, edits, start, stop)
+		i = stop
+	}
+	return out.String()
+}
+
+// writeHunk writes the hunk of edits[start:stop].
+func writeHunk(out *strings.Builder, edits []edit, start int, stop int) {
+	// the line numbers of the hunk start after the lines before it.
+	aLine, bLine := 1, 1
+	for _, e := range edits[:start] {
+		if e.op != '+' {
+			aLine++
+		}
+		if e.op != '-' {
+			bLine++
+		}
+	}
+	aLen, bLen := 0, 0
+	for _, e := range edits[start:stop] {
+		if e.op != '+' {
+			aLen++
+		}
+		if e.op != '-' {
+			bLen++
+		}
+	}
+	// an empty range starts after the line before it.
+	if aLen == 0 {
+		aLine--
+	}
+	if bLen == 0 {
+		bLine--
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aLine, aLen), hunkRange(bLine, bLen))
+	for _, e := range edits[start:stop] {
+		out.WriteByte(e.op)
+		out.WriteString(e.line)
+		if !strings.HasSuffix(e.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(line int, n int) string {
+	if n == 1 {
+		return fmt.Sprint(line)
+	}
+	return fmt.Sprintf("%d,%d", line, n)
+}
+
+// splitLines splits s after its newlines.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the edits from a to b, keeping a longest common
+// subsequence of their lines. It uses the linear space variant of
+// Myers' O(ND) algorithm, "An O(ND) Difference Algorithm and Its
+// Variations" (1986).
+func diffLines(a []string, b []string) []edit {
+	d := &differ{a: a, b: b}
+	d.diff(0, len(a), 0, len(b))
+	return d.edits
+}
+
+type differ struct {
+	a, b  []string
+	edits []edit
+}
+
+// diff adds the edits from a[aLo:aHi] to b[bLo:bHi].
+func (d *differ) diff(aLo, aHi, bLo, bHi int) {
+	// lines in common at either end are kept as they are.
+	start := aLo
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		aLo++
+		bLo++
+	}
+	d.keep(start, aLo)
+	end := aHi
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+	switch {
+	case aLo == aHi:
+		for _, line := range d.b[bLo:bHi] {
+			d.edits = append(d.edits, edit{'+', line})
+		}
+	case bLo == bHi:
+		for _, line := range d.a[aLo:aHi] {
+			d.edits = append(d.edits, edit{'-', line})
+		}
+	default:
+		// both ends differ, so there are at least two edits, and
+		// either side of the middle snake has fewer.
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.diff(aLo, x, bLo, y)
+		d.keep(x, u)
+		d.diff(u, aHi, v, bHi)
+	}
+	d.keep(aHi, end)
+}
+
+// keep adds the lines a[lo:hi] as kept.
+func (d *differ) keep(lo, hi int) {
+	for _, line := range d.a[lo:hi] {
+		d.edits = append(d.edits, edit{' ', line})
+	}
+}
+
+// middleSnake returns the middle snake of an optimal path from
+// a[aLo:aHi] to b[bLo:bHi], as the lines a[x:u] that match b[y:v].
+// The path is searched for from both ends at once, until the
+// searches meet.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	// forward[off+k] is the furthest x reached on diagonal k = x - y
+	// from the start, and backward[off+k] the same from the end, in
+	// coordinates that count back from it.
+	off := max + 1
+	forward := make([]int, 2*max+3)
+	backward := make([]int, 2*max+3)
+	for D := 0; D <= max; D++ {
+		for k := -D; k <= D; k += 2 {
+			x, y, sx, sy := d.step(forward, off, k, D, func(x, y int) bool {
+				return x < n && 0 <= y && y < m && d.a[aLo+x] == d.b[bLo+y]
+			})
+			if kb := delta - k; odd && -(D-1) <= kb && kb <= D-1 && x+backward[off+kb] >= n {
+				return aLo + sx, bLo + sy, aLo + x, bLo + y
+			}
+		}
+		for k := -D; k <= D; k += 2 {
+			x, y, sx, sy := d.step(backward, off, k, D, func(x, y int) bool {
+				return x < n && 0 <= y && y < m && d.a[aHi-1-x] == d.b[bHi-1-y]
+			})
+			if kf := delta - k; !odd && -D <= kf && kf <= D && x+forward[off+kf] >= n {
+				return aHi - x, bHi - y, aHi - sx, bHi - sy
+			}
+		}
+	}
+	panic("diff: no middle snake")
+}
+
+// step extends the furthest path on diagonal k of v by one edit and
+// the snake after it, and returns where the snake starts and ends.
+func (d *differ) step(v []int, off int, k int, D int, match func(x, y int) bool) (x, y, sx, sy int) {
+	if k == -D || (k != D && v[off+k-1] < v[off+k+1]) {
+		x = v[off+k+1] // down: an insertion
+	} else {
+		x = v[off+k-1] + 1 // right: a deletion
+	}
+	y = x - k
+	sx, sy = x, y
+	for match(x, y) {
+		x++
+		y++
+	}
+	v[off+k] = x
+	return x, y, sx, sy
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{"", "", ""},
+		// a change in the middle, with three lines of context.
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9\n", "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n"},
+		// changes up to six lines apart share a hunk.
+		{"1\n2\n3\n4\n5\n6\n7\n8\n", "one\n2\n3\n4\n5\n6\n7\neight\n",
+			"@@ -1,8 +1,8 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n"},
+		// further apart, they get hunks of their own.
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9\n", "one\n2\n3\n4\n5\n6\n7\n8\nnine\n",
+			"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -6,4 +6,4 @@\n 6\n 7\n 8\n-9\n+nine\n"},
+		// empty ranges start after the line before them.
+		{"", "a\nb\n", "@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"a\n", "", "@@ -1 +0,0 @@\n-a\n"},
+		{"a\nb\n", "a\nx\nb\n", "@@ -1,2 +1,3 @@\n a\n+x\n b\n"},
+		{"1\n2\n3\n4\n5\n", "1\n2\n3\n4\n", "@@ -2,4 +2,3 @@\n 2\n 3\n 4\n-5\n"},
+		// a missing newline at the end of the file is marked.
+		{"a\nb", "a\nb\n", "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n"},
+		{"a", "b", "@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+b\n\\ No newline at end of file\n"},
+	}
+	for i, tt := range tests {
+		expected := tt.expected
+		if expected != "" {
+			expected = "--- a.orig\n+++ a\n" + expected
+		}
+		if got := unifiedDiff("a.orig", "a", tt.a, tt.b); got != expected {
+			t.Fatalf("test[%d] expected:\n%s\ngot:\n%s", i, expected, got)
+		}
+	}
+}
+
+// TestDiffLines checks that the edits turn a into b, keeping as many
+// lines as a longest common subsequence.
+func TestDiffLines(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		n := rnd.Intn(12)
+		s := make([]string, n)
+		for i := range s {
+			s[i] = string(rune('a' + rnd.Intn(3)))
+		}
+		return s
+	}
+	for i := 0; i < 2000; i++ {
+		a, b := lines(), lines()
+		var gotA, gotB []string
+		kept := 0
+		for _, e := range diffLines(a, b) {
+			if e.op != '+' {
+				gotA = append(gotA, e.line)
+			}
+			if e.op != '-' {
+				gotB = append(gotB, e.line)
+			}
+			if e.op == ' ' {
+				kept++
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("test[%d] edits from %q to %q give %q to %q", i, a, b, gotA, gotB)
+		}
+		if n := lcsLen(a, b); kept != n {
+			t.Fatalf("test[%d] from %q to %q kept %d lines, expected %d", i, a, b, kept, n)
+		}
+	}
+}
+
+func lcsLen(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] > lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	return lcs[0][0]
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	a := make([]string, 50000)
+	for i := range a {
+		a[i] = string(rune('a'+i%26)) + "\n"
+	}
+	b := append([]string{"new\n"}, a...)
+	b[25000] = "changed\n"
+	edits := diffLines(a, b)
+	changed := 0
+	for _, e := range edits {
+		if e.op != ' ' {
+			changed++
+		}
+	}
+	if changed != 3 {
+		t.Fatalf("expected 3 changed lines, got %d", changed)
+	}
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// errInterrupt is returned by readLine when the line is abandoned
+// with Ctrl-C.
+var errInterrupt = errors.New("interrupted")
+
+// maxHistory is the number of history entries that are kept.
+const maxHistory = 1000
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyCtrlJ     = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+
+	// keys sent as escape sequences are mapped to runes from the
+	// surrogate range, which cannot be typed.
+	keyUnknown = 0xd800 + iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+)
+
+// editor is a minimal line editor for terminals, with a history that
+// can be browsed with the arrow keys or searched with Ctrl-R, and
+// completion of the word before the cursor with Tab.
+type editor struct {
+	fd  int
+	in  *bufio.Reader
+	out io.Writer
+
+	history []string
+	file    *os.File // where new history entries are appended
+
+	// complete returns the partial word at the end of prefix, and
+	// the candidates it can be completed to.
+	complete func(prefix string) (word string, candidates []string)
+
+	// the line being edited
+	prompt string
+	line   []rune
+	pos    int
+}
+
+func newEditor(fd int, in io.Reader, out io.Writer) *editor {
+	return &editor{fd: fd, in: bufio.NewReader(in), out: out}
+}
+
+// loadHistory reads the history saved in path, and appends
+// the lines read from now on to it.
+func (e *editor) loadHistory(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	if len(e.history) > maxHistory {
+		// trim the file, so that it doesn't grow forever.
+		e.history = e.history[len(e.history)-maxHistory:]
+		data := strings.Join(e.history, "\n") + "\n"
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			return err
+		}
+	}
+	e.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	return err
+}
+
+// addHistory adds line to the history, unless it is blank or
+// repeats the latest entry.
+func (e *editor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+	if e.file != nil {
+		fmt.Fprintln(e.file, line)
+	}
+}
+
+func (e *editor) close() error {
+	if e.file == nil {
+		return nil
+	}
+	return e.file.Close()
+}
+
+// readLine reads a line, showing prompt before it. The terminal is
+// only in raw mode while the line is being edited.
+func (e *editor) readLine(prompt string) (string, error) {
+	state, err := term.MakeRaw(e.fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(e.fd, state)
+
+	e.prompt, e.line, e.pos = prompt, nil, 0
+	hist := len(e.history) // the entry shown, len(e.history) for the new line
+	var pending []rune     // the new line, while browsing the history
+	e.refresh()
+	var next rune // key left over from a search
+	for {
+		key := next
+		next = 0
+		if key == 0 {
+			if key, err = e.readKey(); err != nil {
+				return "", err
+			}
+		}
+		switch key {
+		case keyEnter, keyCtrlJ:
+			e.write("\r\n")
+			line := string(e.line)
+			e.addHistory(line)
+			return line, nil
+		case keyCtrlC:
+			e.write("^C\r\n")
+			return "", errInterrupt
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				e.write("\r\n")
+				return "", io.EOF
+			}
+			e.edit(keyDelete)
+		case keyUp, keyCtrlP:
+			if hist == 0 {
+				break
+			}
+			if hist == len(e.history) {
+				pending = e.line
+			}
+			hist--
+			e.setLine([]rune(e.history[hist]))
+		case keyDown, keyCtrlN:
+			if hist == len(e.history) {
+				break
+			}
+			hist++
+			if hist == len(e.history) {
+				e.setLine(pending)
+			} else {
+				e.setLine([]rune(e.history[hist]))
+			}
+		case keyCtrlR:
+			if next, err = e.search(); err != nil {
+				return "", err
+			}
+			hist = len(e.history)
+		case keyTab:
+			e.completeWord()
+		case keyCtrlL:
+			e.write("\x1b[H\x1b[2J")
+		default:
+			e.edit(key)
+		}
+		e.refresh()
+	}
+}
+
+// edit handles the keys that edit the line or move the cursor, and
+// inserts printable keys. Other keys are ignored.
+func (e *editor) edit(key rune) {
+	switch key {
+	case keyDelete:
+		e.delete(e.pos, e.pos+1)
+	case keyBackspace, keyCtrlH:
+		e.delete(e.pos-1, e.pos)
+	case keyCtrlW:
+		start := e.pos
+		for start > 0 && e.line[start-1] == ' ' {
+			start--
+		}
+		for start > 0 && e.line[start-1] != ' ' {
+			start--
+		}
+		e.delete(start, e.pos)
+	case keyCtrlK:
+		e.delete(e.pos, len(e.line))
+	case keyCtrlU:
+		e.delete(0, e.pos)
+	case keyLeft, keyCtrlB:
+		if e.pos > 0 {
+			e.pos--
+		}
+	case keyRight, keyCtrlF:
+		if e.pos < len(e.line) {
+			e.pos++
+		}
+	case keyHome, keyCtrlA:
+		e.pos = 0
+	case keyEnd, keyCtrlE:
+		e.pos = len(e.line)
+	default:
+		if key < keyUnknown && unicode.IsPrint(key) {
+			e.insert([]rune{key})
+		}
+	}
+}
+
+// readKey reads a key, decoding the escape sequences sent by the
+// special keys.
+func (e *editor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, err
+	}
+	if e.in.Buffered() == 0 {
+		// the escape key on its own.
+		return keyUnknown, nil
+	}
+	if r, _, err = e.in.ReadRune(); err != nil {
+		return 0, err
+	}
+	if r != '[' && r != 'O' {
+		return keyUnknown, nil
+	}
+	var params []rune
+	for {
+		if r, _, err = e.in.ReadRune(); err != nil {
+			return 0, err
+		}
+		if r >= 0x40 && r <= 0x7e {
+			break
+		}
+		params = append(params, r)
+	}
+	switch r {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	case '~':
+		switch string(params) {
+		case "1", "7":
+			return keyHome, nil
+		case "4", "8":
+			return keyEnd, nil
+		case "3":
+			return keyDelete, nil
+		}
+	}
+	return keyUnknown, nil
+}
+
+// search searches backwards through the history for the entries
+// containing the typed text. Ctrl-R goes to the next older match.
+// Any other key accepts the match, and is returned to be handled
+// as usual, except Ctrl-C and Ctrl-G which cancel the search.
+func (e *editor) search() (rune, error) {
+	var query []rune
+	match := len(e.history)
+	failing := false
+	// find moves to the latest match at or before index from.
+	find := func(from int) {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(e.history[i], string(query)) {
+				match, failing = i, false
+				return
+			}
+		}
+		failing = true
+	}
+	for {
+		status := "reverse-i-search"
+		if failing {
+			status = "failing " + status
+		}
+		shown := ""
+		if match < len(e.history) {
+			shown = e.history[match]
+		}
+		e.write(fmt.Sprintf("\r(%s)`%s': %s\x1b[K", status, string(query), shown))
+		key, err := e.readKey()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case key == keyCtrlR:
+			if match > 0 {
+				find(match - 1)
+			}
+		case key == keyBackspace || key == keyCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(e.history) - 1)
+			}
+		case key == keyCtrlC || key == keyCtrlG:
+			return 0, nil
+		case key < keyUnknown && unicode.IsPrint(key):
+			query = append(query, key)
+			// the current match may still match the longer query.
+			from := match
+			if from == len(e.history) {
+				from--
+			}
+			find(from)
+		default:
+			if match < len(e.history) {
+				e.setLine([]rune(e.history[match]))
+			}
+			return key, nil
+		}
+	}
+}
+
+// completeWord completes the word before the cursor as far as the
+// candidates agree, or lists them if they cannot be narrowed down.
+func (e *editor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	word, candidates := e.complete(string(e.line[:e.pos]))
+	if len(candidates) == 0 {
+		return
+	}
+	common := []rune(candidates[0])
+	for _, c := range candidates[1:] {
+		common = commonPrefix(common, []rune(c))
+	}
+	if n := len([]rune(word)); len(common) > n {
+		e.insert(common[n:])
+	} else if len(candidates) > 1 {
+		e.write("\r\n" + strings.Join(candidates, "  ") + "\r\n")
+	}
+}
+
+// commonPrefix returns the longest prefix shared by a and b.
+func commonPrefix(a, b []rune) []rune {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
+
+func (e *editor) insert(runes []rune) {
+	line := make([]rune, 0, len(e.line)+len(runes))
+	line = append(line, e.line[:e.pos]...)
+	line = append(line, runes...)
+	e.line = append(line, e.line[e.pos:]...)
+	e.pos += len(runes)
+}
+
+// delete removes the runes from start up to end, clamped to the line.
+func (e *editor) delete(start, end int) {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(e.line) {
+		end = len(e.line)
+	}
+	if start >= end {
+		return
+	}
+	e.line = append(e.line[:start:start], e.line[end:]...)
+	e.pos = start
+}
+
+func (e *editor) setLine(line []rune) {
+	e.line = line
+	e.pos = len(line)
+}
+
+// refresh redraws the prompt and the line, and puts the cursor in
+// place.
+func (e *editor) refresh() {
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(e.prompt)
+	b.WriteString(string(e.line))
+	b.WriteString("\x1b[K")
+	if n := len(e.line) - e.pos; n > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", n)
+	}
+	e.write(b.String())
+}
+
+func (e *editor) write(s string) {
+	io.WriteString(e.out, s)
+}
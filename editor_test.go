@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func testEditor(line string, pos int) (*editor, *bytes.Buffer) {
+	var out bytes.Buffer
+	e := newEditor(0, strings.NewReader(""), &out)
+	e.line, e.pos = []rune(line), pos
+	return e, &out
+}
+
+func TestEditorEdit(t *testing.T) {
+	tests := []struct {
+		line string
+		pos  int
+		keys []rune
+		want string
+		at   int
+	}{
+		{"", 0, []rune("héllo"), "héllo", 5},
+		{"ac", 1, []rune{'b'}, "abc", 2},
+		{"abc", 3, []rune{keyBackspace}, "ab", 2},
+		{"abc", 0, []rune{keyBackspace}, "abc", 0},
+		{"abc", 1, []rune{keyDelete}, "ac", 1},
+		{"abc", 3, []rune{keyDelete}, "abc", 3},
+		{"let x = 1  ", 11, []rune{keyCtrlW}, "let x = ", 8},
+		{"foo bar", 5, []rune{keyCtrlW}, "foo ar", 4},
+		{"foo bar", 3, []rune{keyCtrlK}, "foo", 3},
+		{"foo bar", 4, []rune{keyCtrlU}, "bar", 0},
+		{"ab", 1, []rune{keyLeft, keyLeft, keyLeft, 'x'}, "xab", 1},
+		{"ab", 1, []rune{keyRight, keyRight, 'x'}, "abx", 3},
+		{"ab", 1, []rune{keyHome, 'x', keyEnd, 'y'}, "xaby", 4},
+		{"ab", 2, []rune{keyCtrlA, keyCtrlF, keyCtrlD, keyCtrlE}, "ab", 2},
+		{"ab", 2, []rune{keyUnknown, keyUp, '\x1f'}, "ab", 2},
+	}
+	for i, tt := range tests {
+		e, _ := testEditor(tt.line, tt.pos)
+		for _, key := range tt.keys {
+			e.edit(key)
+		}
+		if string(e.line) != tt.want || e.pos != tt.at {
+			t.Fatalf("test[%d] expected %q at %d, got %q at %d", i, tt.want, tt.at, string(e.line), e.pos)
+		}
+	}
+}
+
+func TestEditorCompleteWord(t *testing.T) {
+	tests := []struct {
+		line       string
+		word       string
+		candidates []string
+		want       string
+		listed     bool
+	}{
+		{"pr", "pr", []string{"print"}, "print", false},
+		{"pr", "pr", []string{"print", "println"}, "print", false},
+		{"print", "print", []string{"print", "println"}, "print", true},
+		{"é", "é", []string{"éa", "éb"}, "é", true},
+		{"c", "c", []string{"café", "cafè"}, "caf", false},
+		{"ca", "ca", []string{"ça", "ca"}, "ca", true},
+		{"x", "x", nil, "x", false},
+		{"x", "x", []string{"x'", "x''"}, "x'", false},
+	}
+	for i, tt := range tests {
+		e, out := testEditor(tt.line, len([]rune(tt.line)))
+		e.complete = func(prefix string) (string, []string) {
+			if prefix != tt.line {
+				t.Fatalf("test[%d] expected prefix %q, got %q", i, tt.line, prefix)
+			}
+			return tt.word, tt.candidates
+		}
+		e.completeWord()
+		if string(e.line) != tt.want || e.pos != len([]rune(tt.want)) {
+			t.Fatalf("test[%d] expected %q, got %q at %d", i, tt.want, string(e.line), e.pos)
+		}
+		if listed := out.Len() > 0; listed != tt.listed {
+			t.Fatalf("test[%d] expected listed=%v, got output %q", i, tt.listed, out.String())
+		}
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct{ a, b, want string }{
+		{"abc", "abd", "ab"},
+		{"café", "cafè", "caf"},
+		{"é", "è", ""},
+		{"abc", "abc", "abc"},
+		{"", "abc", ""},
+	}
+	for i, tt := range tests {
+		if got := string(commonPrefix([]rune(tt.a), []rune(tt.b))); got != tt.want {
+			t.Fatalf("test[%d] expected %q, got %q", i, tt.want, got)
+		}
+	}
+}
+
+func TestEditorReadKey(t *testing.T) {
+	e, _ := testEditor("", 0)
+	e.in.Reset(strings.NewReader("a\x1b[A\x1b[B\x1b[C\x1b[D\x1b[H\x1b[F\x1b[3~\x1b[1~\x1b[4~\x1bOA\x1b[5~é"))
+	want := []rune{'a', keyUp, keyDown, keyRight, keyLeft, keyHome, keyEnd, keyDelete, keyHome, keyEnd, keyUp, keyUnknown, 'é'}
+	for i, w := range want {
+		key, err := e.readKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != w {
+			t.Fatalf("key[%d] expected %#x, got %#x", i, w, key)
+		}
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	e, _ := testEditor("", 0)
+	for _, line := range []string{"a", "", "  ", "b", "b", "a"} {
+		e.addHistory(line)
+	}
+	if got := strings.Join(e.history, ","); got != "a,b,a" {
+		t.Fatalf("expected a,b,a, got %s", got)
+	}
+	for i := 0; i < maxHistory+10; i++ {
+		e.addHistory(strings.Repeat("x", i%2+1))
+	}
+	if len(e.history) != maxHistory {
+		t.Fatalf("expected %d entries, got %d", maxHistory, len(e.history))
+	}
+}
@@ -0,0 +1,355 @@
+package eval
+
+import (
+	"sort"
+	"strings"
+)
+
+// Array *value*, backed by a Go slice.
+type Array struct {
+	Basic
+	elems []Value
+}
+
+// maxArrayLen is the length of the longest Array that operations
+// like Range.to_array will build.
+const maxArrayLen = 1 << 28
+
+func (g *GlobalObjects) NewArray(elems []Value) *Array {
+	return &Array{Basic: Basic{klass: g.Array}, elems: elems}
+}
+
+func (g *GlobalObjects) initArray() {
+	g.Array = g.NewClass("Array", g.Object)
+	g.Array.alloc = func(klass *Class) Value {
+		return &Array{Basic: Basic{klass: klass}, elems: []Value{}}
+	}
+	g.defineMethod(g.Array, "inspect", 0, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		parts := make([]string, len(arr.elems))
+		for i, elem := range arr.elems {
+			s, err := g.ctx.inspect(elem)
+			if err != nil {
+				return err
+			}
+			parts[i] = s
+		}
+		return g.NewString("[" + strings.Join(parts, ", ") + "]")
+	})
+	g.defineMethod(g.Array, "len", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NewInteger(int64(len(ref.this.(*Array).elems)))
+	})
+	// arr[i] returns a single element, while arr[i, j] returns
+	// the elements from i up to (but not including) j as a new
+	// Array. Negative indices count from the end. arr[range]
+	// returns the elements at the indices in range.
+	g.defineMethod(g.Array, "[]", -1, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		switch len(args) {
+		case 1:
+			if r, ok := args[0].(*Range); ok {
+				lo, hi, step, err := g.rangeIndices(r, len(arr.elems))
+				if err != nil {
+					return err
+				}
+				elems := []Value{}
+				for i := lo; i < hi; i += step {
+					elems = append(elems, arr.elems[i])
+				}
+				return g.NewArray(elems)
+			}
+			i, err := g.elemIndex(args[0], len(arr.elems))
+			if err != nil {
+				return err
+			}
+			return arr.elems[i]
+		case 2:
+			lo, hi, err := g.sliceIndices(args[0], args[1], len(arr.elems))
+			if err != nil {
+				return err
+			}
+			return g.NewArray(append([]Value{}, arr.elems[lo:hi]...))
+		}
+		return g.ctx.raisef(g.ArgumentError, "[]() takes 1 or 2 argument(s) but %d were given", len(args))
+	})
+	// arr[i] = x replaces a single element, while arr[i, j] = xs
+	// replaces the elements from i up to j with those of xs.
+	// arr[range] = xs is like arr[i, j] = xs, for ranges with a
+	// step of 1.
+	g.defineMethod(g.Array, "[]=", -1, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		switch len(args) {
+		case 2:
+			if r, ok := args[0].(*Range); ok {
+				lo, hi, step, err := g.rangeIndices(r, len(arr.elems))
+				if err != nil {
+					return err
+				}
+				if step != 1 {
+					return g.ctx.raisef(g.ValueError, "cannot assign to a slice with a step of %d", step)
+				}
+				return g.spliceArray(arr, lo, hi, args[1])
+			}
+			i, err := g.elemIndex(args[0], len(arr.elems))
+			if err != nil {
+				return err
+			}
+			arr.elems[i] = args[1]
+			return args[1]
+		case 3:
+			lo, hi, err := g.sliceIndices(args[0], args[1], len(arr.elems))
+			if err != nil {
+				return err
+			}
+			return g.spliceArray(arr, lo, hi, args[2])
+		}
+		return g.ctx.raisef(g.ArgumentError, "[]=() takes 2 or 3 argument(s) but %d were given", len(args))
+	})
+	g.defineMethod(g.Array, "push", -1, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		arr.elems = append(arr.elems, args...)
+		return arr
+	})
+	g.defineMethod(g.Array, "pop", 0, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		if len(arr.elems) == 0 {
+			return g.ctx.raisef(g.IndexError, "pop from empty Array")
+		}
+		last := arr.elems[len(arr.elems)-1]
+		arr.elems = arr.elems[:len(arr.elems)-1]
+		return last
+	})
+	g.defineMethod(g.Array, "each", 1, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		for i := 0; i < len(arr.elems); i++ {
+			if rv := g.ctx.call(args[0], []Value{arr.elems[i]}); isError(rv) {
+				return rv
+			}
+		}
+		return arr
+	})
+	g.defineMethod(g.Array, "map", 1, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		elems := make([]Value, 0, len(arr.elems))
+		for i := 0; i < len(arr.elems); i++ {
+			rv := g.ctx.call(args[0], []Value{arr.elems[i]})
+			if isError(rv) {
+				return rv
+			}
+			elems = append(elems, rv)
+		}
+		return g.NewArray(elems)
+	})
+	g.defineMethod(g.Array, "filter", 1, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		elems := []Value{}
+		for i := 0; i < len(arr.elems); i++ {
+			rv := g.ctx.call(args[0], []Value{arr.elems[i]})
+			if isError(rv) {
+				return rv
+			}
+			if g.isTruthy(rv) {
+				elems = append(elems, arr.elems[i])
+			}
+		}
+		return g.NewArray(elems)
+	})
+	// reduce(f, init) folds the array from the left. Without init,
+	// the first element is used as the initial value.
+	g.defineMethod(g.Array, "reduce", -1, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		var acc Value
+		start := 0
+		switch len(args) {
+		case 1:
+			if len(arr.elems) == 0 {
+				return g.ctx.raisef(g.TypeError, "reduce() of empty Array with no initial value")
+			}
+			acc = arr.elems[0]
+			start = 1
+		case 2:
+			acc = args[1]
+		default:
+			return g.ctx.raisef(g.ArgumentError, "reduce() takes 1 or 2 argument(s) but %d were given", len(args))
+		}
+		for i := start; i < len(arr.elems); i++ {
+			acc = g.ctx.call(args[0], []Value{acc, arr.elems[i]})
+			if isError(acc) {
+				return acc
+			}
+		}
+		return acc
+	})
+	// sort() sorts the array in place using <, or sort(f) using a
+	// function that returns true if its first argument should come
+	// before the second.
+	g.defineMethod(g.Array, "sort", -1, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		var less func(a, b Value) Value
+		switch len(args) {
+		case 0:
+			less = func(a, b Value) Value { return g.ctx.callMethod(a, "<", []Value{b}) }
+		case 1:
+			less = func(a, b Value) Value { return g.ctx.call(args[0], []Value{a, b}) }
+		default:
+			return g.ctx.raisef(g.ArgumentError, "sort() takes 0 or 1 argument(s) but %d were given", len(args))
+		}
+		var err Value
+		sort.SliceStable(arr.elems, func(i, j int) bool {
+			if err != nil {
+				return false
+			}
+			rv := less(arr.elems[i], arr.elems[j])
+			if isError(rv) {
+				err = rv
+				return false
+			}
+			return g.isTruthy(rv)
+		})
+		if err != nil {
+			return err
+		}
+		return arr
+	})
+	g.defineMethod(g.Array, "reverse", 0, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		elems := make([]Value, len(arr.elems))
+		for i, elem := range arr.elems {
+			elems[len(elems)-1-i] = elem
+		}
+		return g.NewArray(elems)
+	})
+	g.defineMethod(g.Array, "contains", 1, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		for i := 0; i < len(arr.elems); i++ {
+			eq, err := g.ctx.equal(arr.elems[i], args[0])
+			if err != nil {
+				return err
+			}
+			if eq {
+				return g.TRUE
+			}
+		}
+		return g.FALSE
+	})
+	// join(sep) joins the elements with sep (by default ""), which
+	// are converted into strings as in string interpolation.
+	g.defineMethod(g.Array, "join", -1, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		sep := ""
+		switch len(args) {
+		case 0:
+		case 1:
+			s, ok := args[0].(*String)
+			if !ok {
+				return g.ctx.raisef(g.TypeError, "join() expects a String, got %s", args[0].Klass().name)
+			}
+			sep = s.s
+		default:
+			return g.ctx.raisef(g.ArgumentError, "join() takes 0 or 1 argument(s) but %d were given", len(args))
+		}
+		parts := make([]string, len(arr.elems))
+		for i, elem := range arr.elems {
+			s, err := g.ctx.toS(elem)
+			if err != nil {
+				return err
+			}
+			parts[i] = s
+		}
+		return g.NewString(strings.Join(parts, sep))
+	})
+	g.defineMethod(g.Array, "==", 1, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		other, ok := args[0].(*Array)
+		if !ok || len(arr.elems) != len(other.elems) {
+			return g.FALSE
+		}
+		for i := range arr.elems {
+			eq, err := g.ctx.equal(arr.elems[i], other.elems[i])
+			if err != nil {
+				return err
+			}
+			if !eq {
+				return g.FALSE
+			}
+		}
+		return g.TRUE
+	})
+}
+
+// spliceArray replaces the elements of arr from lo up to hi with
+// the elements of val, which has to be an Array.
+func (g *GlobalObjects) spliceArray(arr *Array, lo, hi int, val Value) Value {
+	other, ok := val.(*Array)
+	if !ok {
+		return g.ctx.raisef(g.TypeError, "can only assign an Array to a slice, got %s", val.Klass().name)
+	}
+	elems := append([]Value{}, arr.elems[:lo]...)
+	elems = append(elems, other.elems...)
+	arr.elems = append(elems, arr.elems[hi:]...)
+	return val
+}
+
+// toInt converts v into an int, if it is an Integer.
+func (g *GlobalObjects) toInt(v Value) (int, *Error) {
+	n, ok := v.(*Integer)
+	if !ok {
+		return 0, g.ctx.raisef(g.TypeError, "indices must be Integers, not %s", v.Klass().name)
+	}
+	if n.big != nil || int64(int(n.i)) != n.i {
+		return 0, g.ctx.raisef(g.IndexError, "index %s out of range", n)
+	}
+	return int(n.i), nil
+}
+
+// elemIndex converts v into an index into a sequence of length n,
+// counting from the end if v is negative.
+func (g *GlobalObjects) elemIndex(v Value, n int) (int, *Error) {
+	i, err := g.toInt(v)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		i += n
+	}
+	if i < 0 || i >= n {
+		return 0, g.ctx.raisef(g.IndexError, "index %s out of range", v)
+	}
+	return i, nil
+}
+
+// sliceIndices converts lo and hi into bounds for slicing a sequence
+// of length n. Negative bounds count from the end, nil stands for the
+// end of the sequence, and bounds out of range are clamped.
+func (g *GlobalObjects) sliceIndices(lo, hi Value, n int) (int, int, *Error) {
+	clamp := func(v Value, def int) (int, *Error) {
+		if v == g.NIL {
+			return def, nil
+		}
+		i, err := g.toInt(v)
+		if err != nil {
+			return 0, err
+		}
+		if i < 0 {
+			i += n
+		}
+		if i < 0 {
+			return 0, nil
+		} else if i > n {
+			return n, nil
+		}
+		return i, nil
+	}
+	i, err := clamp(lo, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	j, err := clamp(hi, n)
+	if err != nil {
+		return 0, 0, err
+	}
+	if j < i {
+		j = i
+	}
+	return i, j, nil
+}
@@ -0,0 +1,58 @@
+package eval
+
+import "jingle/ast"
+
+// Super is the value of `super` inside a method. Looking up an
+// attribute on it finds the superclass' implementation, bound to
+// the current `self`:
+//
+//	def init(x)
+//	  super.init(x)
+//	end
+type Super struct {
+	Basic
+	this  Value
+	start *Class // the class to start the method lookup from
+}
+
+func (ctx *Context) evalClassStatement(node *ast.ClassStatement) Value {
+	super := ctx.g.Object
+	if node.SuperClass != nil {
+		val := ctx.Eval(node.SuperClass)
+		if isError(val) {
+			return val
+		}
+		klass, ok := val.(*Class)
+		if !ok {
+			return ctx.raisef(ctx.g.TypeError, "cannot inherit from %s", val.Klass().name)
+		}
+		super = klass
+	}
+	klass := ctx.g.NewClass(node.Name.Name(), super)
+	// bind the name first, so that methods can refer to the class.
+	ctx.scope.Assign(node.Name.Name(), klass)
+
+	scope := NewScope(ctx.scope)
+	scope.Set("self", klass)
+	outer := ctx.scope
+	ctx.scope = scope
+	defer func() { ctx.scope = outer }()
+	for _, stmt := range node.Body.Statements {
+		if meth, ok := stmt.(*ast.MethodDeclaration); ok {
+			klass.methods[meth.MethodName.Name] = &Function{
+				Basic:    Basic{klass: ctx.g.Function},
+				name:     meth.MethodName.Name,
+				filename: ctx.frame().filename,
+				params:   meth.Params,
+				body:     meth.Body,
+				scope:    scope,
+				owner:    klass,
+			}
+			continue
+		}
+		if rv := ctx.Eval(stmt); isError(rv) {
+			return rv
+		}
+	}
+	return klass
+}
@@ -0,0 +1,208 @@
+package eval
+
+import (
+	"fmt"
+	"jingle/ast"
+	"strings"
+)
+
+// Error wraps around a reason object, and is an error
+// meant to be unwrapped. If there is no code to catch the error,
+// the error propagates up the stack. This is NOT the Error _class_
+// in the language -- the reason is usually an instance of it.
+type Error struct {
+	Basic
+	Reason Value
+	// Trace lists the frames that the error unwound through,
+	// innermost first.
+	Trace   []Frame
+	pending bool // does the current frame still need to be recorded?
+}
+
+// Frame records where the execution of a function was when an
+// error unwound through it.
+type Frame struct {
+	Name     string // the function name
+	Filename string
+	LineNo   int
+	Column   int
+}
+
+// frame is an active function call.
+type frame struct {
+	name     string
+	filename string
+}
+
+// Exception is an instance of the Error class (or a subclass).
+type Exception struct {
+	Object
+	raised *Error // the error that this was last raised with
+}
+
+func (g *GlobalObjects) NewException(klass *Class, message string) *Exception {
+	exc := &Exception{Object: *g.NewObject(klass)}
+	exc.attrs["message"] = g.NewString(message)
+	return exc
+}
+
+func (g *GlobalObjects) initError() {
+	g.Error = g.NewClass("Error", g.Object)
+	g.Error.alloc = func(klass *Class) Value {
+		return &Exception{Object: *g.NewObject(klass)}
+	}
+	g.defineMethod(g.Error, "init", -1, func(ref *NativeFunction, args []Value) Value {
+		exc := ref.this.(*Exception)
+		switch len(args) {
+		case 0:
+			exc.attrs["message"] = g.NewString("")
+		case 1:
+			exc.attrs["message"] = args[0]
+		default:
+			return g.ctx.raisef(g.ArgumentError, "init() takes 0 or 1 argument(s) but %d were given", len(args))
+		}
+		return g.NIL
+	})
+	g.defineMethod(g.Error, "inspect", 0, func(ref *NativeFunction, args []Value) Value {
+		exc := ref.this.(*Exception)
+		return g.NewString(fmt.Sprintf("<%s: %s>", exc.klass.name, g.ctx.message(exc)))
+	})
+	g.TypeError = g.NewClass("TypeError", g.Error)
+	g.NameError = g.NewClass("NameError", g.Error)
+	g.AttributeError = g.NewClass("AttributeError", g.Error)
+	g.ArgumentError = g.NewClass("ArgumentError", g.Error)
+	g.ValueError = g.NewClass("ValueError", g.Error)
+	g.IndexError = g.NewClass("IndexError", g.Error)
+	g.KeyError = g.NewClass("KeyError", g.Error)
+	g.StopIteration = g.NewClass("StopIteration", g.Error)
+	g.ZeroDivisionError = g.NewClass("ZeroDivisionError", g.Error)
+}
+
+// errorf creates an *Error whose reason is an instance of the
+// Error class, with a formatted message.
+func (ctx *Context) errorf(f string, args ...interface{}) *Error {
+	return ctx.raisef(ctx.g.Error, f, args...)
+}
+
+// raisef is like errorf, but the reason is an instance of klass.
+func (ctx *Context) raisef(klass *Class, f string, args ...interface{}) *Error {
+	return ctx.raise(ctx.g.NewException(klass, fmt.Sprintf(f, args...)))
+}
+
+// raise creates an *Error that propagates reason up the stack.
+func (ctx *Context) raise(reason Value) *Error {
+	err := &Error{Reason: reason, pending: true}
+	if exc, ok := reason.(*Exception); ok {
+		exc.raised = err
+	}
+	return err
+}
+
+// message returns the message of an Error instance, as a Go string.
+func (ctx *Context) message(exc *Exception) string {
+	switch msg := exc.attrs["message"].(type) {
+	case nil:
+		return ""
+	case *String:
+		return msg.s
+	default:
+		return fmt.Sprintf("%v", msg)
+	}
+}
+
+// traceback renders the trace of err in the same way as Python:
+// the most recent call comes last, and each frame shows the
+// offending line (if the source is known) with a caret under the
+// column where the error happened.
+func (ctx *Context) traceback(err *Error) string {
+	var out strings.Builder
+	out.WriteString("Traceback (most recent call last):\n")
+	for i := len(err.Trace) - 1; i >= 0; i-- {
+		f := err.Trace[i]
+		fmt.Fprintf(&out, "  File %q, line %d, column %d, in %s\n",
+			f.Filename, f.LineNo, f.Column, f.Name)
+		lines := ctx.sources[f.Filename]
+		if f.LineNo < 1 || f.LineNo > len(lines) {
+			continue
+		}
+		line := []rune(lines[f.LineNo-1])
+		trimmed := []rune(strings.TrimLeft(string(line), " \t"))
+		out.WriteString("    ")
+		out.WriteString(strings.TrimRight(string(trimmed), " \t\r"))
+		out.WriteString("\n")
+		if col := f.Column - 1 - (len(line) - len(trimmed)); col >= 0 {
+			out.WriteString("    ")
+			out.WriteString(strings.Repeat(" ", col))
+			out.WriteString("^\n")
+		}
+	}
+	return out.String()
+}
+
+// FormatError formats err for display: the traceback, followed by
+// the class and message of the reason.
+func (ctx *Context) FormatError(err *Error) string {
+	var out strings.Builder
+	out.WriteString(ctx.traceback(err))
+	switch reason := err.Reason.(type) {
+	case *Exception:
+		fmt.Fprintf(&out, "%s: %s", reason.klass.name, ctx.message(reason))
+	default:
+		fmt.Fprintf(&out, "%s: %v", reason.Klass().name, reason)
+	}
+	return out.String()
+}
+
+// AddSource registers the source code of filename, so that
+// tracebacks can show the offending lines.
+func (ctx *Context) AddSource(filename string, input string) {
+	ctx.sources[filename] = strings.Split(input, "\n")
+}
+
+// evalTryStatement evaluates the body of node. If it raises an
+// error, the first catch clause that matches the class of the
+// reason handles it. The finally block is always evaluated, even if
+// the body returns; an error or return in the finally block takes
+// precedence over the outcome of the body.
+func (ctx *Context) evalTryStatement(node *ast.TryStatement) Value {
+	rv := ctx.Eval(node.Body)
+	if err, ok := rv.(*Error); ok {
+		for _, clause := range node.Catches {
+			matched, mErr := ctx.catches(clause, err)
+			if mErr != nil {
+				rv = mErr
+				break
+			}
+			if matched {
+				scope := NewScope(ctx.scope)
+				if clause.Binding != nil {
+					scope.Set(clause.Binding.Name(), err.Reason)
+				}
+				rv = ctx.evalBlock(clause.Body, scope)
+				break
+			}
+		}
+	}
+	if node.Finally != nil {
+		if frv := ctx.Eval(node.Finally); isUnwinding(frv) {
+			return frv
+		}
+	}
+	return rv
+}
+
+// catches returns true if clause should handle err.
+func (ctx *Context) catches(clause *ast.CatchClause, err *Error) (bool, *Error) {
+	if clause.Class == nil {
+		return true, nil
+	}
+	val := ctx.Eval(clause.Class)
+	if e, ok := val.(*Error); ok {
+		return false, e
+	}
+	klass, ok := val.(*Class)
+	if !ok {
+		return false, ctx.raisef(ctx.g.TypeError, "cannot catch %s, only classes", val.Klass().name)
+	}
+	return err.Reason.Klass().isSubclassOf(klass), nil
+}
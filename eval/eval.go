@@ -5,25 +5,91 @@ package eval
 import (
 	"fmt"
 	"jingle/ast"
+	"sort"
+	"strings"
 )
 
 type Context struct {
-	scope *Scope
-	g     *GlobalObjects
+	scope   *Scope
+	g       *GlobalObjects
+	frames  []frame             // active function calls, innermost last
+	sources map[string][]string // source lines of each file, for tracebacks
 }
 
 func NewContext() *Context {
-	ctx := &Context{}
+	ctx := &Context{sources: map[string][]string{}}
 	ctx.g = NewGlobalObjects(ctx)
 	ctx.scope = NewGlobalScope(ctx.g)
+	ctx.SetArgs(nil)
 	return ctx
 }
 
+// SetArgs makes args, the command line arguments, available to
+// programs as ARGV, an Array of Strings.
+func (ctx *Context) SetArgs(args []string) {
+	elems := make([]Value, len(args))
+	for i, arg := range args {
+		elems[i] = ctx.g.NewString(arg)
+	}
+	ctx.scope.Set("ARGV", ctx.g.NewArray(elems))
+}
+
 func isError(v Value) bool {
 	_, ok := v.(*Error)
 	return ok
 }
 
+// isUnwinding returns true if v has to be propagated up instead of
+// being used as a value, i.e. it is an error, a return value, or
+// comes from a break or continue statement.
+func isUnwinding(v Value) bool {
+	switch v.(type) {
+	case *Error, *returnValue, *breakValue, *continueValue:
+		return true
+	}
+	return false
+}
+
+// breakValue and continueValue unwind the enclosing blocks up to the
+// loop with the given label, or the innermost loop if it is empty.
+type breakValue struct {
+	Basic
+	label string
+}
+
+type continueValue struct {
+	Basic
+	label string
+}
+
+// loopControl checks whether rv, the result of evaluating the body
+// of a loop labeled label, breaks out of or continues this loop.
+func loopControl(rv Value, label *ast.IdentifierLiteral) (brk bool, cont bool) {
+	targets := func(target string) bool {
+		return target == "" || (label != nil && label.Name() == target)
+	}
+	switch rv := rv.(type) {
+	case *breakValue:
+		return targets(rv.label), false
+	case *continueValue:
+		return false, targets(rv.label)
+	}
+	return false, false
+}
+
+// labelName returns the name of label, or "" if there is none.
+func labelName(label *ast.IdentifierLiteral) string {
+	if label == nil {
+		return ""
+	}
+	return label.Name()
+}
+
+// Scope returns the scope that top-level code is evaluated in.
+func (ctx *Context) Scope() *Scope {
+	return ctx.scope
+}
+
 // lookup finds a variable in the scope stack.
 func (ctx *Context) lookup(name string) (Value, bool) {
 	return ctx.scope.Get(name)
@@ -33,6 +99,8 @@ func (ctx *Context) maybeBind(obj Value, this Value) Value {
 	switch obj := obj.(type) {
 	case *NativeFunction:
 		return obj.Bind(this)
+	case *Function:
+		return obj.Bind(this)
 	}
 	return obj
 }
@@ -41,10 +109,27 @@ func (ctx *Context) maybeBind(obj Value, this Value) Value {
 func (ctx *Context) lookupAttr(obj Value, attr string) (Value, bool) {
 	// first try to find it on the object itself.
 	switch x_obj := obj.(type) {
+	case *Super:
+		for klass := x_obj.start; klass != nil; klass = klass.super {
+			if val, ok := klass.methods[attr]; ok {
+				return ctx.maybeBind(val, x_obj.this), true
+			}
+		}
+		return nil, false
 	case *Object:
 		if val, ok := x_obj.attrs[attr]; ok {
 			return ctx.maybeBind(val, obj), true
 		}
+	case *Exception:
+		if attr == "trace" {
+			if x_obj.raised == nil {
+				return ctx.g.NIL, true
+			}
+			return ctx.g.NewString(ctx.traceback(x_obj.raised)), true
+		}
+		if val, ok := x_obj.attrs[attr]; ok {
+			return ctx.maybeBind(val, obj), true
+		}
 	case *Class:
 		// For classes, we first look at the class attributes.
 		// Failing that, we try to find a method in the class.
@@ -66,24 +151,239 @@ func (ctx *Context) lookupAttr(obj Value, attr string) (Value, bool) {
 	return nil, false
 }
 
+// LookupAttr looks up an attribute in obj, as `obj.attr` does.
+func (ctx *Context) LookupAttr(obj Value, attr string) (Value, bool) {
+	return ctx.lookupAttr(obj, attr)
+}
+
+// Attrs returns the sorted names of the attributes that can be
+// looked up in obj.
+func (ctx *Context) Attrs(obj Value) []string {
+	candidates := map[string]bool{}
+	if attrs, ok := attrsOf(obj); ok {
+		for name := range attrs {
+			candidates[name] = true
+		}
+	}
+	if _, ok := obj.(*Exception); ok {
+		candidates["trace"] = true
+	}
+	for klass := obj.Klass(); klass != nil; klass = klass.super {
+		for name := range klass.attrs {
+			candidates[name] = true
+		}
+		for name := range klass.methods {
+			candidates[name] = true
+		}
+	}
+	if super, ok := obj.(*Super); ok {
+		for klass := super.start; klass != nil; klass = klass.super {
+			for name := range klass.methods {
+				candidates[name] = true
+			}
+		}
+	}
+	var names []string
+	for name := range candidates {
+		if _, ok := ctx.lookupAttr(obj, name); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // call calls the given target with the given arguments.
 func (ctx *Context) call(target Value, args []Value) Value {
 	switch target := target.(type) {
 	case *NativeFunction:
 		return target.Call(args)
+	case *Function:
+		return ctx.callFunction(target, args)
 	}
-	// TODO: return error
-	return nil
+	return ctx.raisef(ctx.g.TypeError, "%s is not callable", target.Klass().name)
+}
+
+// callMethod looks up the method called name on obj, and calls it.
+// This is how operators are dispatched.
+func (ctx *Context) callMethod(obj Value, name string, args []Value) Value {
+	meth, ok := ctx.lookupAttr(obj, name)
+	if !ok {
+		return ctx.raisef(ctx.g.AttributeError, "undefined method %s for %s", name, obj.Klass().name)
+	}
+	return ctx.call(meth, args)
+}
+
+// inspect returns the representation of v given by its inspect
+// method.
+func (ctx *Context) inspect(v Value) (string, *Error) {
+	rv := ctx.callMethod(v, "inspect", []Value{})
+	if err, ok := rv.(*Error); ok {
+		return "", err
+	}
+	s, ok := rv.(*String)
+	if !ok {
+		return "", ctx.raisef(ctx.g.TypeError, "inspect() should return a String, got %s", rv.Klass().name)
+	}
+	return s.s, nil
+}
+
+// Inspect returns the representation of v given by its inspect
+// method.
+func (ctx *Context) Inspect(v Value) (string, *Error) {
+	return ctx.inspect(v)
 }
 
+// toS converts v into a string for display. Strings are used as
+// they are, and other values are converted by their to_s method if
+// they have one, or by inspect otherwise.
+func (ctx *Context) toS(v Value) (string, *Error) {
+	if s, ok := v.(*String); ok {
+		return s.s, nil
+	}
+	meth, ok := ctx.lookupAttr(v, "to_s")
+	if !ok {
+		return ctx.inspect(v)
+	}
+	rv := ctx.call(meth, []Value{})
+	if err, ok := rv.(*Error); ok {
+		return "", err
+	}
+	s, ok := rv.(*String)
+	if !ok {
+		return "", ctx.raisef(ctx.g.TypeError, "to_s() should return a String, got %s", rv.Klass().name)
+	}
+	return s.s, nil
+}
+
+// equal compares a and b using the == method of a.
+func (ctx *Context) equal(a, b Value) (bool, *Error) {
+	rv := ctx.callMethod(a, "==", []Value{b})
+	if err, ok := rv.(*Error); ok {
+		return false, err
+	}
+	return ctx.g.isTruthy(rv), nil
+}
+
+// Eval evaluates node. If evaluating node results in an error that
+// has not been located in the current frame yet, the position of
+// node is recorded in its trace.
 func (ctx *Context) Eval(node ast.Node) Value {
+	rv := ctx.eval(node)
+	if err, ok := rv.(*Error); ok && err.pending {
+		err.pending = false
+		tok := node.GetToken()
+		f := ctx.frame()
+		err.Trace = append(err.Trace, Frame{
+			Name:     f.name,
+			Filename: f.filename,
+			LineNo:   tok.LineNo,
+			Column:   tok.Column,
+		})
+	}
+	return rv
+}
+
+// frame returns the innermost active function call.
+func (ctx *Context) frame() frame {
+	if len(ctx.frames) == 0 {
+		return frame{name: "<main>"}
+	}
+	return ctx.frames[len(ctx.frames)-1]
+}
+
+func (ctx *Context) eval(node ast.Node) Value {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
 		return ctx.evalProgram(node)
 	case *ast.ExpressionStatement:
 		return ctx.Eval(node.Expr)
+	case *ast.Block:
+		return ctx.evalBlock(node, NewScope(ctx.scope))
+	case *ast.IfStatement:
+		cond := ctx.Eval(node.Cond)
+		if isError(cond) {
+			return cond
+		}
+		if ctx.g.isTruthy(cond) {
+			return ctx.Eval(node.Then)
+		} else if node.Else != nil {
+			return ctx.Eval(node.Else)
+		}
+		return ctx.g.NIL
+	case *ast.LetStatement:
+		return ctx.evalLetStatement(node)
+	case *ast.WhileStatement:
+		for {
+			cond := ctx.Eval(node.Condition)
+			if isError(cond) {
+				return cond
+			}
+			if !ctx.g.isTruthy(cond) {
+				return ctx.g.NIL
+			}
+			rv := ctx.evalBlock(node.Body, NewScope(ctx.scope))
+			if brk, cont := loopControl(rv, node.Label); brk {
+				return ctx.g.NIL
+			} else if !cont && isUnwinding(rv) {
+				return rv
+			}
+		}
+	case *ast.ForStatement:
+		return ctx.evalForStatement(node)
+	case *ast.ClassStatement:
+		return ctx.evalClassStatement(node)
+	case *ast.TryStatement:
+		return ctx.evalTryStatement(node)
+	case *ast.RaiseStatement:
+		val := ctx.Eval(node.Expr)
+		if isError(val) {
+			return val
+		}
+		switch val := val.(type) {
+		case *Exception:
+			return ctx.raise(val)
+		case *String:
+			return ctx.raise(ctx.g.NewException(ctx.g.Error, val.s))
+		}
+		return ctx.raisef(ctx.g.TypeError, "cannot raise %s, only Error instances", val.Klass().name)
+	case *ast.BreakStatement:
+		return &breakValue{label: labelName(node.Label)}
+	case *ast.ContinueStatement:
+		return &continueValue{label: labelName(node.Label)}
+	case *ast.ReturnStatement:
+		val := ctx.Eval(node.Expr)
+		if isError(val) {
+			return val
+		}
+		return &returnValue{value: val}
 	// Expressions
+	case *ast.AssignmentExpression:
+		return ctx.evalAssignment(node)
+	case *ast.OrExpression:
+		left := ctx.Eval(node.Left)
+		if isError(left) || ctx.g.isTruthy(left) {
+			return left
+		}
+		return ctx.Eval(node.Right)
+	case *ast.AndExpression:
+		left := ctx.Eval(node.Left)
+		if isError(left) || !ctx.g.isTruthy(left) {
+			return left
+		}
+		return ctx.Eval(node.Right)
+	case *ast.IfElseExpression:
+		cond := ctx.Eval(node.Cond)
+		if isError(cond) {
+			return cond
+		}
+		if ctx.g.isTruthy(cond) {
+			return ctx.Eval(node.Then)
+		} else if node.Else != nil {
+			return ctx.Eval(node.Else)
+		}
+		return ctx.g.NIL
 	case *ast.AttrExpression:
 		target := ctx.Eval(node.Target)
 		if isError(target) {
@@ -91,37 +391,88 @@ func (ctx *Context) Eval(node ast.Node) Value {
 		}
 		val, ok := ctx.lookupAttr(target, node.Name.Name())
 		if !ok {
-			return &Error{Reason: ctx.g.NewString(fmt.Sprintf(
-				"object does not have attr %s",
-				node.Name.Name(),
-			))}
+			return ctx.raisef(ctx.g.AttributeError, "object does not have attr %s", node.Name.Name())
 		}
 		return val
+	case *ast.PrefixExpression:
+		right := ctx.Eval(node.Expr)
+		if isError(right) {
+			return right
+		}
+		return ctx.callMethod(right, node.Op, []Value{})
+	case *ast.InfixExpression:
+		left := ctx.Eval(node.Left)
+		if isError(left) {
+			return left
+		}
+		right := ctx.Eval(node.Right)
+		if isError(right) {
+			return right
+		}
+		return ctx.callMethod(left, node.Op, []Value{right})
+	case *ast.IndexExpression:
+		target := ctx.Eval(node.Target)
+		if isError(target) {
+			return target
+		}
+		args, err := ctx.evalArgs(node.Args)
+		if err != nil {
+			return err
+		}
+		return ctx.callMethod(target, "[]", args)
+	case *ast.ParenExpression:
+		return ctx.Eval(node.Expr)
 	case *ast.CallExpression:
 		target := ctx.Eval(node.Target)
 		if isError(target) {
 			return target
 		}
-		args := make([]Value, len(node.Args))
-		for i, arg_node := range node.Args {
-			args[i] = ctx.Eval(arg_node)
-			if isError(args[i]) {
-				return args[i]
-			}
+		args, err := ctx.evalArgs(node.Args)
+		if err != nil {
+			return err
 		}
 		return ctx.call(target, args)
 	// Literals
 	case *ast.IdentifierLiteral:
 		val, ok := ctx.lookup(node.Name())
 		if !ok {
-			return &Error{Reason: ctx.g.NewString(fmt.Sprintf(
-				"name %s is undefined",
-				node.Name(),
-			))}
+			return ctx.raisef(ctx.g.NameError, "name %s is undefined", node.Name())
 		}
 		return val
+	case *ast.IntegerLiteral:
+		return ctx.g.newBigInteger(node.Value)
+	case *ast.FloatLiteral:
+		return ctx.g.NewFloat(node.Value)
+	case *ast.FunctionLiteral:
+		return ctx.g.NewFunction("", node, ctx.scope)
 	case *ast.StringLiteral:
 		return ctx.g.NewString(node.Value)
+	case *ast.InterpolatedString:
+		var buf strings.Builder
+		for i, str := range node.Strings {
+			buf.WriteString(str)
+			if i == len(node.Exprs) {
+				break
+			}
+			val := ctx.Eval(node.Exprs[i])
+			if isError(val) {
+				return val
+			}
+			s, err := ctx.toS(val)
+			if err != nil {
+				return err
+			}
+			buf.WriteString(s)
+		}
+		return ctx.g.NewString(buf.String())
+	case *ast.ArrayLiteral:
+		elems, err := ctx.evalArgs(node.Elems)
+		if err != nil {
+			return err
+		}
+		return ctx.g.NewArray(elems)
+	case *ast.MapLiteral:
+		return ctx.evalMapLiteral(node)
 	case *ast.BooleanLiteral:
 		if node.Value {
 			return ctx.g.TRUE
@@ -133,13 +484,216 @@ func (ctx *Context) Eval(node ast.Node) Value {
 	default:
 		panic(fmt.Sprintf("not implemented yet: %T", node))
 	}
-	return nil
 }
 
 func (ctx *Context) evalProgram(prog *ast.Program) Value {
+	ctx.frames = append(ctx.frames, frame{name: "<main>", filename: prog.Filename})
+	defer func() { ctx.frames = ctx.frames[:len(ctx.frames)-1] }()
 	var rv Value = ctx.g.NIL
 	for _, x := range prog.Statements {
 		rv = ctx.Eval(x)
+		if isError(rv) {
+			break
+		}
+	}
+	return rv
+}
+
+// evalBlock evaluates the statements of block within scope. It stops
+// early if a statement evaluates to an error or a return value.
+func (ctx *Context) evalBlock(block *ast.Block, scope *Scope) Value {
+	outer := ctx.scope
+	ctx.scope = scope
+	defer func() { ctx.scope = outer }()
+	var rv Value = ctx.g.NIL
+	for _, stmt := range block.Statements {
+		rv = ctx.Eval(stmt)
+		if isUnwinding(rv) {
+			break
+		}
 	}
 	return rv
 }
+
+func (ctx *Context) evalAssignment(node *ast.AssignmentExpression) Value {
+	val := ctx.Eval(node.Right)
+	if isError(val) {
+		return val
+	}
+	return ctx.bind(node.Left, val, false)
+}
+
+func (ctx *Context) evalLetStatement(node *ast.LetStatement) Value {
+	var val Value = ctx.g.NIL
+	binding := ast.Unparen(node.Binding)
+	if assign, ok := binding.(*ast.AssignmentExpression); ok {
+		val = ctx.Eval(assign.Right)
+		if isError(val) {
+			return val
+		}
+		binding = assign.Left
+	}
+	return ctx.bind(binding, val, true)
+}
+
+func (ctx *Context) evalForStatement(node *ast.ForStatement) Value {
+	iterable := ctx.Eval(node.Iterable)
+	if isError(iterable) {
+		return iterable
+	}
+	rv := ctx.iterate(iterable, func(val Value) Value {
+		scope := NewScope(ctx.scope)
+		outer := ctx.scope
+		ctx.scope = scope
+		rv := ctx.bind(node.Binding, val, true)
+		ctx.scope = outer
+		if isError(rv) {
+			return rv
+		}
+		rv = ctx.evalBlock(node.Body, NewScope(scope))
+		if _, cont := loopControl(rv, node.Label); cont {
+			return ctx.g.NIL
+		}
+		return rv
+	})
+	if brk, _ := loopControl(rv, node.Label); !brk && isUnwinding(rv) {
+		return rv
+	}
+	return ctx.g.NIL
+}
+
+// bind binds val to target, which is assignable (see ast.Assignable).
+// If declare is true, names are declared in the current scope instead
+// of being assigned to.
+func (ctx *Context) bind(target ast.Expression, val Value, declare bool) Value {
+	switch target := ast.Unparen(target).(type) {
+	case *ast.IdentifierLiteral:
+		nameFunction(val, target.Name())
+		if declare {
+			if err := ctx.declare(target.Name(), val); err != nil {
+				return err
+			}
+			return val
+		}
+		ctx.scope.Assign(target.Name(), val)
+		return val
+	case *ast.AttrExpression:
+		obj := ctx.Eval(target.Target)
+		if isError(obj) {
+			return obj
+		}
+		attrs, ok := attrsOf(obj)
+		if !ok {
+			return ctx.raisef(ctx.g.TypeError, "cannot set attributes on %s", obj.Klass().name)
+		}
+		attrs[target.Name.Name()] = val
+		return val
+	case *ast.IndexExpression:
+		obj := ctx.Eval(target.Target)
+		if isError(obj) {
+			return obj
+		}
+		args, err := ctx.evalArgs(target.Args)
+		if err != nil {
+			return err
+		}
+		rv := ctx.callMethod(obj, "[]=", append(args, val))
+		if isError(rv) {
+			return rv
+		}
+		return val
+	case *ast.ArrayLiteral:
+		// destructuring -- elements of the form `x = default` are
+		// used when val is too short.
+		arr, ok := val.(*Array)
+		if !ok {
+			return ctx.raisef(ctx.g.TypeError, "cannot destructure %s", val.Klass().name)
+		}
+		if len(arr.elems) > len(target.Elems) {
+			return ctx.raisef(ctx.g.ValueError, "too many values to destructure (expected %d, got %d)",
+				len(target.Elems), len(arr.elems))
+		}
+		for i, elem := range target.Elems {
+			var v Value
+			if i < len(arr.elems) {
+				v = arr.elems[i]
+			}
+			if assign, ok := ast.Unparen(elem).(*ast.AssignmentExpression); ok {
+				elem = assign.Left
+				if v == nil {
+					v = ctx.Eval(assign.Right)
+					if isError(v) {
+						return v
+					}
+				}
+			}
+			if v == nil {
+				return ctx.raisef(ctx.g.ValueError, "not enough values to destructure (expected %d, got %d)",
+					len(target.Elems), len(arr.elems))
+			}
+			if rv := ctx.bind(elem, v, declare); isError(rv) {
+				return rv
+			}
+		}
+		return val
+	}
+	return ctx.errorf("cannot assign to %s", target.Type())
+}
+
+func (ctx *Context) evalMapLiteral(node *ast.MapLiteral) Value {
+	m := ctx.g.NewMap()
+	for _, entry := range node.Entries {
+		var key Value
+		if ident, ok := entry.Key.(*ast.IdentifierLiteral); ok && !entry.Computed {
+			key = ctx.g.NewString(ident.Name())
+		} else {
+			key = ctx.Eval(entry.Key)
+			if isError(key) {
+				return key
+			}
+		}
+		val := ctx.Eval(entry.Value)
+		if isError(val) {
+			return val
+		}
+		if err := m.Set(ctx, key, val); err != nil {
+			return err
+		}
+	}
+	return m
+}
+
+// declare creates a new binding in the current scope.
+func (ctx *Context) declare(name string, val Value) *Error {
+	if _, ok := ctx.scope.values[name]; ok {
+		return ctx.raisef(ctx.g.NameError, "name %s is already declared", name)
+	}
+	ctx.scope.Set(name, val)
+	return nil
+}
+
+// evalArgs evaluates a list of arguments from left to right,
+// stopping at the first error.
+func (ctx *Context) evalArgs(nodes []ast.Expression) ([]Value, *Error) {
+	args := make([]Value, len(nodes))
+	for i, node := range nodes {
+		args[i] = ctx.Eval(node)
+		if err, ok := args[i].(*Error); ok {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// attrsOf returns the attributes of v, if v can have any.
+func attrsOf(v Value) (map[string]Value, bool) {
+	switch v := v.(type) {
+	case *Object:
+		return v.attrs, true
+	case *Exception:
+		return v.attrs, true
+	case *Class:
+		return v.attrs, true
+	}
+	return nil, false
+}
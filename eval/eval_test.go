@@ -0,0 +1,1096 @@
+package eval
+
+import (
+	"jingle/parser"
+	"jingle/scanner"
+	"testing"
+)
+
+func TestEvalNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5", "5"},
+		{"1.5", "1.5"},
+		{"-2", "-2"},
+		{"--2", "2"},
+		{"1 + 2 * 3", "7"},
+		{"(1 + 2) * 3", "9"},
+		{"10 / 4", "2.5"},
+		{"10 - 4 - 3", "3"},
+		{"-(1 + 2)", "-3"},
+		{"0xff + 0o7 + 0b1", "263"},
+		{"1_000 * 1e-3", "1.0"},
+		{"[4 / 2, 1 + 1.0, 2 * 0.5, 3 - 0.5, 1e3]", "[2.0, 2.0, 1.0, 2.5, 1000.0]"},
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"4294967296 * 4294967296", "18446744073709551616"},
+		{"(2 ** 64 - 1) - (2 ** 64 - 2)", "1"},
+		{"2 ** 100", "1267650600228229401496703205376"},
+		{"[2 ** 10, 2 ** -1, 2.0 ** 3, 4 ** 0.5, -2 ** 2, 2 ** 3 ** 2]", "[1024, 0.5, 8.0, 2.0, -4, 512]"},
+		{"[7.div(2), (-7).div(2), 7.div(-2), 7.5.div(2)]", "[3, -4, -4, 3.0]"},
+		{"[7 % 3, -7 % 3, 7 % -3, 7.5 % 2, -0.5 % 2]", "[1, 2, -2, 1.5, 1.5]"},
+		{"(2 ** 70 + 5) % 2 ** 35", "5"},
+		{"(-(2 ** 70)).div(3)", "-393530540239137101142"},
+		{"[6 & 3, 6 | 3, 6 ^ 3, ~5, 1 << 4, -16 >> 2, 1 >> 100]", "[2, 7, 5, -6, 16, -4, 0]"},
+		{"1 << 64", "18446744073709551616"},
+		{"[0 << 9223372036854775807, 1 ** 9223372036854775807, (-1) ** 9223372036854775807, 0 ** 9223372036854775807]", "[0, 1, -1, 0]"},
+		{"[(1 << 1000000) >> 999999, (2 ** 1000000) >> 999999]", "[2, 2]"},
+		{"(1 << 64) >> 63", "2"},
+		{"(2 ** 64) & (2 ** 64 + 1)", "18446744073709551616"},
+		{"~(2 ** 64)", "-18446744073709551617"},
+		{"[1.5.to_i(), -1.5.to_i(), 3.to_f(), 1e20.to_i()]", "[1, -1, 3.0, 100000000000000000000]"},
+	}
+	for i, tt := range tests {
+		if got := testInspect(t, tt.input); got != tt.expected {
+			t.Fatalf("test[%d] expected=%s, got=%s", i, tt.expected, got)
+		}
+	}
+}
+
+func TestEvalNumberErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / 0", "division by zero"},
+		{"1.5 % 0", "division by zero"},
+		{"1.div(0.0)", "division by zero"},
+		{"0 ** -1", "0 cannot be raised to a negative power"},
+		{"1 & 1.0", "unsupported operand types for &: Integer and Float"},
+		{"1.0 << 1", "unsupported operand types for <<: Float and Integer"},
+		{"1 + nil", "unsupported operand types for +: Integer and Nil"},
+		{`1.5 < "a"`, "unsupported operand types for <: Float and String"},
+		{"1 << -1", "negative shift count -1"},
+		{"1 << 9223372036854775807", "shift count 9223372036854775807 is too large"},
+		{"(2 ** 100) << (2 ** 24)", "shift count 16777216 is too large"},
+		{"3 ** 9223372036854775807", "exponent 9223372036854775807 is too large"},
+		{"(-2) ** (2 ** 24)", "exponent 16777216 is too large"},
+		{"~1.0", "undefined method ~ for Float"},
+		{"(1e308 * 10).to_i()", "cannot convert +Inf to an Integer"},
+	}
+	for i, tt := range tests {
+		testError(t, i, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 == 1", true},
+		{"1 == 2", false},
+		{"1 != 2", true},
+		{"1 <= 1", true},
+		{"2 >= 3", false},
+		{"1 == nil", false},
+		{"nil == nil", true},
+		{"nil != false", true},
+		{`"abc" == "abc"`, true},
+		{`"abc" != "abd"`, true},
+		{"!nil", true},
+		{"!0", false},
+		{"!!true", true},
+		{`2.inspect() == "2"`, true},
+		{"1 == 1.0", true},
+		{"0 == -0.0", true},
+		{"1 == 1.5", false},
+		{"9007199254740993 == 9007199254740992.0", false},
+		{"2 ** 64 == 18446744073709551616.0", true},
+		{"2 ** 64 < 1e300", true},
+		{"-(2 ** 64) > -1e308 * 10", true},
+		{"1 < 1.5", true},
+	}
+	for i, tt := range tests {
+		val := testEval(t, tt.input)
+		b, ok := val.(*Boolean)
+		if !ok {
+			t.Fatalf("test[%d] expected Boolean, got=%#v", i, val)
+		}
+		if b.b != tt.expected {
+			t.Fatalf("test[%d] expected=%t, got=%t", i, tt.expected, b.b)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / 0", "division by zero"},
+		{`1 + "a"`, "unsupported operand types for +: Integer and String"},
+		{`"a" - 1`, "undefined method - for String"},
+		{"x", "name x is undefined"},
+	}
+	for i, tt := range tests {
+		testError(t, i, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 5; x", 5},
+		{"let x = 5; x = x + 1; x", 6},
+		{`i = 0
+		sum = 0
+		while i < 5 do
+			i = i + 1
+			sum = sum + i
+		end
+		sum`, 15},
+		{`i = 0
+		while i < 3 do
+			let j = i * 2
+			i = i + 1
+		end
+		i`, 3},
+		{`x = 1
+		if true then
+			let x = 2
+		end
+		x`, 1},
+		{`f = fn(n)
+			while true do
+				if n > 10 then return n end
+				n = n * 2
+			end
+		end
+		f(3)`, 12},
+		{"1 if 2 > 1 else 3", 1},
+		{"nil or 4", 4},
+		{"1 and 5", 5},
+		{"let (x) = 2; (x) = (x) * 3; x", 6},
+		{"let [a, (b)] = [1, 2]; a + b", 3},
+	}
+	for i, tt := range tests {
+		val := testEval(t, tt.input)
+		num, ok := val.(*Integer)
+		if !ok {
+			t.Fatalf("test[%d] expected Integer, got=%#v", i, val)
+		}
+		if num.i != tt.expected {
+			t.Fatalf("test[%d] expected=%v, got=%v", i, tt.expected, num.i)
+		}
+	}
+}
+
+func TestEvalStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1; let x = 2", "name x is already declared"},
+		{"if true then let y = 1 end; y", "name y is undefined"},
+		{"while x do end", "name x is undefined"},
+	}
+	for i, tt := range tests {
+		testError(t, i, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"fn() 1 end()", 1},
+		{"fn(x) x end(5)", 5},
+		{"add = fn(a, b) a + b end; add(1, 2)", 3},
+		{"f = fn(x) return x * 2; 100 end; f(4)", 8},
+		{`f = fn(x)
+			if x == 0 then
+				if true then
+					return 10
+				end
+			end
+			20
+		end
+		f(0) + f(1)`, 30},
+		{`fib = fn(n)
+			if n == 0 or n == 1 then
+				return n
+			end
+			return fib(n - 1) + fib(n - 2)
+		end
+		fib(10)`, 55},
+		{`counter = fn()
+			n = 0
+			fn()
+				n = n + 1
+			end
+		end
+		c = counter()
+		c(); c()
+		d = counter()
+		d()
+		c()`, 3},
+		{`x = 1
+		f = fn() x = 2 end
+		f()
+		x`, 2},
+		{`f = fn() y = 2 end
+		f()
+		y = 5
+		y`, 5},
+	}
+	for i, tt := range tests {
+		val := testEval(t, tt.input)
+		num, ok := val.(*Integer)
+		if !ok {
+			t.Fatalf("test[%d] expected Integer, got=%#v", i, val)
+		}
+		if num.i != tt.expected {
+			t.Fatalf("test[%d] expected=%v, got=%v", i, tt.expected, num.i)
+		}
+	}
+}
+
+func TestEvalFunctionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a, b) a end(1)", "fn() takes 2 argument(s) but 1 were given"},
+		{"f = fn() y = 1 end; f(); y", "name y is undefined"},
+		{"f = fn() f() end; f()", "maximum call depth exceeded"},
+		{"1()", "Integer is not callable"},
+	}
+	for i, tt := range tests {
+		testError(t, i, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalClasses(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`class Point
+			def init(x, y)
+				self.x = x
+				self.y = y
+			end
+			def +(other)
+				Point.new(self.x + other.x, self.y + other.y)
+			end
+			def ==(other)
+				self.x == other.x and self.y == other.y
+			end
+			def norm1()
+				self.x + self.y
+			end
+		end
+		p = Point.new(1, 2) + Point.new(3, 4)
+		p.norm1()`, 10},
+		{`class A
+			def f() 1 end
+			def g() self.f() * 10 end
+		end
+		class B < A
+			def f() super.f() + 1 end
+		end
+		B.new().g()`, 20},
+		{`class A
+			def init(x) self.x = x end
+		end
+		class B < A
+			def init(x, y)
+				super.init(x)
+				self.y = y
+			end
+		end
+		b = B.new(1, 2)
+		b.x + b.y`, 3},
+		{`class Grid
+			def init() self.cells = Grid.new_cells() end
+			def [](i, j) self.cells * i + j end
+			def []=(i, v) self.cells = v end
+		end
+		Grid.new_cells = fn() 10 end
+		g = Grid.new()
+		g[0] = g[2, 3]
+		g.cells`, 23},
+		{`class A
+			self.count = 0
+			def init() A.count = A.count + 1 end
+		end
+		A.new(); A.new()
+		A.count`, 2},
+		{`class V
+			def init(n) self.n = n end
+			def ==(other) self.n == other.n end
+			def -() V.new(-self.n) end
+		end
+		a = V.new(1)
+		(1 if a != V.new(1) else 2) + (-a).n`, 1},
+	}
+	for i, tt := range tests {
+		val := testEval(t, tt.input)
+		num, ok := val.(*Integer)
+		if !ok {
+			t.Fatalf("test[%d] expected Integer, got=%#v", i, val)
+		}
+		if num.i != tt.expected {
+			t.Fatalf("test[%d] expected=%v, got=%v", i, tt.expected, num.i)
+		}
+	}
+}
+
+func TestEvalClassErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Number.new()", "cannot instantiate Number"},
+		{"class A < Number end; A.new()", "cannot instantiate A"},
+		{"class A < 1 end", "cannot inherit from Integer"},
+		{"class A end; A.new(1)", "init() takes 0 argument(s) but 1 were given"},
+		{"class A end; A.new().foo", "object does not have attr foo"},
+		{"class A end; A.new()[1]", "undefined method [] for A"},
+		{"(1).x = 2", "cannot set attributes on Integer"},
+		{`Number.get_method("+").bind("a")(1)`, "+() must be called on an instance of Number"},
+		{`Number.get_method("<").bind("a")(1)`, "<() must be called on an instance of Number"},
+		{`Integer.get_method("inspect")()`, "inspect() must be called on an instance of Integer"},
+	}
+	for i, tt := range tests {
+		testError(t, i, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalTraceback(t *testing.T) {
+	input := `f = fn(x)
+  g(x) + 1
+end
+class A
+  def go(x)
+    f(x)
+  end
+end
+g = fn(y) 1 / y end
+A.new().go(0)`
+	s := scanner.New("test.jg", input)
+	s.ScanAll()
+	program, errs := parser.New("test.jg", s.Tokens()).Parse()
+	if errs != nil {
+		t.Fatalf("cannot parse: %v", errs)
+	}
+	ctx := NewContext()
+	ctx.AddSource("test.jg", input)
+	err, ok := ctx.Eval(program).(*Error)
+	if !ok {
+		t.Fatalf("expected an error")
+	}
+	expected := []Frame{
+		{"g", "test.jg", 9, 13},
+		{"f", "test.jg", 2, 4},
+		{"A.go", "test.jg", 6, 6},
+		{"<main>", "test.jg", 10, 11},
+	}
+	if len(err.Trace) != len(expected) {
+		t.Fatalf("expected %d frames, got=%+v", len(expected), err.Trace)
+	}
+	for i, f := range expected {
+		if err.Trace[i] != f {
+			t.Errorf("frame[%d] expected=%+v, got=%+v", i, f, err.Trace[i])
+		}
+	}
+	formatted := ctx.FormatError(err)
+	expectedFormat := `Traceback (most recent call last):
+  File "test.jg", line 10, column 11, in <main>
+    A.new().go(0)
+              ^
+  File "test.jg", line 6, column 6, in A.go
+    f(x)
+     ^
+  File "test.jg", line 2, column 4, in f
+    g(x) + 1
+     ^
+  File "test.jg", line 9, column 13, in g
+    g = fn(y) 1 / y end
+                ^
+ZeroDivisionError: division by zero`
+	if formatted != expectedFormat {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expectedFormat, formatted)
+	}
+	trace, ok := ctx.lookupAttr(err.Reason, "trace")
+	if !ok || trace.(*String).s != ctx.traceback(err) {
+		t.Fatalf("expected err.trace to be the traceback, got=%#v", trace)
+	}
+}
+
+func TestEvalErrorClass(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`Error.new("oops").message`, "oops"},
+		{`class MyError < Error end; MyError.new("a").message`, "a"},
+		{`Error.new().message`, ""},
+	}
+	for i, tt := range tests {
+		val := testEval(t, tt.input)
+		str, ok := val.(*String)
+		if !ok {
+			t.Fatalf("test[%d] expected String, got=%#v", i, val)
+		}
+		if str.s != tt.expected {
+			t.Fatalf("test[%d] expected=%q, got=%q", i, tt.expected, str.s)
+		}
+	}
+}
+
+func TestEvalTryStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"try 1 catch 2 end", 1},
+		{"try raise \"x\" catch 2 end", 2},
+		{"try 1 / 0 catch e: ZeroDivisionError 2 catch 3 end", 2},
+		{"try x catch e: ZeroDivisionError 2 catch e: NameError 3 end", 3},
+		{"try x catch e: Error 4 end", 4},
+		{`class IOError < Error end
+		try
+			raise IOError.new("disk")
+		catch e: TypeError
+			1
+		catch e: IOError
+			2 if e.message == "disk" else 3
+		end`, 2},
+		{`x = 0
+		try
+			raise "a"
+		catch
+			x = x + 1
+		finally
+			x = x + 10
+		end
+		x`, 11},
+		{`x = 0
+		try
+			try 1 / 0 finally x = 1 end
+		catch
+			x = x + 1
+		end
+		x`, 2},
+		{`f = fn()
+			try return 1 finally return 2 end
+		end
+		f()`, 2},
+		{`x = 0
+		f = fn()
+			try return 1 finally x = 5 end
+		end
+		f() + x`, 6},
+		{`f = fn() raise "deep" end
+		g = fn() f() end
+		try g() catch e 7 end`, 7},
+		{`e = 1
+		try raise "x" catch e 2 end
+		e`, 1},
+	}
+	for i, tt := range tests {
+		val := testEval(t, tt.input)
+		num, ok := val.(*Integer)
+		if !ok {
+			t.Fatalf("test[%d] expected Integer, got=%#v", i, val)
+		}
+		if num.i != tt.expected {
+			t.Fatalf("test[%d] expected=%v, got=%v", i, tt.expected, num.i)
+		}
+	}
+}
+
+func TestEvalTryStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`raise "oops"`, "oops"},
+		{"raise 1", "cannot raise Integer, only Error instances"},
+		{"try 1 / 0 catch e: NameError 1 end", "division by zero"},
+		{`try raise "a" finally raise "b" end`, "b"},
+		{`try raise "a" catch e raise "b" end`, "b"},
+		{`try raise "a" catch e: 1 2 end`, "cannot catch Integer, only classes"},
+	}
+	for i, tt := range tests {
+		testError(t, i, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalArrays(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[]", "[]"},
+		{`[1, "a", [nil, true]]`, `[1, "a", [nil, true]]`},
+		{"a = [1, 2, 3]; a[0] + a[-1]", "4"},
+		{"a = [1, 2, 3, 4]; a[1, 3]", "[2, 3]"},
+		{"a = [1, 2, 3, 4]; a[-2, nil]", "[3, 4]"},
+		{"a = [1, 2, 3, 4]; a[3, 1]", "[]"},
+		{"a = [1, 2, 3, 4]; a[-10, 10]", "[1, 2, 3, 4]"},
+		{"a = [1, 2, 3]; a[-1] = 5; a", "[1, 2, 5]"},
+		{"a = [1, 2, 3, 4]; a[1, 3] = [9]; a", "[1, 9, 4]"},
+		{"[1, 2, 3].len()", "3"},
+		{"a = []; a.push(1, 2).push(3); a", "[1, 2, 3]"},
+		{"a = [1, 2]; [a.pop(), a]", "[2, [1]]"},
+		{"[1, 2, 3].map(fn(x) x * 2 end)", "[2, 4, 6]"},
+		{"[1, 2, 3, 4].filter(fn(x) x > 2 end)", "[3, 4]"},
+		{"[1, 2, 3].reduce(fn(a, b) a + b end)", "6"},
+		{"[].reduce(fn(a, b) a + b end, 10)", "10"},
+		{"[3, 1, 2].sort()", "[1, 2, 3]"},
+		{"[3, 1, 2].sort(fn(a, b) a > b end)", "[3, 2, 1]"},
+		{"s = 0; [1, 2, 3].each(fn(x) s = s + x end); s", "6"},
+		{`[1, "a", 2].join(", ")`, "\"1, a, 2\""},
+		{"[1, 2, 3].reverse()", "[3, 2, 1]"},
+		{"[[1], 2].contains([1])", "true"},
+		{"[1, 2].contains(3)", "false"},
+		{"[1, [2]] == [1, [2]]", "true"},
+		{"[1, 2] == [1]", "false"},
+		{"[1, 2] != [1, 3]", "true"},
+		{"s = 0; for x in [1, 2, 3] do s = s + x end; s", "6"},
+		{"a = [1]; n = 0; for x in a do if x < 3 then a.push(x + 1) end; n = n + 1 end; n", "3"},
+		{"s = []; for [a, b] in [[1, 2], [3, 4]] do s.push(a * b) end; s", "[2, 12]"},
+		{"[a, b] = [1, 2]; [a, b] = [b, a]; [a, b]", "[2, 1]"},
+		{"let [a, [b, c = 3]] = [1, [2]]; [a, b, c]", "[1, 2, 3]"},
+		{"class L < Array end; l = L.new(); l.push(1); l", "[1]"},
+	}
+	for i, tt := range tests {
+		if got := testInspect(t, tt.input); got != tt.expected {
+			t.Fatalf("test[%d] expected=%s, got=%s", i, tt.expected, got)
+		}
+	}
+}
+
+func TestEvalArrayErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2][2]", "index 2 out of range"},
+		{"[1, 2][-3]", "index -3 out of range"},
+		{"[1, 2][-5]", "index -5 out of range"},
+		{"[1, 2][0.5]", "indices must be Integers, not Float"},
+		{`[1, 2]["a"]`, "indices must be Integers, not String"},
+		{"[].pop()", "pop from empty Array"},
+		{"[1].join(2)", "join() expects a String, got Integer"},
+		{"[].reduce(fn(a, b) a end)", "reduce() of empty Array with no initial value"},
+		{"[1, nil].sort()", "undefined method < for Nil"},
+		{"[1].map(fn(x) x.y end)", "object does not have attr y"},
+		{"for x in 1 do end", "Integer is not iterable"},
+		{"[a, b] = [1]", "not enough values to destructure (expected 2, got 1)"},
+		{"[a] = [1, 2]", "too many values to destructure (expected 1, got 2)"},
+		{"[a] = 1", "cannot destructure Integer"},
+	}
+	for i, tt := range tests {
+		testError(t, i, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalMaps(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{}", "{}"},
+		{`{a: 1, "b": 2, [1 + 1]: 3}`, `{"a": 1, "b": 2, 2: 3}`},
+		{`k = "x"; {[k]: 1, k: 2}`, `{"x": 1, "k": 2}`},
+		{`{a: 1, a: 2}`, `{"a": 2}`},
+		{`m = {[1]: "a"}; m[1.0] = "b"; m[-0.0] = "c"; m[0] = "d"; m`, `{1: "b", -0.0: "d"}`},
+		{`m = {[2 ** 70]: 1}; [m[2 ** 70], m.has(2.0 ** 70), m.has(2 ** 70 + 1)]`, "[1, true, false]"},
+		{`m = {a: 1}; m["a"]`, "1"},
+		{`m = {}; m[0] = "x"; m[-0]`, `"x"`},
+		{`m = {b: 1}; m["a"] = 2; m["b"] = 3; m`, `{"b": 3, "a": 2}`},
+		{`{a: 1, b: 2}.len()`, "2"},
+		{`{a: 1, b: 2}.keys()`, `["a", "b"]`},
+		{`{a: 1, b: 2}.values()`, "[1, 2]"},
+		{`m = {a: 1, b: 2, c: 3}; [m.delete("b"), m]`, `[2, {"a": 1, "c": 3}]`},
+		{`m = {a: 1}; m.delete("a"); m["a"] = 2; m`, `{"a": 2}`},
+		{`m = {a: 1}; [m.has("a"), m.has("b")]`, "[true, false]"},
+		{`m = {a: 1}; [m.get("a"), m.get("b"), m.get("b", 0)]`, "[1, nil, 0]"},
+		{`{a: 1, b: 2}.merge({b: 3, c: 4})`, `{"a": 1, "b": 3, "c": 4}`},
+		{`{a: 1, b: [2]} == {b: [2], a: 1}`, "true"},
+		{`{a: 1} == {a: 2}`, "false"},
+		{`{a: 1} == {b: 1}`, "false"},
+		{`m = {}; for i in [0, 1, 2, 3, 4, 5, 6, 7, 8, 9] do m[i] = i end
+		for i in [0, 1, 2, 3, 4, 5, 6, 7] do m.delete(i) end
+		m[0] = 0; m`, "{8: 8, 9: 9, 0: 0}"},
+		{`class P
+			def init(x, y) self.x = x; self.y = y end
+			def hash() self.x end
+			def ==(other) self.x == other.x and self.y == other.y end
+		end
+		m = {[P.new(1, 2)]: "a"}
+		m[P.new(1, 3)] = "b"
+		m[P.new(1, 2)] = "c"
+		[m.len(), m[P.new(1, 2)], m[P.new(1, 3)]]`, `[2, "c", "b"]`},
+		{`class K end; k = K.new(); m = {[k]: 1}; [m.has(k), m.has(K.new())]`, "[true, false]"},
+	}
+	for i, tt := range tests {
+		if got := testInspect(t, tt.input); got != tt.expected {
+			t.Fatalf("test[%d] expected=%s, got=%s", i, tt.expected, got)
+		}
+	}
+}
+
+func TestEvalMapErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{a: 1}["b"]`, `key "b" not found`},
+		{`{}.delete(1)`, "key 1 not found"},
+		{`{}.merge([])`, "merge() expects a Map, got Array"},
+		{`{[[1]]: 1}`, "Array cannot be used as a key"},
+		{`{}[{}] = 1`, "Map cannot be used as a key"},
+		{`class A def hash() nil end end; {[A.new()]: 1}`, "hash() should return a Number or String, got Nil"},
+		{`{a: x}`, "name x is undefined"},
+	}
+	for i, tt := range tests {
+		testError(t, i, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalIteration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`s = []; for c in "héllo" do s.push(c) end; s`, `["h", "é", "l", "l", "o"]`},
+		{`s = []; for k, v in {a: 1, b: 2} do s.push(k, v) end; s`, `["a", 1, "b", 2]`},
+		{`s = []; for p in {a: 1} do s.push(p) end; s`, `[["a", 1]]`},
+		{`m = {a: 1, b: 2, c: 3}; s = []
+		for k, v in m do
+			m.delete("b") if m.has("b")
+			m["d"] = 4
+			s.push(k)
+		end
+		[s, m]`, `[["a", "c"], {"a": 1, "c": 3, "d": 4}]`},
+		{`it = [1, 2].iter(); [it.next(), it.next()]`, "[1, 2]"},
+		{`it = [1].iter(); s = []; for x in it do s.push(x) end; for x in it do s.push(x) end; s`, "[1]"},
+		{`it = [].iter(); try it.next() catch e: StopIteration "done" end`, `"done"`},
+		{`class Countdown
+			def init(n) self.n = n end
+			def iter() self end
+			def next()
+				if self.n == 0 then raise StopIteration.new() end
+				self.n = self.n - 1
+				self.n + 1
+			end
+		end
+		s = []; for x in Countdown.new(3) do s.push(x) end; s`, "[3, 2, 1]"},
+		{`class Pairs
+			def iter() [[1, 2], [3, 4]].iter() end
+		end
+		s = 0; for a, b in Pairs.new() do s = s + a * b end; s`, "14"},
+		{`f = fn() for x in [1, 2, 3] do if x == 2 then return x end end end; f()`, "2"},
+	}
+	for i, tt := range tests {
+		if got := testInspect(t, tt.input); got != tt.expected {
+			t.Fatalf("test[%d] expected=%s, got=%s", i, tt.expected, got)
+		}
+	}
+}
+
+func TestEvalIterationErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for x in nil do end", "Nil is not iterable"},
+		{"class A def iter() 1 end end; for x in A.new() do end", "iter() should return an iterator, got Integer"},
+		{`class A
+			def iter() self end
+			def next() raise "broken" end
+		end
+		for x in A.new() do end`, "broken"},
+		{"for k, v in [1] do end", "cannot destructure Integer"},
+		{"[].iter().next()", "iterator is exhausted"},
+	}
+	for i, tt := range tests {
+		testError(t, i, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalBreakContinue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"i = 0; while true do i = i + 1; if i == 3 then break end end; i", "3"},
+		{`s = []; for x in [1, 2, 3, 4] do if x == 2 then continue end; s.push(x) end; s`, "[1, 3, 4]"},
+		{`s = []; for x in [1, 2, 3, 4] do if x > 2 then break end; s.push(x) end; s`, "[1, 2]"},
+		{`i = 0; s = []
+		while i < 5 do
+			i = i + 1
+			if i == 2 or i == 4 then continue end
+			s.push(i)
+		end
+		s`, "[1, 3, 5]"},
+		{`s = []
+		outer: for x in [1, 2, 3] do
+			for y in [1, 2, 3] do
+				if y == 2 then continue outer end
+				if x == 3 then break outer end
+				s.push([x, y])
+			end
+		end
+		s`, "[[1, 1], [2, 1]]"},
+		{`s = []
+		for x in [1, 2] do
+			inner: for y in [1, 2] do
+				if false then break inner end
+				break
+			end
+			s.push(x)
+		end
+		s`, "[1, 2]"},
+		{`s = []
+		for x in [1, 2, 3] do
+			try
+				if x == 2 then continue end
+				s.push(x)
+			finally
+				s.push("f")
+			end
+		end
+		s`, `[1, "f", "f", 3, "f"]`},
+		{`f = fn()
+			for x in [1, 2, 3] do
+				while true do break end
+				if x == 2 then return x end
+			end
+		end
+		f()`, "2"},
+		{`for x in [1] do break end`, "nil"},
+	}
+	for i, tt := range tests {
+		if got := testInspect(t, tt.input); got != tt.expected {
+			t.Fatalf("test[%d] expected=%s, got=%s", i, tt.expected, got)
+		}
+	}
+}
+
+func TestEvalRanges(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1..5", "1..5"},
+		{"(1...5).step(2)", "(1...5).step(2)"},
+		{"(1..5).to_array()", "[1, 2, 3, 4, 5]"},
+		{"(1...5).to_array()", "[1, 2, 3, 4]"},
+		{"(5..1).to_array()", "[]"},
+		{"(5..1).step(-2).to_array()", "[5, 3, 1]"},
+		{"(1...10).step(3).to_array()", "[1, 4, 7]"},
+		{"(0..1).step(0.25).to_array()", "[0.0, 0.25, 0.5, 0.75, 1.0]"},
+		{"[(1..5).len(), (1...5).len(), (1...1).len(), (1..10).step(3).len()]", "[5, 4, 0, 4]"},
+		{"r = (1..9).step(2); [r.contains(5), r.contains(6), r.contains(11), r.contains(-1)]", "[true, false, false, false]"},
+		{"[(1...5).contains(5), (1..5).contains(5), (1..5).contains(nil)]", "[false, true, false]"},
+		{"s = 0; for i in 1..100 do s = s + i end; s", "5050"},
+		{"n = 3; s = []; for i in 0...n do s.push(i * i) end; s", "[0, 1, 4]"},
+		{"[(0..3000000000).len(), (0...3000000000).step(7).len(), (0..2**70).len()]", "[3000000001, 428571429, 1180591620717411303425]"},
+		{"r = 0..3000000000; [r.contains(3000000000), r.contains(3000000001)]", "[true, false]"},
+		{"s = 0; for i in (0..3000000000).step(1000000000) do s = s + i end; s", "6000000000"},
+		{"(2**60..2**60+3).to_array()", "[1152921504606846976, 1152921504606846977, 1152921504606846978, 1152921504606846979]"},
+		{"(2**70...2**70+6).step(2).to_array()", "[1180591620717411303424, 1180591620717411303426, 1180591620717411303428]"},
+		{"(2**63-2..2**63+1).to_array()", "[9223372036854775806, 9223372036854775807, 9223372036854775808, 9223372036854775809]"},
+		{"(2**64..2**64-4).step(-3).to_array()", "[18446744073709551616, 18446744073709551613]"},
+		{"s = []; for i in 2**60+1..2**60+2 do s.push(i - 2**60) end; s", "[1, 2]"},
+		{"r = (2**60..2**61).step(3); [r.len(), r.contains(2**60 + 3), r.contains(2**60 + 4), r.contains(2.0**60)]", "[384307168202282326, true, false, true]"},
+		{"[2**60..2**60+1, (0..2**70).step(2**65)]", "[1152921504606846976..1152921504606846977, (0..1180591620717411303424).step(36893488147419103232)]"},
+		{"[1, 2, 3][1..2**70]", "[2, 3]"},
+		{"[1, 2, 3][-(2**70)..0]", "[1]"},
+		{"[1, 2, 3][5..7]", "[]"},
+		{"[1, 2, 3][(0..2).step(2**70)]", "[1]"},
+		{"[(2**60..2**60+1) == (2**60..2**60+1), (2**60..2**60+1) == (2**60..2**60+2)]", "[true, false]"},
+		{"(1..3) == (1..3)", "true"},
+		{"(1..3) == (1...3)", "false"},
+		{"a = [1, 2, 3, 4, 5]; [a[1..3], a[1...3], a[-2..-1], a[3..10], a[3..1]]", "[[2, 3, 4], [2, 3], [4, 5], [4, 5], []]"},
+		{"[1, 2, 3, 4, 5][(0..-1).step(2)]", "[1, 3, 5]"},
+		{"a = [1, 2, 3, 4]; a[1..2] = [9]; a", "[1, 9, 4]"},
+		{`["héllo"[1..3], "héllo"[-1], "héllo"[1, 3], "abc"[(0..-1).step(2)]]`, `["éll", "o", "él", "ac"]`},
+	}
+	for i, tt := range tests {
+		if got := testInspect(t, tt.input); got != tt.expected {
+			t.Fatalf("test[%d] expected=%s, got=%s", i, tt.expected, got)
+		}
+	}
+}
+
+func TestEvalRangeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`1.."a"`, "unsupported operand types for ..: Integer and String"},
+		{"(1..2).step(0)", "step cannot be 0"},
+		{`(1..2).step("a")`, "step must be a Number, not String"},
+		{"(0..2**30).to_array()", "0..1073741824 is too long for an Array"},
+		{"(0..2**70).to_array()", "0..1180591620717411303424 is too long for an Array"},
+		{"[1, 2][(0..1).step(-(2**70))]", "cannot slice with a step of -1180591620717411303424"},
+		{"[1, 2][(0..1).step(-1)]", "cannot slice with a step of -1"},
+		{"[1, 2][0.5..1]", "indices must be Integers, not Float"},
+		{"a = [1, 2, 3]; a[(0..2).step(2)] = []", "cannot assign to a slice with a step of 2"},
+	}
+	for i, tt := range tests {
+		testError(t, i, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestRangeIterAllocs(t *testing.T) {
+	it, ok := testEval(t, "(0...1000).iter()").(*Iterator)
+	if !ok {
+		t.Fatalf("expected Iterator")
+	}
+	allocs := testing.AllocsPerRun(500, func() {
+		if _, ok, err := it.next(); !ok || err != nil {
+			t.Fatalf("iterator ran out")
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations per element, got %v", allocs)
+	}
+}
+
+func BenchmarkRangeIter(b *testing.B) {
+	ctx := NewContext()
+	r := ctx.g.NewRange(ctx.g.NewInteger(0), ctx.g.NewInteger(1000), ctx.g.NewInteger(1), true)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		it := ctx.callMethod(r, "iter", nil).(*Iterator)
+		for {
+			if _, ok, _ := it.next(); !ok {
+				break
+			}
+		}
+	}
+}
+
+func TestEvalInterpolatedString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`name = "bob"; n = 2; "hello ${name}, you have ${n + 1} items"`, "hello bob, you have 3 items"},
+		{`"${nil} ${true} ${[1, "a"]} ${{k: "v"}}"`, `nil true [1, "a"] {"k": "v"}`},
+		{`m = {k: "v"}; "${m["k"]}${"${m["k"]}"}"`, "vv"},
+		{`class P def to_s() "a P" end end; "<${P.new()}>"`, "<a P>"},
+		{`class Q def inspect() "a Q" end end; "<${Q.new()}>"`, "<a Q>"},
+		{`"\${x} $x ${"}"}"`, "${x} $x }"},
+		{`["a", 1, nil].join("-")`, "a-1-nil"},
+	}
+	for i, tt := range tests {
+		val := testEval(t, tt.input)
+		str, ok := val.(*String)
+		if !ok {
+			t.Fatalf("test[%d] expected String, got=%#v", i, val)
+		}
+		if str.s != tt.expected {
+			t.Fatalf("test[%d] expected=%q, got=%q", i, tt.expected, str.s)
+		}
+	}
+	testError(t, 0, testEval(t, `class P def to_s() 1 end end; "${P.new()}"`), "to_s() should return a String, got Integer")
+	testError(t, 1, testEval(t, `"${x}"`), "name x is undefined")
+
+	// errors are located within the embedded expression.
+	val := testEval(t, "x = 1\n\"a ${x / 0}\"")
+	testError(t, 2, val, "division by zero")
+	if f := val.(*Error).Trace[0]; f.LineNo != 2 || f.Column != 8 {
+		t.Fatalf("expected error at 2:8, got=%d:%d", f.LineNo, f.Column)
+	}
+}
+
+func TestEvalStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`["abc".len(), "héllo".len(), "".len()]`, "[3, 5, 0]"},
+		{`["héllo"[0], "héllo"[1], "héllo"[-1], "abc"[0, 2]]`, `["h", "é", "o", "ab"]`},
+		{`"hé".bytes()`, "[104, 195, 169]"},
+		{`"héllo".chars()`, `["h", "é", "l", "l", "o"]`},
+		{`["Héllo".upper(), "HÉLLO".lower()]`, `["HÉLLO", "héllo"]`},
+		{`["  a b\tc ".split(), "a,b,,c".split(","), "abc".split("")]`, `[["a", "b", "c"], ["a", "b", "", "c"], ["a", "b", "c"]]`},
+		{`[" a b ".strip(), "xxaxx".strip("x")]`, `["a b", "a"]`},
+		{`["aaa".replace("a", "b"), "aaa".replace("a", "b", 2)]`, `["bbb", "bba"]`},
+		{`["hello".starts_with("he"), "hello".starts_with("lo"), "hello".ends_with("lo")]`, "[true, false, true]"},
+		{`["héllo".find("l"), "héllo".find("z"), "abc".find("")]`, "[2, -1, 0]"},
+		{`["ab" + "cd", "ab" * 3, "ab" * 0, "" * (2 ** 70)]`, `["abcd", "ababab", "", ""]`},
+		{`["a" < "b", "b" < "a", "a" <= "a", "b" > "a", "ab" >= "b", "é" > "z"]`, "[true, false, true, true, false, true]"},
+		{`"{} + {} = {}".format(1, 2, 3)`, `"1 + 2 = 3"`},
+		{`"{1}{0}{1}".format("a", "b")`, `"bab"`},
+		{`"{{{}}}".format([1, "a"])`, `"{[1, \"a\"]}"`},
+		{`s = ""; for c in "hé!" do s = s + c + "." end; s`, `"h.é.!."`},
+		{`"abc".to_s()`, `"abc"`},
+		{"caf\u00e9 = \"a\"; cafe\u0301 + {cafe\u0301: 1}.keys()[0]", `"acafé"`},
+	}
+	for i, tt := range tests {
+		if got := testInspect(t, tt.input); got != tt.expected {
+			t.Fatalf("test[%d] expected=%s, got=%s", i, tt.expected, got)
+		}
+	}
+}
+
+func TestEvalStringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a" + 1`, "unsupported operand types for +: String and Integer"},
+		{`"a" * "b"`, "unsupported operand types for *: String and String"},
+		{`"a" * -1`, "cannot repeat a String -1 times"},
+		{`"a" * -(2 ** 70)`, "cannot repeat a String -1180591620717411303424 times"},
+		{`"ab" * (2 ** 62)`, "String repeated 4611686018427387904 times is too long"},
+		{`"ab" * (2 ** 70)`, "String repeated 1180591620717411303424 times is too long"},
+		{`"a" * 1.5`, "unsupported operand types for *: String and Float"},
+		{`"a" < 1`, "unsupported operand types for <: String and Integer"},
+		{`"héllo"[5]`, "index 5 out of range"},
+		{`"{} {}".format(1)`, "format() needs argument 1, but only 1 were given"},
+		{`"{x}".format(1)`, "invalid field {x} in format string"},
+		{`"{".format()`, "unclosed { in format string"},
+		{`"}".format()`, "single } in format string"},
+		{`"a".split(1)`, "split() expects a String, got Integer"},
+		{`"a".strip(nil)`, "strip() expects a String, got Nil"},
+		{`"a".replace("a", 1)`, "replace() expects a String, got Integer"},
+		{`["a".starts_with(1), "a".ends_with(1), "a".find(1)]`, "starts_with() expects a String, got Integer"},
+		{`"a".ends_with([])`, "ends_with() expects a String, got Array"},
+		{`"a".find(nil)`, "find() expects a String, got Nil"},
+	}
+	for i, tt := range tests {
+		testError(t, i, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalNamesAndAttrs(t *testing.T) {
+	s := scanner.New("", `
+class Point
+  def init(x)
+    self.x = x
+  end
+  def norm() self.x end
+end
+let p = Point.new(3)`)
+	s.ScanAll()
+	program, errs := parser.New("", s.Tokens()).Parse()
+	if errs != nil {
+		t.Fatalf("cannot parse: %v", errs)
+	}
+	ctx := NewContext()
+	if val := ctx.Eval(program); isError(val) {
+		t.Fatalf("cannot eval: %s", ctx.FormatError(val.(*Error)))
+	}
+	names := ctx.Scope().Names()
+	for _, name := range []string{"Point", "p", "Object", "Integer"} {
+		if !contains(names, name) {
+			t.Errorf("expected %s in names, got=%v", name, names)
+		}
+	}
+	p, _ := ctx.Scope().Get("p")
+	attrs := ctx.Attrs(p)
+	for _, name := range []string{"x", "norm", "init", "inspect"} {
+		if !contains(attrs, name) {
+			t.Errorf("expected %s in attrs, got=%v", name, attrs)
+		}
+	}
+	for i := 1; i < len(attrs); i++ {
+		if attrs[i-1] >= attrs[i] {
+			t.Fatalf("expected sorted attrs without duplicates, got=%v", attrs)
+		}
+	}
+	if str, err := ctx.Inspect(p); err != nil || str == "" {
+		t.Fatalf("cannot inspect p: %v", err)
+	}
+}
+
+func TestEvalArgs(t *testing.T) {
+	s := scanner.New("", `[ARGV.len(), ARGV[0], ARGV[1]]`)
+	s.ScanAll()
+	program, errs := parser.New("", s.Tokens()).Parse()
+	if errs != nil {
+		t.Fatalf("cannot parse: %v", errs)
+	}
+	ctx := NewContext()
+	ctx.SetArgs([]string{"a", "b c"})
+	str, err := ctx.inspect(ctx.Eval(program))
+	if err != nil {
+		t.Fatalf("cannot inspect: %s", ctx.FormatError(err))
+	}
+	if expected := `[2, "a", "b c"]`; str != expected {
+		t.Fatalf("expected=%s, got=%s", expected, str)
+	}
+	if got := testInspect(t, "ARGV"); got != "[]" {
+		t.Fatalf("expected ARGV to be empty by default, got=%s", got)
+	}
+}
+
+// ==================
+// Utils
+// ==================
+
+func testEval(t *testing.T, input string) Value {
+	s := scanner.New("", input)
+	s.ScanAll()
+	if s.Errors() != nil {
+		t.Fatalf("cannot scan %q: %v", input, s.Errors())
+	}
+	program, errs := parser.New("", s.Tokens()).Parse()
+	if errs != nil {
+		t.Fatalf("cannot parse %q: %v", input, errs)
+	}
+	return NewContext().Eval(program)
+}
+
+// testInspect evaluates input and returns the inspected result.
+func testInspect(t *testing.T, input string) string {
+	s := scanner.New("", input)
+	s.ScanAll()
+	if s.Errors() != nil {
+		t.Fatalf("cannot scan %q: %v", input, s.Errors())
+	}
+	program, errs := parser.New("", s.Tokens()).Parse()
+	if errs != nil {
+		t.Fatalf("cannot parse %q: %v", input, errs)
+	}
+	ctx := NewContext()
+	val := ctx.Eval(program)
+	if err, ok := val.(*Error); ok {
+		t.Fatalf("cannot eval %q: %s", input, ctx.FormatError(err))
+	}
+	str, err := ctx.inspect(val)
+	if err != nil {
+		t.Fatalf("cannot inspect %#v: %s", val, ctx.FormatError(err))
+	}
+	return str
+}
+
+func testError(t *testing.T, i int, val Value, msg string) {
+	err, ok := val.(*Error)
+	if !ok {
+		t.Fatalf("test[%d] expected Error, got=%#v", i, val)
+	}
+	reason, ok := err.Reason.(*Exception)
+	if !ok {
+		t.Fatalf("test[%d] expected Exception, got=%#v", i, err.Reason)
+	}
+	if got := reason.attrs["message"].(*String).s; got != msg {
+		t.Fatalf("test[%d] expected=%q, got=%q", i, msg, got)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, elem := range list {
+		if elem == s {
+			return true
+		}
+	}
+	return false
+}
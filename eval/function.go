@@ -0,0 +1,118 @@
+package eval
+
+import (
+	"fmt"
+	"jingle/ast"
+)
+
+// maxDepth is the maximum number of nested calls before we give up
+// instead of overflowing the Go stack.
+const maxDepth = 2000
+
+// Function is a function (or method) written in jingle. It closes
+// over the scope it was defined in.
+type Function struct {
+	Basic
+	name     string // empty for anonymous functions
+	filename string // file that the function was defined in
+	params   []*ast.IdentifierLiteral
+	body     *ast.Block
+	scope    *Scope // the defining scope
+	this     Value  // bound receiver, if any
+	owner    *Class // class that the method was declared in, if any
+}
+
+func (g *GlobalObjects) NewFunction(name string, node *ast.FunctionLiteral, scope *Scope) *Function {
+	return &Function{
+		Basic:    Basic{klass: g.Function},
+		name:     name,
+		filename: g.ctx.frame().filename,
+		params:   node.Params,
+		body:     node.Body,
+		scope:    scope,
+	}
+}
+
+// Name returns the name of fn, as shown in tracebacks.
+func (fn *Function) Name() string {
+	name := fn.name
+	if name == "" {
+		name = "fn"
+	}
+	if fn.owner != nil {
+		return fn.owner.name + "." + name
+	}
+	return name
+}
+
+// nameFunction gives v a name if it is an anonymous function,
+// so that `f = fn() ... end` shows up as f in tracebacks.
+func nameFunction(v Value, name string) {
+	if fn, ok := v.(*Function); ok && fn.name == "" {
+		fn.name = name
+	}
+}
+
+func (fn *Function) Bind(this Value) *Function {
+	if fn.this != nil {
+		return fn
+	}
+	bound := *fn
+	bound.this = this
+	return &bound
+}
+
+// returnValue wraps the value of a return statement while it
+// unwinds the enclosing blocks up to the function call.
+type returnValue struct {
+	Basic
+	value Value
+}
+
+func (g *GlobalObjects) initFunction() {
+	g.Function = g.NewClass("Function", g.Object)
+	g.Function.alloc = noAlloc
+	g.defineMethod(g.Function, "inspect", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NewString(fmt.Sprintf("<fn %s>", ref.this.(*Function).Name()))
+	})
+	g.defineMethod(g.Function, "bind", 1, func(ref *NativeFunction, args []Value) Value {
+		return ref.this.(*Function).Bind(args[0])
+	})
+}
+
+// callFunction binds args to the parameters of fn in a fresh scope,
+// and evaluates the body of fn.
+func (ctx *Context) callFunction(fn *Function, args []Value) Value {
+	if len(args) != len(fn.params) {
+		return ctx.raisef(ctx.g.ArgumentError, "%s() takes %d argument(s) but %d were given",
+			fn.Name(), len(fn.params), len(args))
+	}
+	if len(ctx.frames) >= maxDepth {
+		return ctx.errorf("maximum call depth exceeded")
+	}
+	scope := NewFunctionScope(fn.scope)
+	if fn.this != nil {
+		scope.Set("self", fn.this)
+		if fn.owner != nil && fn.owner.super != nil {
+			scope.Set("super", &Super{
+				Basic: Basic{klass: ctx.g.Object},
+				this:  fn.this,
+				start: fn.owner.super,
+			})
+		}
+	}
+	for i, param := range fn.params {
+		scope.Set(param.Name(), args[i])
+	}
+	ctx.frames = append(ctx.frames, frame{name: fn.Name(), filename: fn.filename})
+	rv := ctx.evalBlock(fn.body, scope)
+	ctx.frames = ctx.frames[:len(ctx.frames)-1]
+	switch rv := rv.(type) {
+	case *returnValue:
+		return rv.value
+	case *Error:
+		// the caller has to record where it called us from.
+		rv.pending = true
+	}
+	return rv
+}
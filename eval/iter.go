@@ -0,0 +1,118 @@
+package eval
+
+// The iteration protocol: an iterable value has an iter method that
+// returns an iterator. Calling next on the iterator returns the next
+// value, or raises StopIteration once it is exhausted.
+
+// Iterator is an iterator implemented in Go. next returns false
+// instead of raising StopIteration once the iterator is exhausted,
+// so that for loops over native iterables do not have to allocate
+// an error at the end.
+type Iterator struct {
+	Basic
+	next func() (Value, bool, *Error)
+}
+
+func (g *GlobalObjects) NewIterator(next func() (Value, bool, *Error)) *Iterator {
+	return &Iterator{Basic: Basic{klass: g.Iterator}, next: next}
+}
+
+func (g *GlobalObjects) initIterator() {
+	g.Iterator = g.NewClass("Iterator", g.Object)
+	g.Iterator.alloc = noAlloc
+	g.defineMethod(g.Iterator, "next", 0, func(ref *NativeFunction, args []Value) Value {
+		val, ok, err := ref.this.(*Iterator).next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return g.ctx.raisef(g.StopIteration, "iterator is exhausted")
+		}
+		return val
+	})
+	g.defineMethod(g.Iterator, "iter", 0, func(ref *NativeFunction, args []Value) Value {
+		return ref.this
+	})
+	g.defineMethod(g.Array, "iter", 0, func(ref *NativeFunction, args []Value) Value {
+		arr := ref.this.(*Array)
+		i := 0
+		// the array may be modified while iterating over it, so we
+		// have to check the length every time.
+		return g.NewIterator(func() (Value, bool, *Error) {
+			if i >= len(arr.elems) {
+				return nil, false, nil
+			}
+			i++
+			return arr.elems[i-1], true, nil
+		})
+	})
+	// iterating over a Map yields [key, value] pairs. Entries that are
+	// added while iterating are not visited.
+	g.defineMethod(g.Map, "iter", 0, func(ref *NativeFunction, args []Value) Value {
+		entries := append([]*mapEntry{}, ref.this.(*Map).entries...)
+		i := 0
+		return g.NewIterator(func() (Value, bool, *Error) {
+			for ; i < len(entries); i++ {
+				if entry := entries[i]; entry != nil && !entry.deleted {
+					i++
+					return g.NewArray([]Value{entry.key, entry.value}), true, nil
+				}
+			}
+			return nil, false, nil
+		})
+	})
+	g.defineMethod(g.String, "iter", 0, func(ref *NativeFunction, args []Value) Value {
+		str := ref.this.(*String)
+		n := str.len()
+		i := 0
+		return g.NewIterator(func() (Value, bool, *Error) {
+			if i >= n {
+				return nil, false, nil
+			}
+			i++
+			return g.NewString(str.slice(i-1, i)), true, nil
+		})
+	})
+}
+
+// iterate calls fn with every value produced by iterable, stopping
+// early if fn returns an error or a return value.
+func (ctx *Context) iterate(iterable Value, fn func(Value) Value) Value {
+	if _, ok := ctx.lookupAttr(iterable, "iter"); !ok {
+		return ctx.raisef(ctx.g.TypeError, "%s is not iterable", iterable.Klass().name)
+	}
+	it := ctx.callMethod(iterable, "iter", []Value{})
+	if isError(it) {
+		return it
+	}
+	if it, ok := it.(*Iterator); ok {
+		for {
+			val, ok, err := it.next()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return ctx.g.NIL
+			}
+			if rv := fn(val); isUnwinding(rv) {
+				return rv
+			}
+		}
+	}
+	next, ok := ctx.lookupAttr(it, "next")
+	if !ok {
+		return ctx.raisef(ctx.g.TypeError, "iter() should return an iterator, got %s", it.Klass().name)
+	}
+	for {
+		val := ctx.call(next, []Value{})
+		if err, ok := val.(*Error); ok {
+			if err.Reason.Klass().isSubclassOf(ctx.g.StopIteration) {
+				return ctx.g.NIL
+			}
+			return err
+		}
+		if rv := fn(val); isUnwinding(rv) {
+			return rv
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package eval
+
+import (
+	"math"
+	"strings"
+)
+
+// Map *value*. Entries are kept in insertion order, and are found
+// through a Go map from hash keys (see hashKey) to the entries that
+// share the hash key.
+type Map struct {
+	Basic
+	entries []*mapEntry // in insertion order; nil where deleted
+	buckets map[interface{}][]*mapEntry
+	size    int
+}
+
+type mapEntry struct {
+	key     Value
+	value   Value
+	index   int  // position in Map.entries
+	deleted bool // for iterators that still refer to the entry
+}
+
+func (g *GlobalObjects) NewMap() *Map {
+	return &Map{
+		Basic:   Basic{klass: g.Map},
+		buckets: map[interface{}][]*mapEntry{},
+	}
+}
+
+// bigKey is the hash key of an Integer that does not fit in an int64.
+type bigKey string
+
+// hashKey returns a Go value such that keys which are == have equal
+// hash keys. Strings and Numbers are hashed by value, objects that
+// define a hash method by its result, and everything else by
+// identity.
+func (ctx *Context) hashKey(key Value) (interface{}, *Error) {
+	switch key := key.(type) {
+	case *String:
+		return key.s, nil
+	case *Integer:
+		if key.big != nil {
+			return bigKey(key.big.String()), nil
+		}
+		return key.i, nil
+	case *Float:
+		// whole Floats are equal to Integers, so they have to hash
+		// to the same key.
+		f := key.f
+		if f != math.Trunc(f) || math.IsInf(f, 0) {
+			return f, nil
+		}
+		if -1<<63 <= f && f < 1<<63 {
+			return int64(f), nil // -0 == 0
+		}
+		return bigKey(floatToBig(f).String()), nil
+	case *Array, *Map:
+		return nil, ctx.raisef(ctx.g.TypeError, "%s cannot be used as a key", key.Klass().name)
+	}
+	meth, ok := ctx.lookupAttr(key, "hash")
+	if !ok {
+		return key, nil
+	}
+	rv := ctx.call(meth, []Value{})
+	if err, ok := rv.(*Error); ok {
+		return nil, err
+	}
+	switch rv := rv.(type) {
+	case *Integer, *Float:
+		return ctx.hashKey(rv)
+	case *String:
+		return rv.s, nil
+	}
+	return nil, ctx.raisef(ctx.g.TypeError, "hash() should return a Number or String, got %s", rv.Klass().name)
+}
+
+// find returns the entry for key, or nil if there is none,
+// along with the hash key of key.
+func (m *Map) find(ctx *Context, key Value) (*mapEntry, interface{}, *Error) {
+	hk, err := ctx.hashKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, entry := range m.buckets[hk] {
+		if entry.key == key {
+			return entry, hk, nil
+		}
+		eq, err := keysEqual(ctx, entry.key, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if eq {
+			return entry, hk, nil
+		}
+	}
+	return nil, hk, nil
+}
+
+// keysEqual compares two keys with the same hash key. Strings and
+// Numbers are compared without going through ==.
+func keysEqual(ctx *Context, a, b Value) (bool, *Error) {
+	switch a := a.(type) {
+	case *String:
+		if b, ok := b.(*String); ok {
+			return a.s == b.s, nil
+		}
+	case *Integer, *Float:
+		if _, ok := toFloat(b); ok {
+			c, ok := compareNumbers(a, b)
+			return ok && c == 0, nil
+		}
+	}
+	return ctx.equal(a, b)
+}
+
+func (m *Map) Get(ctx *Context, key Value) (Value, bool, *Error) {
+	entry, _, err := m.find(ctx, key)
+	if err != nil || entry == nil {
+		return nil, false, err
+	}
+	return entry.value, true, nil
+}
+
+func (m *Map) Set(ctx *Context, key, value Value) *Error {
+	entry, hk, err := m.find(ctx, key)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		entry.value = value
+		return nil
+	}
+	entry = &mapEntry{key: key, value: value, index: len(m.entries)}
+	m.entries = append(m.entries, entry)
+	m.buckets[hk] = append(m.buckets[hk], entry)
+	m.size++
+	return nil
+}
+
+func (m *Map) Delete(ctx *Context, key Value) (Value, bool, *Error) {
+	entry, hk, err := m.find(ctx, key)
+	if err != nil || entry == nil {
+		return nil, false, err
+	}
+	bucket := m.buckets[hk]
+	for i, e := range bucket {
+		if e == entry {
+			bucket = append(bucket[:i:i], bucket[i+1:]...)
+			break
+		}
+	}
+	if len(bucket) == 0 {
+		delete(m.buckets, hk)
+	} else {
+		m.buckets[hk] = bucket
+	}
+	m.entries[entry.index] = nil
+	entry.deleted = true
+	m.size--
+	// compact once most of the entries are gone, so that iterating
+	// stays proportional to the size of the map.
+	if len(m.entries) > 8 && m.size < len(m.entries)/2 {
+		entries := make([]*mapEntry, 0, m.size)
+		for _, e := range m.entries {
+			if e != nil {
+				e.index = len(entries)
+				entries = append(entries, e)
+			}
+		}
+		m.entries = entries
+	}
+	return entry.value, true, nil
+}
+
+// each calls fn on every entry in insertion order, stopping early
+// if fn returns false.
+func (m *Map) each(fn func(entry *mapEntry) bool) {
+	for _, entry := range m.entries {
+		if entry != nil && !fn(entry) {
+			return
+		}
+	}
+}
+
+func (g *GlobalObjects) initMap() {
+	g.Map = g.NewClass("Map", g.Object)
+	g.Map.alloc = func(klass *Class) Value {
+		m := g.NewMap()
+		m.klass = klass
+		return m
+	}
+	g.defineMethod(g.Map, "inspect", 0, func(ref *NativeFunction, args []Value) Value {
+		parts := []string{}
+		var err *Error
+		ref.this.(*Map).each(func(entry *mapEntry) bool {
+			var k, v string
+			if k, err = g.ctx.inspect(entry.key); err != nil {
+				return false
+			}
+			if v, err = g.ctx.inspect(entry.value); err != nil {
+				return false
+			}
+			parts = append(parts, k+": "+v)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		return g.NewString("{" + strings.Join(parts, ", ") + "}")
+	})
+	g.defineMethod(g.Map, "len", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NewInteger(int64(ref.this.(*Map).size))
+	})
+	g.defineMethod(g.Map, "[]", 1, func(ref *NativeFunction, args []Value) Value {
+		val, ok, err := ref.this.(*Map).Get(g.ctx, args[0])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return g.ctx.keyError(args[0])
+		}
+		return val
+	})
+	g.defineMethod(g.Map, "[]=", 2, func(ref *NativeFunction, args []Value) Value {
+		if err := ref.this.(*Map).Set(g.ctx, args[0], args[1]); err != nil {
+			return err
+		}
+		return args[1]
+	})
+	// get(key, default) is like [], but returns default (or nil)
+	// instead of raising an error if key is missing.
+	g.defineMethod(g.Map, "get", -1, func(ref *NativeFunction, args []Value) Value {
+		if len(args) != 1 && len(args) != 2 {
+			return g.ctx.raisef(g.ArgumentError, "get() takes 1 or 2 argument(s) but %d were given", len(args))
+		}
+		val, ok, err := ref.this.(*Map).Get(g.ctx, args[0])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if len(args) == 2 {
+				return args[1]
+			}
+			return g.NIL
+		}
+		return val
+	})
+	g.defineMethod(g.Map, "has", 1, func(ref *NativeFunction, args []Value) Value {
+		_, ok, err := ref.this.(*Map).Get(g.ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return g.NewBoolean(ok)
+	})
+	g.defineMethod(g.Map, "delete", 1, func(ref *NativeFunction, args []Value) Value {
+		val, ok, err := ref.this.(*Map).Delete(g.ctx, args[0])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return g.ctx.keyError(args[0])
+		}
+		return val
+	})
+	g.defineMethod(g.Map, "keys", 0, func(ref *NativeFunction, args []Value) Value {
+		keys := []Value{}
+		ref.this.(*Map).each(func(entry *mapEntry) bool {
+			keys = append(keys, entry.key)
+			return true
+		})
+		return g.NewArray(keys)
+	})
+	g.defineMethod(g.Map, "values", 0, func(ref *NativeFunction, args []Value) Value {
+		values := []Value{}
+		ref.this.(*Map).each(func(entry *mapEntry) bool {
+			values = append(values, entry.value)
+			return true
+		})
+		return g.NewArray(values)
+	})
+	// merge returns a new Map with the entries of both maps; the
+	// values of other win when both have the same key.
+	g.defineMethod(g.Map, "merge", 1, func(ref *NativeFunction, args []Value) Value {
+		other, ok := args[0].(*Map)
+		if !ok {
+			return g.ctx.raisef(g.TypeError, "merge() expects a Map, got %s", args[0].Klass().name)
+		}
+		m := g.NewMap()
+		var err *Error
+		set := func(entry *mapEntry) bool {
+			err = m.Set(g.ctx, entry.key, entry.value)
+			return err == nil
+		}
+		ref.this.(*Map).each(set)
+		if err == nil {
+			other.each(set)
+		}
+		if err != nil {
+			return err
+		}
+		return m
+	})
+	g.defineMethod(g.Map, "==", 1, func(ref *NativeFunction, args []Value) Value {
+		m := ref.this.(*Map)
+		other, ok := args[0].(*Map)
+		if !ok || m.size != other.size {
+			return g.FALSE
+		}
+		rv := Value(g.TRUE)
+		m.each(func(entry *mapEntry) bool {
+			val, ok, err := other.Get(g.ctx, entry.key)
+			if err != nil {
+				rv = err
+				return false
+			}
+			if !ok {
+				rv = g.FALSE
+				return false
+			}
+			eq, err := g.ctx.equal(entry.value, val)
+			if err != nil {
+				rv = err
+				return false
+			}
+			if !eq {
+				rv = g.FALSE
+			}
+			return eq
+		})
+		return rv
+	})
+}
+
+// keyError raises a KeyError for key.
+func (ctx *Context) keyError(key Value) *Error {
+	s, err := ctx.inspect(key)
+	if err != nil {
+		return err
+	}
+	return ctx.raisef(ctx.g.KeyError, "key %s not found", s)
+}
@@ -0,0 +1,507 @@
+package eval
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Integer *value*. Integers have arbitrary precision: they are kept
+// in an int64, and promoted to a big.Int when they would overflow.
+type Integer struct {
+	Basic
+	i   int64
+	big *big.Int // nil unless the integer does not fit in i
+}
+
+// The Integers from minSmallInteger to maxSmallInteger are
+// preallocated, so that counting with them, as when iterating over a
+// range, does not allocate. Integers are immutable, so they can be
+// shared.
+const (
+	minSmallInteger = -128
+	maxSmallInteger = 1023
+)
+
+func (g *GlobalObjects) NewInteger(i int64) *Integer {
+	if minSmallInteger <= i && i <= maxSmallInteger {
+		return &g.smallIntegers[i-minSmallInteger]
+	}
+	return &Integer{Basic: Basic{klass: g.Integer}, i: i}
+}
+
+// newBigInteger returns n as an Integer, demoting it to an int64
+// if it fits.
+func (g *GlobalObjects) newBigInteger(n *big.Int) *Integer {
+	if n.IsInt64() {
+		return g.NewInteger(n.Int64())
+	}
+	return &Integer{Basic: Basic{klass: g.Integer}, big: n}
+}
+
+func (n *Integer) String() string {
+	if n.big != nil {
+		return n.big.String()
+	}
+	return strconv.FormatInt(n.i, 10)
+}
+
+// toBig returns n as a big.Int, which must not be modified.
+func (n *Integer) toBig() *big.Int {
+	if n.big != nil {
+		return n.big
+	}
+	return big.NewInt(n.i)
+}
+
+// bitLen returns the number of bits of the absolute value of n.
+func (n *Integer) bitLen() int {
+	switch {
+	case n.big != nil:
+		return n.big.BitLen()
+	case n.i < 0:
+		return bits.Len64(uint64(-n.i))
+	}
+	return bits.Len64(uint64(n.i))
+}
+
+func (n *Integer) toFloat() float64 {
+	if n.big != nil {
+		f, _ := new(big.Float).SetInt(n.big).Float64()
+		return f
+	}
+	return float64(n.i)
+}
+
+// Float *value*
+type Float struct {
+	Basic
+	f float64
+}
+
+func (g *GlobalObjects) NewFloat(f float64) *Float {
+	return &Float{Basic: Basic{klass: g.Float}, f: f}
+}
+
+func (n *Float) String() string {
+	return formatFloat(n.f)
+}
+
+// formatFloat formats f so that it can be told apart from an Integer.
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// toFloat converts the Number v into a float64.
+func toFloat(v Value) (float64, bool) {
+	switch v := v.(type) {
+	case *Integer:
+		return v.toFloat(), true
+	case *Float:
+		return v.f, true
+	}
+	return 0, false
+}
+
+func isFloat(v Value) bool {
+	_, ok := v.(*Float)
+	return ok
+}
+
+// floatToBig converts the whole number f into a big.Int.
+func floatToBig(f float64) *big.Int {
+	n, _ := big.NewFloat(f).Int(nil)
+	return n
+}
+
+// compareNumbers compares two Numbers exactly, returning -1, 0 or 1.
+// ok is false if either of them is NaN.
+func compareNumbers(a, b Value) (c int, ok bool) {
+	x, xInt := a.(*Integer)
+	y, yInt := b.(*Integer)
+	switch {
+	case xInt && yInt:
+		if x.big == nil && y.big == nil {
+			return compareInt64(x.i, y.i), true
+		}
+		return x.toBig().Cmp(y.toBig()), true
+	case xInt:
+		c, ok := compareNumbers(b, a)
+		return -c, ok
+	}
+	f, _ := toFloat(a)
+	if math.IsNaN(f) {
+		return 0, false
+	}
+	if !yInt {
+		g, _ := toFloat(b)
+		if math.IsNaN(g) {
+			return 0, false
+		}
+		return compareFloat64(f, g), true
+	}
+	// comparing a Float with an Integer: convert the Integer into a
+	// Float if that can be done exactly.
+	if y.big == nil && -1<<53 <= y.i && y.i <= 1<<53 {
+		return compareFloat64(f, float64(y.i)), true
+	}
+	if math.IsInf(f, 0) {
+		return int(math.Copysign(1, f)), true
+	}
+	return big.NewFloat(f).Cmp(new(big.Float).SetInt(y.toBig())), true
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloat64(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// intOp is an operator on Integers. small is tried first if both
+// operands fit in an int64, and returns false if the result would
+// overflow, in which case large is used instead.
+type intOp struct {
+	small func(a, b int64) (int64, bool)
+	large func(z, a, b *big.Int) *big.Int
+}
+
+func (op intOp) apply(g *GlobalObjects, a, b *Integer) Value {
+	if a.big == nil && b.big == nil && op.small != nil {
+		if c, ok := op.small(a.i, b.i); ok {
+			return g.NewInteger(c)
+		}
+	}
+	return g.newBigInteger(op.large(new(big.Int), a.toBig(), b.toBig()))
+}
+
+var (
+	intAdd = intOp{
+		small: func(a, b int64) (int64, bool) {
+			c := a + b
+			return c, (c > a) == (b > 0)
+		},
+		large: (*big.Int).Add,
+	}
+	intSub = intOp{
+		small: func(a, b int64) (int64, bool) {
+			c := a - b
+			return c, (c < a) == (b > 0)
+		},
+		large: (*big.Int).Sub,
+	}
+	intMul = intOp{
+		small: func(a, b int64) (int64, bool) {
+			if a == 0 || b == 0 {
+				return 0, true
+			}
+			c := a * b
+			return c, c/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64)
+		},
+		large: (*big.Int).Mul,
+	}
+	// div and mod round towards negative infinity, so that the result
+	// of a % b has the same sign as b.
+	intDiv = intOp{
+		small: func(a, b int64) (int64, bool) {
+			if a == math.MinInt64 && b == -1 {
+				return 0, false
+			}
+			q := a / b
+			if a%b != 0 && (a < 0) != (b < 0) {
+				q--
+			}
+			return q, true
+		},
+		large: func(z, a, b *big.Int) *big.Int {
+			r := new(big.Int)
+			z.QuoRem(a, b, r)
+			if r.Sign() != 0 && r.Sign() != b.Sign() {
+				z.Sub(z, big.NewInt(1))
+			}
+			return z
+		},
+	}
+	intMod = intOp{
+		small: func(a, b int64) (int64, bool) {
+			r := a % b
+			if r != 0 && (r < 0) != (b < 0) {
+				r += b
+			}
+			return r, true
+		},
+		large: func(z, a, b *big.Int) *big.Int {
+			z.Rem(a, b)
+			if z.Sign() != 0 && z.Sign() != b.Sign() {
+				z.Add(z, b)
+			}
+			return z
+		},
+	}
+	intAnd = intOp{
+		small: func(a, b int64) (int64, bool) { return a & b, true },
+		large: (*big.Int).And,
+	}
+	intOr = intOp{
+		small: func(a, b int64) (int64, bool) { return a | b, true },
+		large: (*big.Int).Or,
+	}
+	intXor = intOp{
+		small: func(a, b int64) (int64, bool) { return a ^ b, true },
+		large: (*big.Int).Xor,
+	}
+)
+
+func floatMod(a, b float64) float64 {
+	r := math.Mod(a, b)
+	if r != 0 && (r < 0) != (b < 0) {
+		r += b
+	}
+	return r
+}
+
+// maxIntegerBits is the size in bits of the largest Integer that
+// operations like ** and << will build.
+const maxIntegerBits = 1 << 24
+
+func (g *GlobalObjects) initNumber() {
+	// Number is the abstract superclass of Integer and Float. Operators
+	// on Integers give Integers, while mixing Integers with Floats
+	// gives Floats.
+	g.Number = g.NewClass("Number", g.Object)
+	g.Number.alloc = noAlloc
+	g.Integer = g.NewClass("Integer", g.Number)
+	g.smallIntegers = make([]Integer, maxSmallInteger-minSmallInteger+1)
+	for i := range g.smallIntegers {
+		g.smallIntegers[i] = Integer{Basic: Basic{klass: g.Integer}, i: int64(minSmallInteger + i)}
+	}
+	g.Float = g.NewClass("Float", g.Number)
+	g.defineMethod(g.Integer, "inspect", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NewString(ref.this.(*Integer).String())
+	})
+	g.defineMethod(g.Float, "inspect", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NewString(ref.this.(*Float).String())
+	})
+	// "-" doubles as unary minus when called without arguments.
+	g.defineMethod(g.Number, "-", -1, func(ref *NativeFunction, args []Value) Value {
+		switch len(args) {
+		case 0:
+			switch n := ref.this.(type) {
+			case *Integer:
+				if n.big == nil && n.i != math.MinInt64 {
+					return g.NewInteger(-n.i)
+				}
+				return g.newBigInteger(new(big.Int).Neg(n.toBig()))
+			case *Float:
+				return g.NewFloat(-n.f)
+			}
+			return g.wrongType(ref)
+		case 1:
+			return g.numberOp(ref, args[0], intSub.apply, func(a, b float64) Value {
+				return g.NewFloat(a - b)
+			})
+		}
+		return g.ctx.raisef(g.ArgumentError, "-() takes 0 or 1 argument(s) but %d were given", len(args))
+	})
+	zeroDivision := func(b Value) *Error {
+		if n, ok := b.(*Integer); ok && n.big == nil && n.i == 0 {
+			return g.ctx.raisef(g.ZeroDivisionError, "division by zero")
+		}
+		if n, ok := b.(*Float); ok && n.f == 0 {
+			return g.ctx.raisef(g.ZeroDivisionError, "division by zero")
+		}
+		return nil
+	}
+	arith := map[string]struct {
+		int   func(g *GlobalObjects, a, b *Integer) Value
+		float func(a, b float64) Value
+	}{
+		"+": {intAdd.apply, func(a, b float64) Value { return g.NewFloat(a + b) }},
+		"*": {intMul.apply, func(a, b float64) Value { return g.NewFloat(a * b) }},
+		// a / b always gives a Float; use a.div(b) to divide Integers.
+		"/": {nil, func(a, b float64) Value { return g.NewFloat(a / b) }},
+		"div": {intDiv.apply, func(a, b float64) Value {
+			return g.NewFloat(math.Floor(a / b))
+		}},
+		"%": {intMod.apply, func(a, b float64) Value { return g.NewFloat(floatMod(a, b)) }},
+		"**": {
+			func(g *GlobalObjects, a, b *Integer) Value {
+				// the result has about a.bitLen() * b bits, unless a is
+				// 0, 1 or -1.
+				if b.big == nil && b.i >= 0 {
+					if a.bitLen() > 1 && b.i > int64(maxIntegerBits/a.bitLen()) {
+						return g.ctx.raisef(g.ValueError, "exponent %s is too large", b)
+					}
+					return g.newBigInteger(new(big.Int).Exp(a.toBig(), b.toBig(), nil))
+				}
+				if b.big != nil && b.big.Sign() > 0 {
+					return g.ctx.raisef(g.ValueError, "exponent %s is too large", b)
+				}
+				if a.big == nil && a.i == 0 {
+					return g.ctx.raisef(g.ZeroDivisionError, "0 cannot be raised to a negative power")
+				}
+				return g.NewFloat(math.Pow(a.toFloat(), b.toFloat()))
+			},
+			func(a, b float64) Value { return g.NewFloat(math.Pow(a, b)) },
+		},
+		"&": {intAnd.apply, nil},
+		"|": {intOr.apply, nil},
+		"^": {intXor.apply, nil},
+		"<<": {func(g *GlobalObjects, a, b *Integer) Value {
+			n, err := g.shiftCount(b)
+			if err != nil {
+				return err
+			}
+			if a.big == nil && n < 63 && (a.i<<n)>>n == a.i {
+				return g.NewInteger(a.i << n)
+			}
+			if size := a.bitLen(); size > 0 && uint(size)+n > maxIntegerBits {
+				return g.ctx.raisef(g.ValueError, "shift count %s is too large", b)
+			}
+			return g.newBigInteger(new(big.Int).Lsh(a.toBig(), n))
+		}, nil},
+		">>": {func(g *GlobalObjects, a, b *Integer) Value {
+			n, err := g.shiftCount(b)
+			if err != nil {
+				return err
+			}
+			if a.big == nil {
+				if n >= 64 {
+					n = 63
+				}
+				return g.NewInteger(a.i >> n)
+			}
+			return g.newBigInteger(new(big.Int).Rsh(a.big, n))
+		}, nil},
+	}
+	for op, fns := range arith {
+		op, fns := op, fns
+		checkZero := op == "/" || op == "div" || op == "%"
+		g.defineMethod(g.Number, op, 1, func(ref *NativeFunction, args []Value) Value {
+			if checkZero {
+				if err := zeroDivision(args[0]); err != nil {
+					return err
+				}
+			}
+			return g.numberOp(ref, args[0], fns.int, fns.float)
+		})
+	}
+	g.defineMethod(g.Integer, "~", 0, func(ref *NativeFunction, args []Value) Value {
+		n := ref.this.(*Integer)
+		if n.big == nil {
+			return g.NewInteger(^n.i)
+		}
+		return g.newBigInteger(new(big.Int).Not(n.big))
+	})
+	compare := map[string]func(c int) bool{
+		"<":  func(c int) bool { return c < 0 },
+		"<=": func(c int) bool { return c <= 0 },
+		">":  func(c int) bool { return c > 0 },
+		">=": func(c int) bool { return c >= 0 },
+	}
+	for op, fn := range compare {
+		op, fn := op, fn
+		g.defineMethod(g.Number, op, 1, func(ref *NativeFunction, args []Value) Value {
+			if _, ok := toFloat(ref.this); !ok {
+				return g.wrongType(ref)
+			}
+			if _, ok := toFloat(args[0]); !ok {
+				return g.ctx.raisef(g.TypeError, "unsupported operand types for %s: %s and %s",
+					op, ref.this.Klass().name, args[0].Klass().name)
+			}
+			c, ok := compareNumbers(ref.this, args[0])
+			return g.NewBoolean(ok && fn(c))
+		})
+	}
+	// Integers and Floats are equal if they have exactly the same value.
+	g.defineMethod(g.Number, "==", 1, func(ref *NativeFunction, args []Value) Value {
+		if _, ok := toFloat(ref.this); !ok {
+			return g.wrongType(ref)
+		}
+		if _, ok := toFloat(args[0]); !ok {
+			return g.FALSE
+		}
+		c, ok := compareNumbers(ref.this, args[0])
+		return g.NewBoolean(ok && c == 0)
+	})
+	g.defineMethod(g.Integer, "to_i", 0, func(ref *NativeFunction, args []Value) Value {
+		return ref.this
+	})
+	g.defineMethod(g.Integer, "to_f", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NewFloat(ref.this.(*Integer).toFloat())
+	})
+	// to_i truncates a Float towards zero.
+	g.defineMethod(g.Float, "to_i", 0, func(ref *NativeFunction, args []Value) Value {
+		f := ref.this.(*Float).f
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return g.ctx.raisef(g.ValueError, "cannot convert %s to an Integer", formatFloat(f))
+		}
+		f = math.Trunc(f)
+		if -1<<63 <= f && f < 1<<63 {
+			return g.NewInteger(int64(f))
+		}
+		return g.newBigInteger(floatToBig(f))
+	})
+	g.defineMethod(g.Float, "to_f", 0, func(ref *NativeFunction, args []Value) Value {
+		return ref.this
+	})
+}
+
+// numberOp applies the operator implemented by intFn and floatFn to
+// the receiver of ref and other. intFn is used if both are Integers,
+// and floatFn otherwise. A nil function means that the operator is
+// not supported for those types.
+func (g *GlobalObjects) numberOp(ref *NativeFunction, other Value,
+	intFn func(g *GlobalObjects, a, b *Integer) Value,
+	floatFn func(a, b float64) Value) Value {
+	a, aInt := ref.this.(*Integer)
+	b, bInt := other.(*Integer)
+	if aInt && bInt && intFn != nil {
+		return intFn(g, a, b)
+	}
+	x, ok := toFloat(ref.this)
+	if !ok {
+		return g.wrongType(ref)
+	}
+	y, ok := toFloat(other)
+	if !ok || floatFn == nil {
+		return g.ctx.raisef(g.TypeError, "unsupported operand types for %s: %s and %s",
+			ref.name, ref.this.Klass().name, other.Klass().name)
+	}
+	return floatFn(x, y)
+}
+
+// wrongType reports that ref was bound to a value that is not a Number.
+func (g *GlobalObjects) wrongType(ref *NativeFunction) *Error {
+	return g.ctx.raisef(g.TypeError, "%s() called with the wrong type of value", ref.name)
+}
+
+// shiftCount converts n into a shift count.
+func (g *GlobalObjects) shiftCount(n *Integer) (uint, *Error) {
+	if n.big != nil || n.i < 0 {
+		if n.big == nil || n.big.Sign() < 0 {
+			return 0, g.ctx.raisef(g.ValueError, "negative shift count %s", n)
+		}
+		return 0, g.ctx.raisef(g.ValueError, "shift count %s is too large", n)
+	}
+	return uint(n.i), nil
+}
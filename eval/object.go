@@ -1,6 +1,8 @@
 package eval
 
-import "fmt"
+import (
+	"fmt"
+)
 
 // Value represents any Jingle value.
 // We take inspiration from Ruby's object implementation.
@@ -19,14 +21,34 @@ type GlobalObjects struct {
 	Object         *Class // the Object class
 	Class          *Class // the Class class
 	NativeFunction *Class // the NativeFunction class
+	Function       *Class // the Function class
 	Nil            *Class // the class of nil, Nil
 	Boolean        *Class // class of booleans, Boolean
+	Number         *Class // Number class, the superclass of Integer and Float
+	Integer        *Class // Integer class
+	Float          *Class // Float class
 	String         *Class // String class
+	Array          *Class // Array class
+	Map            *Class // Map class
+	Iterator       *Class // class of native iterators
+	Range          *Class // Range class
 	Error          *Class // Error class
+	// Subclasses of Error raised by the runtime
+	TypeError         *Class
+	NameError         *Class
+	AttributeError    *Class
+	ArgumentError     *Class
+	ValueError        *Class
+	IndexError        *Class
+	KeyError          *Class
+	StopIteration     *Class
+	ZeroDivisionError *Class
 	// Literals
 	TRUE  *Boolean
 	FALSE *Boolean
 	NIL   *Nil
+	// Preallocated Integers, see NewInteger
+	smallIntegers []Integer
 }
 
 func NewGlobalObjects(ctx *Context) *GlobalObjects {
@@ -39,36 +61,78 @@ func NewGlobalObjects(ctx *Context) *GlobalObjects {
 	g.NativeFunction = g.NewClass("NativeFunction", g.Object)
 
 	// define Object methods here (class', attrs)
-	g.Object.methods["class'"] = g.NewNativeFunction(func(ref *NativeFunction, args []Value) Value {
+	g.defineMethod(g.Object, "class'", 0, func(ref *NativeFunction, args []Value) Value {
 		return ref.this.Klass()
 	})
-	g.Object.methods["inspect"] = g.NewNativeFunction(func(ref *NativeFunction, args []Value) Value {
+	g.defineMethod(g.Object, "inspect", 0, func(ref *NativeFunction, args []Value) Value {
 		return g.NewString(fmt.Sprintf("<object %p>", ref.this))
 	})
+	// operators that make sense for every object.
+	g.defineMethod(g.Object, "==", 1, func(ref *NativeFunction, args []Value) Value {
+		return g.NewBoolean(ref.this == args[0])
+	})
+	g.defineMethod(g.Object, "!=", 1, func(ref *NativeFunction, args []Value) Value {
+		eq := ctx.callMethod(ref.this, "==", args)
+		if isError(eq) {
+			return eq
+		}
+		return g.NewBoolean(!g.isTruthy(eq))
+	})
+	g.defineMethod(g.Object, "!", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NewBoolean(!g.isTruthy(ref.this))
+	})
+	g.defineMethod(g.Object, "init", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NIL
+	})
+	g.Object.alloc = func(klass *Class) Value { return g.NewObject(klass) }
 	// define Class methods here (new)
-	g.Class.methods["inspect"] = g.NewNativeFunction(func(ref *NativeFunction, args []Value) Value {
+	g.defineMethod(g.Class, "new", -1, func(ref *NativeFunction, args []Value) Value {
+		klass := ref.this.(*Class)
+		obj := klass.allocate()
+		if obj == nil {
+			return ctx.raisef(g.TypeError, "cannot instantiate %s", klass.name)
+		}
+		rv := ctx.callMethod(obj, "init", args)
+		if isError(rv) {
+			return rv
+		}
+		return obj
+	})
+	g.defineMethod(g.Class, "super", 0, func(ref *NativeFunction, args []Value) Value {
+		if super := ref.this.(*Class).super; super != nil {
+			return super
+		}
+		return g.NIL
+	})
+	g.defineMethod(g.Class, "inspect", 0, func(ref *NativeFunction, args []Value) Value {
 		return g.NewString(fmt.Sprintf(
 			"<class %s>",
 			ref.this.(*Class).name,
 		))
 	})
-	g.Class.methods["get_method"] = g.NewNativeFunction(func (ref *NativeFunction, args []Value) Value {
-		name := args[0].(*String).s
-		return ref.this.(*Class).methods[name]
+	g.defineMethod(g.Class, "get_method", 1, func(ref *NativeFunction, args []Value) Value {
+		name, ok := args[0].(*String)
+		if !ok {
+			return ctx.raisef(g.TypeError, "get_method() expects a String, got %s", args[0].Klass().name)
+		}
+		if meth, ok := ref.this.(*Class).methods[name.s]; ok {
+			return meth
+		}
+		return g.NIL
 	})
 	// define NativeFunction methods here
-	g.NativeFunction.methods["bind"] = g.NewNativeFunction(func (ref *NativeFunction, args []Value) Value {
+	g.defineMethod(g.NativeFunction, "bind", 1, func(ref *NativeFunction, args []Value) Value {
 		return ref.this.(*NativeFunction).Bind(args[0])
 	})
 
-	g.String = g.NewClass("String", g.Object)
+	g.initString()
 	g.Nil = g.NewClass("Nil", g.Object)
-	g.Nil.methods["inspect"] = g.NewNativeFunction(func(ref *NativeFunction, args []Value) Value {
+	g.defineMethod(g.Nil, "inspect", 0, func(ref *NativeFunction, args []Value) Value {
 		return g.NewString("nil")
 	})
 
 	g.Boolean = g.NewClass("Boolean", g.Object)
-	g.Boolean.methods["inspect"] = g.NewNativeFunction(func(ref *NativeFunction, args []Value) Value {
+	g.defineMethod(g.Boolean, "inspect", 0, func(ref *NativeFunction, args []Value) Value {
 		switch ref.this {
 		case g.TRUE:
 			return g.NewString("true")
@@ -77,6 +141,16 @@ func NewGlobalObjects(ctx *Context) *GlobalObjects {
 		}
 		return nil
 	})
+	g.initNumber()
+	g.initFunction()
+	g.initError()
+	g.initArray()
+	g.initMap()
+	g.initIterator()
+	g.initRange()
+	for _, klass := range []*Class{g.Class, g.NativeFunction, g.String, g.Nil, g.Boolean} {
+		klass.alloc = noAlloc
+	}
 
 	g.NIL = &Nil{Basic: Basic{klass: g.Nil}}
 	g.TRUE = &Boolean{Basic: Basic{klass: g.Boolean}, b: true}
@@ -84,6 +158,25 @@ func NewGlobalObjects(ctx *Context) *GlobalObjects {
 	return g
 }
 
+// defineMethod defines a native method called name on klass. fn can
+// assume that it is bound to an instance of klass: methods taken from
+// a class with get_method may be bound to anything, so this is checked
+// before fn is called.
+func (g *GlobalObjects) defineMethod(klass *Class, name string, arity int, fn nativeFn) {
+	klass.methods[name] = g.NewNativeFunction(name, arity, func(ref *NativeFunction, args []Value) Value {
+		if ref.this == nil || ref.this.Klass() == nil || !ref.this.Klass().isSubclassOf(klass) {
+			return g.ctx.raisef(g.TypeError, "%s() must be called on an instance of %s", name, klass.name)
+		}
+		return fn(ref, args)
+	})
+}
+
+// isTruthy returns false for nil and false, and true for
+// everything else.
+func (g *GlobalObjects) isTruthy(v Value) bool {
+	return v != g.NIL && v != g.FALSE
+}
+
 // Embedded in each concrete Value.
 type Basic struct{ klass *Class }
 
@@ -100,6 +193,34 @@ type Class struct {
 	attrs   map[string]Value // my attributes.
 	methods map[string]Value // my methods.
 	super   *Class
+	alloc   allocator // creates instances; inherited if nil.
+}
+
+// allocator creates a blank instance of klass, or returns
+// nil if klass cannot be instantiated.
+type allocator func(klass *Class) Value
+
+func noAlloc(klass *Class) Value { return nil }
+
+// allocate creates a blank instance of c, using the allocator
+// of the closest class that defines one.
+func (c *Class) allocate() Value {
+	for klass := c; klass != nil; klass = klass.super {
+		if klass.alloc != nil {
+			return klass.alloc(c)
+		}
+	}
+	return nil
+}
+
+// isSubclassOf returns true if c is other, or inherits from other.
+func (c *Class) isSubclassOf(other *Class) bool {
+	for klass := c; klass != nil; klass = klass.super {
+		if klass == other {
+			return true
+		}
+	}
+	return false
 }
 
 func (g *GlobalObjects) NewClass(name string, super *Class) *Class {
@@ -132,28 +253,29 @@ type Boolean struct {
 	b bool
 }
 
-// Nil *value*
-type Nil struct{ Basic }
-
-// String *value*
-type String struct {
-	Basic
-	s string
+// NewBoolean returns the TRUE or FALSE singleton.
+func (g *GlobalObjects) NewBoolean(b bool) *Boolean {
+	if b {
+		return g.TRUE
+	}
+	return g.FALSE
 }
 
-func (g *GlobalObjects) NewString(str string) *String {
-	return &String{Basic: Basic{klass: g.String}, s: str}
-}
+// Nil *value*
+type Nil struct{ Basic }
 
-func (s String) String() string { return s.s }
+// nativeFn is the signature of functions written in Go.
+type nativeFn func(*NativeFunction, []Value) Value
 
 // NativeFunction is a function written in Go.
 type NativeFunction struct {
 	Basic
 	ctx   *Context
+	name  string
+	arity int // number of arguments, or -1 if variadic.
 	this  Value
 	attrs map[string]Value
-	fn    func(*NativeFunction, []Value) Value
+	fn    nativeFn
 }
 
 func (nf *NativeFunction) Bind(this Value) *NativeFunction {
@@ -162,6 +284,9 @@ func (nf *NativeFunction) Bind(this Value) *NativeFunction {
 	}
 	return &NativeFunction{
 		Basic: nf.Basic,
+		ctx:   nf.ctx,
+		name:  nf.name,
+		arity: nf.arity,
 		this:  this,
 		attrs: nf.attrs,
 		fn:    nf.fn,
@@ -169,24 +294,21 @@ func (nf *NativeFunction) Bind(this Value) *NativeFunction {
 }
 
 func (nf *NativeFunction) Call(args []Value) Value {
+	if nf.arity >= 0 && len(args) != nf.arity {
+		return nf.ctx.raisef(nf.ctx.g.ArgumentError, "%s() takes %d argument(s) but %d were given",
+			nf.name, nf.arity, len(args))
+	}
 	return nf.fn(nf, args)
 }
 
-func (g *GlobalObjects) NewNativeFunction(fn func(*NativeFunction, []Value) Value) *NativeFunction {
+func (g *GlobalObjects) NewNativeFunction(name string, arity int, fn nativeFn) *NativeFunction {
 	return &NativeFunction{
 		Basic: Basic{klass: g.NativeFunction},
 		ctx:   g.ctx,
+		name:  name,
+		arity: arity,
 		this:  nil,
 		attrs: map[string]Value{},
 		fn:    fn,
 	}
 }
-
-// Error wraps around a reason object, and is an error
-// meant to be unwrapped. If there is no code to catch the error,
-// the error propagates up the stack. This is NOT the Error _class_
-// in the language.
-type Error struct {
-	Basic
-	Reason Value
-}
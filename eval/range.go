@@ -0,0 +1,283 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Range *value*: the numbers start, start+step, ... up to end, which
+// is included unless the range is exclusive. Ranges are lazy -- the
+// numbers are only computed while iterating. A range produces Floats
+// if any of start, end or step is a Float, and Integers otherwise;
+// the bounds are kept as Numbers of that class, so that Integer
+// ranges are exact however large they are.
+type Range struct {
+	Basic
+	start     Value
+	end       Value
+	step      Value
+	exclusive bool
+}
+
+// NewRange returns the range from start to end in steps of step,
+// which must be Numbers.
+func (g *GlobalObjects) NewRange(start, end, step Value, exclusive bool) *Range {
+	if isFloat(start) || isFloat(end) || isFloat(step) {
+		start, end, step = g.toFloatValue(start), g.toFloatValue(end), g.toFloatValue(step)
+	}
+	return &Range{
+		Basic:     Basic{klass: g.Range},
+		start:     start,
+		end:       end,
+		step:      step,
+		exclusive: exclusive,
+	}
+}
+
+// toFloatValue converts the Number v into a Float.
+func (g *GlobalObjects) toFloatValue(v Value) Value {
+	if isFloat(v) {
+		return v
+	}
+	f, _ := toFloat(v)
+	return g.NewFloat(f)
+}
+
+func (r *Range) float() bool { return isFloat(r.start) }
+
+func (r *Range) String() string {
+	op := ".."
+	if r.exclusive {
+		op = "..."
+	}
+	s := fmt.Sprintf("%s%s%s", r.start, op, r.end)
+	if f, _ := toFloat(r.step); f != 1 {
+		s = fmt.Sprintf("(%s).step(%s)", s, r.step)
+	}
+	return s
+}
+
+// floats returns the bounds of a Float range.
+func (r *Range) floats() (start, end, step float64) {
+	return r.start.(*Float).f, r.end.(*Float).f, r.step.(*Float).f
+}
+
+// len returns the number of elements in r, or nil if r is infinite.
+func (r *Range) len() *big.Int {
+	if !r.float() {
+		// n = floor((end - start) / step), counting in the direction
+		// of step.
+		d := new(big.Int).Sub(r.end.(*Integer).toBig(), r.start.(*Integer).toBig())
+		step := r.step.(*Integer).toBig()
+		if step.Sign() < 0 {
+			d.Neg(d)
+			step = new(big.Int).Neg(step)
+		}
+		n, m := new(big.Int).DivMod(d, step, new(big.Int))
+		if r.exclusive && m.Sign() == 0 {
+			n.Sub(n, big.NewInt(1))
+		}
+		if n.Sign() < 0 {
+			return n.SetInt64(0)
+		}
+		return n.Add(n, big.NewInt(1))
+	}
+	start, end, step := r.floats()
+	n := math.Floor((end - start) / step)
+	if r.exclusive && start+n*step == end {
+		n--
+	}
+	if n < 0 || math.IsNaN(n) {
+		return new(big.Int)
+	}
+	if math.IsInf(n, 0) {
+		return nil
+	}
+	return floatToBig(n).Add(floatToBig(n), big.NewInt(1))
+}
+
+// contains reports whether the Number x is one of the elements of r.
+func (r *Range) contains(x Value) bool {
+	if !r.float() {
+		var k *big.Int
+		switch x := x.(type) {
+		case *Integer:
+			k = x.toBig()
+		case *Float:
+			if x.f != math.Trunc(x.f) || math.IsInf(x.f, 0) {
+				return false
+			}
+			k = floatToBig(x.f)
+		default:
+			return false
+		}
+		// x is the i-th element if x - start = i * step.
+		d := new(big.Int).Sub(k, r.start.(*Integer).toBig())
+		i, m := new(big.Int).QuoRem(d, r.step.(*Integer).toBig(), new(big.Int))
+		return m.Sign() == 0 && i.Sign() >= 0 && i.Cmp(r.len()) < 0
+	}
+	f, ok := toFloat(x)
+	if !ok {
+		return false
+	}
+	start, _, step := r.floats()
+	k := (f - start) / step
+	if k != math.Trunc(k) || k < 0 {
+		return false
+	}
+	n := r.len()
+	return n == nil || floatToBig(k).Cmp(n) < 0
+}
+
+// rangeValue returns the i-th element of r.
+func (g *GlobalObjects) rangeValue(r *Range, i int64) Value {
+	if r.float() {
+		start, _, step := r.floats()
+		return g.NewFloat(start + float64(i)*step)
+	}
+	start, step := r.start.(*Integer), r.step.(*Integer)
+	if start.big == nil && step.big == nil {
+		if d, ok := intMul.small(i, step.i); ok {
+			if n, ok := intAdd.small(start.i, d); ok {
+				return g.NewInteger(n)
+			}
+		}
+	}
+	d := new(big.Int).Mul(big.NewInt(i), step.toBig())
+	return g.newBigInteger(d.Add(d, start.toBig()))
+}
+
+func (g *GlobalObjects) initRange() {
+	g.Range = g.NewClass("Range", g.Object)
+	g.Range.alloc = noAlloc
+	for _, op := range []string{"..", "..."} {
+		exclusive := op == "..."
+		g.defineMethod(g.Number, op, 1, func(ref *NativeFunction, args []Value) Value {
+			if _, ok := toFloat(args[0]); !ok {
+				return g.ctx.raisef(g.TypeError, "unsupported operand types for %s: %s and %s",
+					ref.name, ref.this.Klass().name, args[0].Klass().name)
+			}
+			return g.NewRange(ref.this, args[0], g.NewInteger(1), exclusive)
+		})
+	}
+	g.defineMethod(g.Range, "inspect", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NewString(ref.this.(*Range).String())
+	})
+	g.defineMethod(g.Range, "len", 0, func(ref *NativeFunction, args []Value) Value {
+		n := ref.this.(*Range).len()
+		if n == nil {
+			return g.ctx.raisef(g.ValueError, "%s is infinite", ref.this)
+		}
+		return g.newBigInteger(n)
+	})
+	// step returns a copy of the range that counts in steps of n.
+	g.defineMethod(g.Range, "step", 1, func(ref *NativeFunction, args []Value) Value {
+		r := ref.this.(*Range)
+		step, ok := toFloat(args[0])
+		if !ok {
+			return g.ctx.raisef(g.TypeError, "step must be a Number, not %s", args[0].Klass().name)
+		}
+		if step == 0 || math.IsNaN(step) {
+			return g.ctx.raisef(g.ValueError, "step cannot be %s", args[0])
+		}
+		return g.NewRange(r.start, r.end, args[0], r.exclusive)
+	})
+	// contains returns true if x would be produced by iterating
+	// over the range.
+	g.defineMethod(g.Range, "contains", 1, func(ref *NativeFunction, args []Value) Value {
+		return g.NewBoolean(ref.this.(*Range).contains(args[0]))
+	})
+	g.defineMethod(g.Range, "to_array", 0, func(ref *NativeFunction, args []Value) Value {
+		r := ref.this.(*Range)
+		n := r.len()
+		if n == nil || !n.IsInt64() || n.Int64() > maxArrayLen {
+			return g.ctx.raisef(g.ValueError, "%s is too long for an Array", r)
+		}
+		elems := make([]Value, n.Int64())
+		for i := range elems {
+			elems[i] = g.rangeValue(r, int64(i))
+		}
+		return g.NewArray(elems)
+	})
+	g.defineMethod(g.Range, "iter", 0, func(ref *NativeFunction, args []Value) Value {
+		r := ref.this.(*Range)
+		// ranges too long to count through never run out.
+		n := r.len()
+		bounded := n != nil && n.IsInt64()
+		var i int64
+		return g.NewIterator(func() (Value, bool, *Error) {
+			if bounded && i >= n.Int64() {
+				return nil, false, nil
+			}
+			i++
+			return g.rangeValue(r, i-1), true, nil
+		})
+	})
+	g.defineMethod(g.Range, "==", 1, func(ref *NativeFunction, args []Value) Value {
+		r := ref.this.(*Range)
+		other, ok := args[0].(*Range)
+		return g.NewBoolean(ok && r.float() == other.float() && r.exclusive == other.exclusive &&
+			numbersEqual(r.start, other.start) && numbersEqual(r.end, other.end) &&
+			numbersEqual(r.step, other.step))
+	})
+}
+
+// rangeIndices converts r into bounds for slicing a sequence of
+// length n. As with sliceIndices, negative numbers count from the
+// end and the bounds are clamped to the sequence.
+func (g *GlobalObjects) rangeIndices(r *Range, n int) (lo, hi, step int, err *Error) {
+	if r.float() {
+		return 0, 0, 0, g.ctx.raisef(g.TypeError, "indices must be Integers, not Float")
+	}
+	if s := r.step.(*Integer); s.toBig().Sign() <= 0 {
+		return 0, 0, 0, g.ctx.raisef(g.ValueError, "cannot slice with a step of %s", s)
+	}
+	lo, hi, step = clampIndex(r.start, n), clampIndex(r.end, n), clampIndex(r.step, n)
+	if lo < 0 {
+		lo += n
+	}
+	if hi < 0 {
+		hi += n
+	}
+	if !r.exclusive {
+		hi++
+	}
+	if lo < 0 {
+		lo = 0
+	}
+	if lo > n {
+		lo = n
+	}
+	if hi > n {
+		hi = n
+	}
+	if hi < lo {
+		hi = lo
+	}
+	return lo, hi, step, nil
+}
+
+// clampIndex converts the Integer v into an int, clamped to -n-1 to
+// n+1, which is outside of a sequence of length n either way.
+func clampIndex(v Value, n int) int {
+	i := v.(*Integer)
+	switch {
+	case i.big != nil:
+		if i.big.Sign() < 0 {
+			return -n - 1
+		}
+		return n + 1
+	case i.i < int64(-n-1):
+		return -n - 1
+	case i.i > int64(n+1):
+		return n + 1
+	}
+	return int(i.i)
+}
+
+// numbersEqual reports whether the Numbers a and b are equal.
+func numbersEqual(a, b Value) bool {
+	c, ok := compareNumbers(a, b)
+	return ok && c == 0
+}
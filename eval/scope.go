@@ -1,8 +1,11 @@
 package eval
 
+import "sort"
+
 type Scope struct {
-	values map[string]Value
-	outer  *Scope
+	values   map[string]Value
+	outer    *Scope
+	function bool // is this the top-level scope of a function call?
 }
 
 func NewScope(outer *Scope) *Scope {
@@ -12,6 +15,14 @@ func NewScope(outer *Scope) *Scope {
 	}
 }
 
+// NewFunctionScope creates the scope for a function call. Assigning
+// to an undeclared name defines it in the closest such scope.
+func NewFunctionScope(outer *Scope) *Scope {
+	s := NewScope(outer)
+	s.function = true
+	return s
+}
+
 func (s *Scope) Get(name string) (Value, bool) {
 	if v, ok := s.values[name]; ok {
 		return v, ok
@@ -27,11 +38,64 @@ func (s *Scope) Set(name string, v Value) Value {
 	return v
 }
 
+// Names returns the sorted names of all the bindings visible from s.
+func (s *Scope) Names() []string {
+	seen := map[string]bool{}
+	var names []string
+	for scope := s; scope != nil; scope = scope.outer {
+		for name := range scope.values {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Assign updates the binding called name in the closest scope
+// that has it. If there is no such binding, it is created in the
+// closest function (or global) scope.
+func (s *Scope) Assign(name string, v Value) Value {
+	for scope := s; scope != nil; scope = scope.outer {
+		if _, ok := scope.values[name]; ok {
+			return scope.Set(name, v)
+		}
+	}
+	scope := s
+	for !scope.function && scope.outer != nil {
+		scope = scope.outer
+	}
+	return scope.Set(name, v)
+}
+
 func NewGlobalScope(g *GlobalObjects) *Scope {
 	s := NewScope(nil)
 	s.values["Object"] = g.Object
 	s.values["Class"] = g.Class
 	s.values["Boolean"] = g.Boolean
+	s.values["Number"] = g.Number
+	s.values["Integer"] = g.Integer
+	s.values["Float"] = g.Float
 	s.values["String"] = g.String
+	s.values["Array"] = g.Array
+	s.values["Map"] = g.Map
+	s.values["Iterator"] = g.Iterator
+	s.values["Range"] = g.Range
+	s.values["Error"] = g.Error
+	for _, klass := range []*Class{
+		g.TypeError,
+		g.NameError,
+		g.AttributeError,
+		g.ArgumentError,
+		g.ValueError,
+		g.IndexError,
+		g.KeyError,
+		g.StopIteration,
+		g.ZeroDivisionError,
+	} {
+		s.values[klass.name] = klass
+	}
 	return s
 }
@@ -0,0 +1,334 @@
+package eval
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// String *value*. Strings are immutable sequences of runes; they are
+// stored as UTF-8, but indexed by rune. Indexing is O(1): ASCII
+// strings are indexed directly, and other strings are decoded into
+// runes the first time they are indexed.
+type String struct {
+	Basic
+	s     string
+	runes []rune // decoded s, for non-ASCII strings
+	ascii int8   // 1 if s is ASCII, -1 if not, 0 if we do not know yet
+}
+
+// maxStringLen is the length in bytes of the longest String that
+// operations like repetition will build.
+const maxStringLen = 1 << 30
+
+func (g *GlobalObjects) NewString(str string) *String {
+	return &String{Basic: Basic{klass: g.String}, s: str}
+}
+
+func (s *String) String() string { return s.s }
+
+func (s *String) isASCII() bool {
+	if s.ascii == 0 {
+		s.ascii = 1
+		for i := 0; i < len(s.s); i++ {
+			if s.s[i] >= utf8.RuneSelf {
+				s.ascii = -1
+				break
+			}
+		}
+	}
+	return s.ascii > 0
+}
+
+// decode returns the runes of a non-ASCII string.
+func (s *String) decode() []rune {
+	if s.runes == nil {
+		s.runes = []rune(s.s)
+	}
+	return s.runes
+}
+
+// len returns the number of runes in s.
+func (s *String) len() int {
+	if s.isASCII() {
+		return len(s.s)
+	}
+	return len(s.decode())
+}
+
+// slice returns the runes of s from lo up to hi.
+func (s *String) slice(lo, hi int) string {
+	if s.isASCII() {
+		return s.s[lo:hi]
+	}
+	return string(s.decode()[lo:hi])
+}
+
+// runeIndex converts the byte offset i into s into a rune index.
+func (s *String) runeIndex(i int) int {
+	if s.isASCII() {
+		return i
+	}
+	return utf8.RuneCountInString(s.s[:i])
+}
+
+// stringArg returns the contents of v, an argument of the method
+// called name, which has to be a String.
+func (g *GlobalObjects) stringArg(name string, v Value) (string, *Error) {
+	s, ok := v.(*String)
+	if !ok {
+		return "", g.ctx.raisef(g.TypeError, "%s() expects a String, got %s", name, v.Klass().name)
+	}
+	return s.s, nil
+}
+
+func (g *GlobalObjects) initString() {
+	g.String = g.NewClass("String", g.Object)
+	g.defineMethod(g.String, "inspect", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NewString(strconv.Quote(ref.this.(*String).s))
+	})
+	g.defineMethod(g.String, "to_s", 0, func(ref *NativeFunction, args []Value) Value {
+		return ref.this
+	})
+	g.defineMethod(g.String, "len", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NewInteger(int64(ref.this.(*String).len()))
+	})
+	// like Array, strings can be indexed by a single index, a pair
+	// of indices, or a range.
+	g.defineMethod(g.String, "[]", -1, func(ref *NativeFunction, args []Value) Value {
+		str := ref.this.(*String)
+		switch len(args) {
+		case 1:
+			if r, ok := args[0].(*Range); ok {
+				lo, hi, step, err := g.rangeIndices(r, str.len())
+				if err != nil {
+					return err
+				}
+				if step == 1 {
+					return g.NewString(str.slice(lo, hi))
+				}
+				var buf strings.Builder
+				for i := lo; i < hi; i += step {
+					buf.WriteString(str.slice(i, i+1))
+				}
+				return g.NewString(buf.String())
+			}
+			i, err := g.elemIndex(args[0], str.len())
+			if err != nil {
+				return err
+			}
+			return g.NewString(str.slice(i, i+1))
+		case 2:
+			lo, hi, err := g.sliceIndices(args[0], args[1], str.len())
+			if err != nil {
+				return err
+			}
+			return g.NewString(str.slice(lo, hi))
+		}
+		return g.ctx.raisef(g.ArgumentError, "[]() takes 1 or 2 argument(s) but %d were given", len(args))
+	})
+	g.defineMethod(g.String, "bytes", 0, func(ref *NativeFunction, args []Value) Value {
+		s := ref.this.(*String).s
+		elems := make([]Value, len(s))
+		for i := 0; i < len(s); i++ {
+			elems[i] = g.NewInteger(int64(s[i]))
+		}
+		return g.NewArray(elems)
+	})
+	g.defineMethod(g.String, "chars", 0, func(ref *NativeFunction, args []Value) Value {
+		str := ref.this.(*String)
+		elems := make([]Value, str.len())
+		for i := range elems {
+			elems[i] = g.NewString(str.slice(i, i+1))
+		}
+		return g.NewArray(elems)
+	})
+	g.defineMethod(g.String, "upper", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NewString(strings.ToUpper(ref.this.(*String).s))
+	})
+	g.defineMethod(g.String, "lower", 0, func(ref *NativeFunction, args []Value) Value {
+		return g.NewString(strings.ToLower(ref.this.(*String).s))
+	})
+	// split() splits around runs of whitespace, while split(sep)
+	// splits around every occurrence of sep.
+	g.defineMethod(g.String, "split", -1, func(ref *NativeFunction, args []Value) Value {
+		s := ref.this.(*String).s
+		var parts []string
+		switch len(args) {
+		case 0:
+			parts = strings.Fields(s)
+		case 1:
+			sep, err := g.stringArg("split", args[0])
+			if err != nil {
+				return err
+			}
+			parts = strings.Split(s, sep)
+		default:
+			return g.ctx.raisef(g.ArgumentError, "split() takes 0 or 1 argument(s) but %d were given", len(args))
+		}
+		elems := make([]Value, len(parts))
+		for i, part := range parts {
+			elems[i] = g.NewString(part)
+		}
+		return g.NewArray(elems)
+	})
+	// strip() removes leading and trailing whitespace, while
+	// strip(chars) removes any of the runes in chars.
+	g.defineMethod(g.String, "strip", -1, func(ref *NativeFunction, args []Value) Value {
+		s := ref.this.(*String).s
+		switch len(args) {
+		case 0:
+			return g.NewString(strings.TrimSpace(s))
+		case 1:
+			chars, err := g.stringArg("strip", args[0])
+			if err != nil {
+				return err
+			}
+			return g.NewString(strings.Trim(s, chars))
+		}
+		return g.ctx.raisef(g.ArgumentError, "strip() takes 0 or 1 argument(s) but %d were given", len(args))
+	})
+	// replace(old, new) replaces every occurrence of old, and
+	// replace(old, new, n) only the first n.
+	g.defineMethod(g.String, "replace", -1, func(ref *NativeFunction, args []Value) Value {
+		if len(args) != 2 && len(args) != 3 {
+			return g.ctx.raisef(g.ArgumentError, "replace() takes 2 or 3 argument(s) but %d were given", len(args))
+		}
+		from, err := g.stringArg("replace", args[0])
+		if err != nil {
+			return err
+		}
+		to, err := g.stringArg("replace", args[1])
+		if err != nil {
+			return err
+		}
+		n := -1
+		if len(args) == 3 {
+			if n, err = g.toInt(args[2]); err != nil {
+				return err
+			}
+		}
+		return g.NewString(strings.Replace(ref.this.(*String).s, from, to, n))
+	})
+	g.defineMethod(g.String, "starts_with", 1, func(ref *NativeFunction, args []Value) Value {
+		prefix, err := g.stringArg("starts_with", args[0])
+		if err != nil {
+			return err
+		}
+		return g.NewBoolean(strings.HasPrefix(ref.this.(*String).s, prefix))
+	})
+	g.defineMethod(g.String, "ends_with", 1, func(ref *NativeFunction, args []Value) Value {
+		suffix, err := g.stringArg("ends_with", args[0])
+		if err != nil {
+			return err
+		}
+		return g.NewBoolean(strings.HasSuffix(ref.this.(*String).s, suffix))
+	})
+	// find returns the (rune) index of the first occurrence of sub,
+	// or -1 if there is none.
+	g.defineMethod(g.String, "find", 1, func(ref *NativeFunction, args []Value) Value {
+		sub, err := g.stringArg("find", args[0])
+		if err != nil {
+			return err
+		}
+		str := ref.this.(*String)
+		i := strings.Index(str.s, sub)
+		if i < 0 {
+			return g.NewInteger(-1)
+		}
+		return g.NewInteger(int64(str.runeIndex(i)))
+	})
+	g.defineMethod(g.String, "+", 1, func(ref *NativeFunction, args []Value) Value {
+		other, ok := args[0].(*String)
+		if !ok {
+			return g.ctx.raisef(g.TypeError, "unsupported operand types for +: String and %s", args[0].Klass().name)
+		}
+		return g.NewString(ref.this.(*String).s + other.s)
+	})
+	g.defineMethod(g.String, "*", 1, func(ref *NativeFunction, args []Value) Value {
+		n, ok := args[0].(*Integer)
+		if !ok {
+			return g.ctx.raisef(g.TypeError, "unsupported operand types for *: String and %s", args[0].Klass().name)
+		}
+		if n.toBig().Sign() < 0 {
+			return g.ctx.raisef(g.ValueError, "cannot repeat a String %s times", n)
+		}
+		s := ref.this.(*String).s
+		if s == "" {
+			return g.NewString("")
+		}
+		// strings.Repeat panics if the result is too large.
+		if n.big != nil || n.i > int64(maxStringLen/len(s)) {
+			return g.ctx.raisef(g.ValueError, "String repeated %s times is too long", n)
+		}
+		return g.NewString(strings.Repeat(s, int(n.i)))
+	})
+	g.defineMethod(g.String, "==", 1, func(ref *NativeFunction, args []Value) Value {
+		other, ok := args[0].(*String)
+		return g.NewBoolean(ok && ref.this.(*String).s == other.s)
+	})
+	// comparing UTF-8 bytewise is the same as comparing code points.
+	compare := map[string]func(a, b string) bool{
+		"<":  func(a, b string) bool { return a < b },
+		"<=": func(a, b string) bool { return a <= b },
+		">":  func(a, b string) bool { return a > b },
+		">=": func(a, b string) bool { return a >= b },
+	}
+	for op, fn := range compare {
+		op, fn := op, fn
+		g.defineMethod(g.String, op, 1, func(ref *NativeFunction, args []Value) Value {
+			other, ok := args[0].(*String)
+			if !ok {
+				return g.ctx.raisef(g.TypeError, "unsupported operand types for %s: String and %s",
+					op, args[0].Klass().name)
+			}
+			return g.NewBoolean(fn(ref.this.(*String).s, other.s))
+		})
+	}
+	// format replaces {} with the next argument, and {n} with the n-th
+	// argument (from 0). Arguments are converted as in interpolated
+	// strings. Use {{ and }} for literal braces.
+	g.defineMethod(g.String, "format", -1, func(ref *NativeFunction, args []Value) Value {
+		s := ref.this.(*String).s
+		var buf strings.Builder
+		next := 0
+		for i := 0; i < len(s); i++ {
+			switch {
+			case strings.HasPrefix(s[i:], "{{"):
+				buf.WriteByte('{')
+				i++
+			case strings.HasPrefix(s[i:], "}}"):
+				buf.WriteByte('}')
+				i++
+			case s[i] == '{':
+				end := strings.IndexByte(s[i:], '}')
+				if end < 0 {
+					return g.ctx.raisef(g.ValueError, "unclosed { in format string")
+				}
+				n := next
+				if field := s[i+1 : i+end]; field != "" {
+					var err error
+					if n, err = strconv.Atoi(field); err != nil || n < 0 {
+						return g.ctx.raisef(g.ValueError, "invalid field {%s} in format string", field)
+					}
+				} else {
+					next++
+				}
+				if n >= len(args) {
+					return g.ctx.raisef(g.IndexError, "format() needs argument %d, but only %d were given", n, len(args))
+				}
+				str, err := g.ctx.toS(args[n])
+				if err != nil {
+					return err
+				}
+				buf.WriteString(str)
+				i += end
+			case s[i] == '}':
+				return g.ctx.raisef(g.ValueError, "single } in format string")
+			default:
+				buf.WriteByte(s[i])
+			}
+		}
+		return g.NewString(buf.String())
+	})
+}
@@ -0,0 +1,91 @@
+// Package format pretty-prints jingle programs in a canonical layout:
+// one statement per line, blocks indented by two spaces, and single
+// spaces around operators. Comments are kept where they were, and so
+// are single blank lines between statements.
+//
+// A few choices are left to the input: a construct that is written
+// on one line, like `fn(x) x * 2 end`, is kept on one line, and a
+// bracketed list whose brackets are on other lines than its elements
+// is written with one element per line.
+package format
+
+import (
+	"jingle/ast"
+	"jingle/parser"
+	"jingle/scanner"
+	"strings"
+)
+
+// Source formats src, the source of a program in filename. If src
+// cannot be parsed, the errors are returned instead.
+func Source(filename string, src string) (string, []parser.ParserError) {
+	s := &commentSource{Scanner: scanner.New(filename, src, scanner.KeepComments())}
+	prog, errs := parser.NewSource(filename, s).Parse()
+	if errs != nil {
+		return "", errs
+	}
+	return Program(prog, s.comments), nil
+}
+
+// Program formats prog, parsed from an input with the given comments,
+// in the order they appear in it.
+func Program(prog *ast.Program, comments []scanner.Token) string {
+	p := &printer{comments: comments}
+	p.program(prog)
+	return p.out.String()
+}
+
+// commentSource collects the comments before the tokens it returns.
+type commentSource struct {
+	*scanner.Scanner
+	comments []scanner.Token
+}
+
+func (s *commentSource) Next() (scanner.Token, error) {
+	tok, err := s.Scanner.Next()
+	if err == nil && tok.Trivia != nil {
+		for _, c := range tok.Trivia.Comments {
+			// the EOF token may be returned more than once.
+			if n := len(s.comments); n == 0 || c.Offset > s.comments[n-1].Offset {
+				s.comments = append(s.comments, c)
+			}
+		}
+	}
+	return tok, err
+}
+
+// quote returns s as a string literal.
+func quote(s string) string {
+	return `"` + escape(s) + `"`
+}
+
+// escape escapes s to be written between the quotes of a string
+// literal.
+func escape(s string) string {
+	var b strings.Builder
+	for i, ch := range s {
+		switch ch {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case 0:
+			b.WriteString(`\0`)
+		case '$':
+			if strings.HasPrefix(s[i+1:], "{") {
+				b.WriteString(`\$`)
+			} else {
+				b.WriteRune(ch)
+			}
+		default:
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
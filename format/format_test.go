@@ -0,0 +1,143 @@
+package format_test
+
+import (
+	"jingle/ast"
+	"jingle/format"
+	"jingle/parser"
+	"jingle/scanner"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"\n\n", ""},
+		{"let x=1;let y  =  2", "let x = 1\nlet y = 2\n"},
+		{"x=-a+b*c**2", "x = -a + b * c ** 2\n"},
+		{"a..b; a ... b", "a..b\na...b\n"},
+		{"!a  and  b or c", "!a and b or c\n"},
+		{"x if  c else y", "x if c else y\n"},
+		{"a . b(1 ,2,)[ 0 ]", "a.b(1, 2)[0]\n"},
+		{"[ 1,2 ,3 ]", "[1, 2, 3]\n"},
+		{"[\n1,2]", "[\n  1,\n  2,\n]\n"},
+		{"{ a:1,\"b\" : 2, [c]:3 }", "{a: 1, \"b\": 2, [c]: 3}\n"},
+		{`"a\"b\\c\td${x}e\${f}"`, `"a\"b\\c\td${x}e\${f}"` + "\n"},
+		{"a\n\n\n\nb", "a\n\nb\n"},
+		{"while x do\ny\nend", "while x do\n  y\nend\n"},
+		{"while x do end", "while x do end\n"},
+		{"while x do\nend", "while x do\nend\n"},
+		{"for a,b in c do\nd\nend", "for a, b in c do\n  d\nend\n"},
+		{"for [a,b] in c do d end", "for [a, b] in c do d end\n"},
+		{"l:while x do break l end", "l: while x do break l end\n"},
+		{"if a then b;c else d end", "if a then b; c else d end\n"},
+		{"if a then\nb\nelse\nc\nend", "if a then\n  b\nelse\n  c\nend\n"},
+		{"class A<B\ndef f(a,b)\nreturn a\nend\nend",
+			"class A < B\n  def f(a, b)\n    return a\n  end\nend\n"},
+		{"class A def f() nil end end", "class A; def f() nil end end\n"},
+		{"try a catch e:E b finally c end", "try a catch e: E; b finally c end\n"},
+		{"try\na\ncatch\nb\nend", "try\n  a\ncatch\n  b\nend\n"},
+		{"f = fn( x ) x end", "f = fn(x) x end\n"},
+		// comments
+		{"a // one\nb", "a // one\nb\n"},
+		{"// one\n\n\n// two\na", "// one\n\n// two\na\n"},
+		{"a /* one */ + b", "a /* one */ + b\n"},
+		{"while x do\n  y\n    // last\nend", "while x do\n  y\n  // last\nend\n"},
+		{"f(a, // one\nb)", "f(\n  a, // one\n  b,\n)\n"},
+		{"a\n// the end", "a\n// the end\n"},
+	}
+	for i, tt := range tests {
+		actual, errs := format.Source("<test>", tt.input)
+		if errs != nil {
+			t.Fatalf("test[%d] failed to parse: %v", i, errs)
+		}
+		if actual != tt.expected {
+			t.Fatalf("test[%d] expected %q, got %q", i, tt.expected, actual)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	for i, input := range []string{"let", "if x then", "(1"} {
+		if _, errs := format.Source("<test>", input); errs == nil {
+			t.Fatalf("test[%d] expected an error", i)
+		}
+	}
+}
+
+// TestFormatCorpus checks that formatting the files in testdata keeps
+// their syntax trees and comments, and that formatting the result
+// again does not change it.
+func TestFormatCorpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.jg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no files in testdata")
+	}
+	for _, name := range files {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted, errs := format.Source(name, string(src))
+		if errs != nil {
+			t.Fatalf("%s: failed to format: %v", name, errs)
+		}
+		before, after := parse(t, name, string(src)), parse(t, name, formatted)
+		if !ast.Equal(before, after) {
+			t.Fatalf("%s: the syntax tree changed, formatted:\n%s", name, formatted)
+		}
+		again, errs := format.Source(name, formatted)
+		if errs != nil {
+			t.Fatalf("%s: failed to format again: %v", name, errs)
+		}
+		if again != formatted {
+			t.Fatalf("%s: not idempotent, formatted once:\n%s\ntwice:\n%s", name, formatted, again)
+		}
+		expected, actual := comments(t, string(src)), comments(t, formatted)
+		if len(expected) != len(actual) {
+			t.Fatalf("%s: expected %d comments, got %d", name, len(expected), len(actual))
+		}
+		for i := range expected {
+			if expected[i] != actual[i] {
+				t.Fatalf("%s: comment[%d] expected %q, got %q", name, i, expected[i], actual[i])
+			}
+		}
+	}
+}
+
+func parse(t *testing.T, name string, src string) *ast.Program {
+	t.Helper()
+	prog, errs := parser.NewSource(name, scanner.New(name, src)).Parse()
+	if errs != nil {
+		t.Fatalf("%s: failed to parse: %v", name, errs)
+	}
+	return prog
+}
+
+// comments returns the text of the comments in src.
+func comments(t *testing.T, src string) []string {
+	t.Helper()
+	s := scanner.New("<test>", src, scanner.KeepComments())
+	var texts []string
+	for {
+		tok, err := s.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Trivia != nil {
+			for _, c := range tok.Trivia.Comments {
+				texts = append(texts, c.Value)
+			}
+		}
+		if tok.Type == scanner.TokenEOF {
+			return texts
+		}
+	}
+}
@@ -0,0 +1,487 @@
+package format
+
+import (
+	"jingle/ast"
+	"jingle/scanner"
+	"math"
+	"strings"
+)
+
+const indentation = "  "
+
+type printer struct {
+	out      strings.Builder
+	indent   int
+	newlines int             // newlines to write before the next text
+	blank    bool            // whether the pending newline can become a blank line
+	lastLine int             // input line of the last token or comment written
+	comments []scanner.Token // comments not written yet
+	flat     int             // > 0 within interpolated strings, which ignore newlines
+	space    bool            // whether to write a space before the next text
+}
+
+// write writes text, after the pending newlines and the indentation.
+func (p *printer) write(text string) {
+	if p.newlines > 0 && p.out.Len() > 0 {
+		p.out.WriteString(strings.Repeat("\n", p.newlines))
+		p.out.WriteString(strings.Repeat(indentation, p.indent))
+	} else if p.space {
+		p.out.WriteString(" ")
+	}
+	p.newlines = 0
+	p.space = false
+	p.out.WriteString(text)
+}
+
+// last returns the last byte written, as a string.
+func (p *printer) last() string {
+	s := p.out.String()
+	if s == "" {
+		return ""
+	}
+	return s[len(s)-1:]
+}
+
+// newline makes the next text start on a new line.
+func (p *printer) newline() {
+	if p.newlines == 0 {
+		p.newlines = 1
+	}
+}
+
+// startLine keeps a blank line before text from line in the input,
+// if there is one and the pending newline allows it.
+func (p *printer) startLine(line int) {
+	if p.newlines == 1 && p.blank && line > p.lastLine+1 {
+		p.newlines = 2
+	}
+}
+
+// token writes text in place of tok, after the comments before tok.
+func (p *printer) token(tok scanner.Token, text string) {
+	p.flush(tok.Offset, tok.LineNo)
+	switch tok.Type {
+	case scanner.TokenRParen, scanner.TokenRBracket, scanner.TokenRBrace:
+		// no space between a comment and a closing bracket.
+		p.space = false
+	}
+	p.startLine(tok.LineNo)
+	p.write(text)
+	p.blank = false
+	p.lastLine = tok.EndLineNo
+}
+
+// flush writes the comments before offset in the input, where the
+// next token starts on the given line.
+func (p *printer) flush(offset int, line int) {
+	for len(p.comments) > 0 && p.comments[0].Offset < offset {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		isLine := strings.HasPrefix(c.Value, "//")
+		switch {
+		case p.newlines > 0 && c.LineNo == p.lastLine:
+			// at the end of the previous line.
+			p.out.WriteString(" ")
+			p.out.WriteString(c.Value)
+		case p.newlines > 0 || p.out.Len() == 0:
+			// on a line of its own, unless it is followed by the
+			// next token on the same line.
+			p.startLine(c.LineNo)
+			p.write(c.Value)
+			if isLine || c.EndLineNo != line {
+				p.newline()
+			} else {
+				p.space = true
+			}
+		default:
+			// in the middle of a line, apart from what is around it
+			// but opening brackets.
+			if !p.space && !strings.ContainsAny(p.last(), " ([{") {
+				p.space = true
+			}
+			p.write(c.Value)
+			p.space = !isLine
+		}
+		if isLine {
+			p.newline()
+		}
+		p.lastLine = c.EndLineNo
+	}
+}
+
+func (p *printer) program(prog *ast.Program) {
+	p.blank = true
+	p.statements(prog.Statements)
+	p.newline()
+	p.blank = true
+	p.flush(math.MaxInt32, -1)
+	if p.out.Len() > 0 {
+		p.out.WriteString("\n")
+	}
+}
+
+// statements writes stmts on lines of their own.
+func (p *printer) statements(stmts []ast.Statement) {
+	for i, stmt := range stmts {
+		if i > 0 {
+			p.newline()
+			p.blank = true
+		}
+		p.statement(stmt)
+	}
+}
+
+// inline reports whether the construct from start to the end of the
+// last of its blocks is kept on one line: it has to be written on one
+// line in the input.
+func (p *printer) inline(start scanner.Token, last *ast.Block) bool {
+	return p.flat > 0 || start.LineNo == last.Terminal.LineNo
+}
+
+// block writes the statements of block, and the keyword that ends it.
+// Inline blocks are written on the current line, separated from the
+// header of the construct by sep.
+func (p *printer) block(block *ast.Block, inline bool, sep string) {
+	if inline {
+		for i, stmt := range block.Statements {
+			if i == 0 {
+				p.write(sep)
+			} else {
+				p.write("; ")
+			}
+			p.statement(stmt)
+		}
+		p.write(" ")
+		p.token(block.Terminal, block.Terminal.Value)
+		return
+	}
+	p.indent++
+	p.newline()
+	p.statements(block.Statements)
+	p.newline()
+	p.blank = len(block.Statements) > 0
+	p.flush(block.Terminal.Offset, block.Terminal.LineNo)
+	p.indent--
+	p.blank = false
+	p.token(block.Terminal, block.Terminal.Value)
+}
+
+// list writes a bracketed list of elements, with the given spans,
+// from open up to close. It is written with one element per line if
+// the input has the brackets on other lines than the elements, or
+// line comments between them.
+func (p *printer) list(open scanner.Token, spans []scanner.Span, elem func(i int), close scanner.Token) {
+	p.token(open, open.Value)
+	multiline := false
+	if p.flat == 0 {
+		if len(spans) > 0 {
+			multiline = open.LineNo != spans[0].Start.LineNo ||
+				close.LineNo != spans[len(spans)-1].End.LineNo
+		}
+		multiline = multiline || p.lineCommentBetween(close.Offset, spans)
+	}
+	if !multiline {
+		for i := range spans {
+			if i > 0 {
+				p.write(", ")
+			}
+			elem(i)
+		}
+		p.token(close, close.Value)
+		return
+	}
+	p.indent++
+	for i := range spans {
+		p.newline()
+		elem(i)
+		p.write(",")
+	}
+	p.newline()
+	p.flush(close.Offset, close.LineNo)
+	p.indent--
+	p.token(close, close.Value)
+}
+
+// lineCommentBetween reports whether there is a line comment before
+// offset that is not within one of spans.
+func (p *printer) lineCommentBetween(offset int, spans []scanner.Span) bool {
+comments:
+	for _, c := range p.comments {
+		if c.Offset >= offset {
+			break
+		}
+		if !strings.HasPrefix(c.Value, "//") {
+			continue
+		}
+		for _, span := range spans {
+			if span.Start.Offset <= c.Offset && c.Offset < span.End.Offset {
+				continue comments
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (p *printer) label(label *ast.IdentifierLiteral) {
+	if label != nil {
+		p.token(label.Token, label.Name())
+		p.write(": ")
+	}
+}
+
+func (p *printer) params(params []*ast.IdentifierLiteral) {
+	// params after a line comment are indented like the body.
+	p.indent++
+	defer func() { p.indent-- }()
+	p.write("(")
+	for i, param := range params {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.token(param.Token, param.Name())
+	}
+	p.write(")")
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch node := stmt.(type) {
+	case *ast.ExpressionStatement:
+		p.expr(node.Expr)
+	case *ast.LetStatement:
+		p.token(node.Token, "let")
+		p.write(" ")
+		p.expr(node.Binding)
+	case *ast.ForStatement:
+		inline := p.inline(node.Token, node.Body)
+		p.label(node.Label)
+		p.token(node.Token, "for")
+		p.write(" ")
+		if arr, ok := node.Binding.(*ast.ArrayLiteral); ok && arr.Token.Offset == arr.Token.EndOffset {
+			// the `for a, b in c` shorthand, without brackets.
+			for i, elem := range arr.Elems {
+				if i > 0 {
+					p.write(", ")
+				}
+				p.expr(elem)
+			}
+		} else {
+			p.expr(node.Binding)
+		}
+		p.write(" in ")
+		p.expr(node.Iterable)
+		p.write(" do")
+		p.block(node.Body, inline, " ")
+	case *ast.WhileStatement:
+		inline := p.inline(node.Token, node.Body)
+		p.label(node.Label)
+		p.token(node.Token, "while")
+		p.write(" ")
+		p.expr(node.Condition)
+		p.write(" do")
+		p.block(node.Body, inline, " ")
+	case *ast.IfStatement:
+		then := node.Then.(*ast.Block)
+		last := then
+		if node.Else != nil {
+			last = node.Else.(*ast.Block)
+		}
+		inline := p.inline(node.Token, last)
+		p.token(node.Token, "if")
+		p.write(" ")
+		p.expr(node.Cond)
+		p.write(" then")
+		p.block(then, inline, " ")
+		if node.Else != nil {
+			p.block(last, inline, " ")
+		}
+	case *ast.ClassStatement:
+		inline := p.inline(node.Token, node.Body)
+		p.token(node.Token, "class")
+		p.write(" ")
+		p.expr(node.Name)
+		if node.SuperClass != nil {
+			p.write(" < ")
+			p.expr(node.SuperClass)
+		}
+		// a statement right after the superclass would continue it.
+		p.block(node.Body, inline, "; ")
+	case *ast.MethodDeclaration:
+		inline := p.inline(node.Token, node.Body)
+		p.token(node.Token, "def")
+		p.write(" ")
+		p.token(node.MethodName.Token, node.MethodName.Name)
+		p.params(node.Params)
+		p.block(node.Body, inline, " ")
+	case *ast.ReturnStatement:
+		p.token(node.Token, "return")
+		p.write(" ")
+		p.expr(node.Expr)
+	case *ast.RaiseStatement:
+		p.token(node.Token, "raise")
+		p.write(" ")
+		p.expr(node.Expr)
+	case *ast.BreakStatement:
+		p.token(node.Token, "break")
+		if node.Label != nil {
+			p.write(" ")
+			p.expr(node.Label)
+		}
+	case *ast.ContinueStatement:
+		p.token(node.Token, "continue")
+		if node.Label != nil {
+			p.write(" ")
+			p.expr(node.Label)
+		}
+	case *ast.TryStatement:
+		p.tryStatement(node)
+	default:
+		panic("format: unexpected statement " + stmt.Type().String())
+	}
+}
+
+func (p *printer) tryStatement(node *ast.TryStatement) {
+	last := node.Body
+	if node.Finally != nil {
+		last = node.Finally
+	} else if n := len(node.Catches); n > 0 {
+		last = node.Catches[n-1].Body
+	}
+	inline := p.inline(node.Token, last)
+	p.token(node.Token, "try")
+	p.block(node.Body, inline, " ")
+	// the catch and finally keywords end the blocks before them.
+	for _, c := range node.Catches {
+		if c.Binding != nil {
+			p.write(" ")
+			p.expr(c.Binding)
+		}
+		if c.Class != nil {
+			p.write(": ")
+			p.expr(c.Class)
+		}
+		// a statement right after the binding or class would be
+		// taken as part of them.
+		p.block(c.Body, inline, "; ")
+	}
+	if node.Finally != nil {
+		p.block(node.Finally, inline, " ")
+	}
+}
+
+func (p *printer) expr(expr ast.Expression) {
+	switch node := expr.(type) {
+	case *ast.IdentifierLiteral:
+		p.token(node.Token, node.Token.Value)
+	case *ast.NilLiteral:
+		p.token(node.Token, node.Token.Value)
+	case *ast.BooleanLiteral:
+		p.token(node.Token, node.Token.Value)
+	case *ast.IntegerLiteral:
+		p.token(node.Token, node.Token.Value)
+	case *ast.FloatLiteral:
+		p.token(node.Token, node.Token.Value)
+	case *ast.StringLiteral:
+		p.token(node.Token, quote(node.Value))
+	case *ast.InterpolatedString:
+		p.flat++
+		p.token(node.Token, `"`+escape(node.Strings[0])+"${")
+		for i, e := range node.Exprs {
+			p.expr(e)
+			str := escape(node.Strings[i+1])
+			if i == len(node.Exprs)-1 {
+				p.token(node.End, "}"+str+`"`)
+			} else {
+				p.write("}" + str + "${")
+			}
+		}
+		p.flat--
+	case *ast.PrefixExpression:
+		p.token(node.Token, node.Op)
+		p.expr(node.Expr)
+	case *ast.InfixExpression:
+		p.expr(node.Left)
+		if node.Token.Type == scanner.TokenDotDot || node.Token.Type == scanner.TokenDotDotDot {
+			p.token(node.Token, node.Op)
+		} else {
+			p.infixOp(node.Token)
+		}
+		p.expr(node.Right)
+	case *ast.AssignmentExpression:
+		p.expr(node.Left)
+		p.infixOp(node.Token)
+		p.expr(node.Right)
+	case *ast.OrExpression:
+		p.expr(node.Left)
+		p.infixOp(node.Token)
+		p.expr(node.Right)
+	case *ast.AndExpression:
+		p.expr(node.Left)
+		p.infixOp(node.Token)
+		p.expr(node.Right)
+	case *ast.AttrExpression:
+		p.expr(node.Target)
+		p.token(node.Token, ".")
+		p.expr(node.Name)
+	case *ast.IndexExpression:
+		p.expr(node.Target)
+		p.list(node.Token, spans(node.Args), func(i int) { p.expr(node.Args[i]) }, node.RBracket)
+	case *ast.CallExpression:
+		p.expr(node.Target)
+		p.list(node.Token, spans(node.Args), func(i int) { p.expr(node.Args[i]) }, node.RParen)
+	case *ast.ParenExpression:
+		p.token(node.Token, "(")
+		p.expr(node.Expr)
+		p.token(node.RParen, ")")
+	case *ast.IfElseExpression:
+		p.expr(node.Then)
+		p.infixOp(node.Token)
+		p.expr(node.Cond)
+		if node.Else != nil {
+			p.write(" else ")
+			p.expr(node.Else)
+		}
+	case *ast.FunctionLiteral:
+		inline := p.inline(node.Token, node.Body)
+		p.token(node.Token, "fn")
+		p.params(node.Params)
+		p.block(node.Body, inline, " ")
+	case *ast.ArrayLiteral:
+		p.list(node.Token, spans(node.Elems), func(i int) { p.expr(node.Elems[i]) }, node.RBracket)
+	case *ast.MapLiteral:
+		entrySpans := make([]scanner.Span, len(node.Entries))
+		for i, entry := range node.Entries {
+			entrySpans[i] = entry.Key.Span().Join(entry.Value.Span())
+		}
+		p.list(node.Token, entrySpans, func(i int) { p.mapEntry(node.Entries[i]) }, node.RBrace)
+	default:
+		panic("format: unexpected expression " + expr.Type().String())
+	}
+}
+
+// infixOp writes an operator with spaces around it.
+func (p *printer) infixOp(tok scanner.Token) {
+	p.write(" ")
+	p.token(tok, tok.Value)
+	p.write(" ")
+}
+
+func (p *printer) mapEntry(entry *ast.MapEntry) {
+	if entry.Computed {
+		p.write("[")
+		p.expr(entry.Key)
+		p.write("]")
+	} else {
+		p.expr(entry.Key)
+	}
+	p.write(": ")
+	p.expr(entry.Value)
+}
+
+func spans(exprs []ast.Expression) []scanner.Span {
+	spans := make([]scanner.Span, len(exprs))
+	for i, expr := range exprs {
+		spans[i] = expr.Span()
+	}
+	return spans
+}
@@ -1,5 +1,10 @@
 module jingle
 
-go 1.16
+go 1.18
 
-require golang.org/x/tools v0.1.5 // indirect
+require (
+	golang.org/x/term v0.21.0
+	golang.org/x/text v0.21.0
+)
+
+require golang.org/x/sys v0.21.0 // indirect
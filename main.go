@@ -1,23 +1,52 @@
+// Command jingle runs, parses and checks jingle programs.
+//
+// Usage:
+//
+//	jingle run file.jg [args...]
+//	jingle parse [--deep | --json] [file]
+//	jingle tokens [file]
+//	jingle check file...
+//	jingle fmt [-w] [--check] [--diff] [file...]
+//	jingle repl
+//
+// Files called "-", and missing files, are read from stdin. The exit
+// status tells what went wrong, see the exit* constants.
 package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"jingle/ast"
+	"jingle/eval"
+	"jingle/format"
 	"jingle/parser"
 	sc "jingle/scanner"
 	"os"
-	"reflect"
 	"strings"
+
+	"golang.org/x/term"
 )
 
-const (
+// Colors are only used when stdout is a terminal, see main.
+var (
 	OK_FORMAT  = "\x1b[1;32m"
 	ERR_FORMAT = "\x1b[1;31m"
 	ERR_RESET  = "\x1b[0m"
 )
 
+// Exit statuses.
+const (
+	exitOK      = 0
+	exitFailure = 1 // a runtime error, or a file could not be read
+	exitUsage   = 2
+	exitScan    = 3 // the input could not be scanned
+	exitParse   = 4 // the input could not be parsed
+)
+
 func printOkEnd() {
 	fmt.Printf("%s-------------------%s\n",
 		OK_FORMAT,
@@ -33,15 +62,15 @@ func printOkStart() {
 }
 
 func printErrors(errors []error) {
-	fmt.Printf("%s------ ERRORS ------%s\n", ERR_FORMAT, ERR_RESET)
+	fmt.Fprintf(os.Stderr, "%s------ ERRORS ------%s\n", ERR_FORMAT, ERR_RESET)
 	for _, err := range errors {
-		fmt.Printf("%s\n", err)
+		fmt.Fprintf(os.Stderr, "%s\n", err)
 	}
-	fmt.Printf("%s--------------------%s\n", ERR_FORMAT, ERR_RESET)
+	fmt.Fprintf(os.Stderr, "%s--------------------%s\n", ERR_FORMAT, ERR_RESET)
 }
 
 func printError(err error) {
-	fmt.Printf("%s------ ERROR ------%s\n%s\n%s-------------------%s\n",
+	fmt.Fprintf(os.Stderr, "%s------ ERROR ------%s\n%s\n%s-------------------%s\n",
 		ERR_FORMAT,
 		ERR_RESET,
 		err,
@@ -50,86 +79,303 @@ func printError(err error) {
 	)
 }
 
+type command struct {
+	name string
+	args string // shown in the usage
+	run  func(args []string) int
+}
+
+var commands = []command{
+	{"run", "file.jg [args...]", runCommand},
+	{"parse", "[--deep | --json] [file]", parseCommand},
+	{"tokens", "[file]", tokensCommand},
+	{"check", "file...", checkCommand},
+	{"fmt", "[-w] [--check] [--diff] [file...]", fmtCommand},
+	{"repl", "", replCommand},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %s\n", strings.TrimSpace("jingle "+cmd.name+" "+cmd.args))
+	}
+}
+
+// commandUsage returns the usage function of the flags of the
+// command called name.
+func commandUsage(fs *flag.FlagSet, name string, args string) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "usage: %s\n", strings.TrimSpace("jingle "+name+" "+args))
+		fs.PrintDefaults()
+	}
+}
+
 func main() {
-	var deep bool
-	flag.BoolVar(&deep, "deep", false, "recursively print ast")
-	flag.Parse()
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		OK_FORMAT, ERR_FORMAT, ERR_RESET = "", "", ""
+	}
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "jingle: unknown command %q\n", os.Args[1])
+	usage()
+	os.Exit(exitUsage)
+}
 
-	str, err := io.ReadAll(os.Stdin)
+// isStdin reports whether name stands for stdin, see open.
+func isStdin(name string) bool {
+	return name == "" || name == "-"
+}
+
+// open opens the file called name, or stdin if name is "" or "-".
+func open(name string) (string, io.ReadCloser, error) {
+	if isStdin(name) {
+		return "<stdin>", io.NopCloser(os.Stdin), nil
+	}
+	f, err := os.Open(name)
+	return name, f, err
+}
+
+// scanSource remembers whether the scanner found errors, to tell
+// them apart from the errors found by the parser.
+type scanSource struct {
+	*sc.Scanner
+	failed bool
+}
+
+func (s *scanSource) Next() (sc.Token, error) {
+	tok, err := s.Scanner.Next()
 	if err != nil {
-		printError(err)
-		return
-	}
-	input := string(str)
-	lex := sc.New("<stdin>", input)
-	lex.ScanAll()
-	if lex.Errors() != nil {
-		printErrors(lex.Errors())
-		return
-	}
-	// fmt.Println(lex.Tokens())
-	p := parser.New("<stdin>", lex.Tokens())
-	program, err := p.Parse()
+		s.failed = true
+	}
+	return tok, err
+}
+
+// parseFile parses the file called name, and returns the program
+// and its source, or the exit status for the errors it found, which
+// are printed.
+func parseFile(name string) (*ast.Program, string, int) {
+	filename, f, err := open(name)
 	if err != nil {
 		printError(err)
-		return
+		return nil, "", exitFailure
+	}
+	defer f.Close()
+	// keep the source as it is read, for the tracebacks.
+	var src strings.Builder
+	s := &scanSource{Scanner: sc.NewReader(filename, io.TeeReader(f, &src))}
+	program, errs := parser.NewSource(filename, s).Parse()
+	if errs != nil {
+		errors := make([]error, len(errs))
+		for i, err := range errs {
+			errors[i] = err
+		}
+		printErrors(errors)
+		if s.failed {
+			return nil, "", exitScan
+		}
+		return nil, "", exitParse
+	}
+	return program, src.String(), exitOK
+}
+
+func runCommand(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.Usage = commandUsage(fs, "run", "file.jg [args...]")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	program, src, status := parseFile(fs.Arg(0))
+	if program == nil {
+		return status
+	}
+	ctx := eval.NewContext()
+	ctx.SetArgs(fs.Args()[1:])
+	ctx.AddSource(program.Filename, src)
+	if err, ok := ctx.Eval(program).(*eval.Error); ok {
+		fmt.Fprintf(os.Stderr, "%s%s%s\n", ERR_FORMAT, ctx.FormatError(err), ERR_RESET)
+		return exitFailure
 	}
-	if deep {
+	return exitOK
+}
+
+func parseCommand(args []string) int {
+	var deep, asJSON bool
+	fs := flag.NewFlagSet("parse", flag.ExitOnError)
+	fs.BoolVar(&deep, "deep", false, "recursively print ast")
+	fs.BoolVar(&asJSON, "json", false, "print ast as JSON")
+	fs.Usage = commandUsage(fs, "parse", "[--deep | --json] [file]")
+	fs.Parse(args)
+	if fs.NArg() > 1 || (deep && asJSON) {
+		fs.Usage()
+		return exitUsage
+	}
+	program, _, status := parseFile(fs.Arg(0))
+	if program == nil {
+		return status
+	}
+	if asJSON {
+		// no banners, the output is for other programs.
+		data, err := ast.MarshalJSON(program)
+		if err != nil {
+			printError(err)
+			return exitFailure
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", "  "); err != nil {
+			printError(err)
+			return exitFailure
+		}
+		out.WriteString("\n")
+		out.WriteTo(os.Stdout)
+	} else if deep {
 		printOkStart()
 		w := bufio.NewWriter(os.Stdout)
-		Frprint(w, program, 0)
+		ast.Frprint(w, program, 0)
 		w.WriteString("\n")
 		w.Flush()
 		printOkEnd()
 	} else {
 		fmt.Println(program.String())
 	}
+	return exitOK
 }
 
-type w interface {
-	io.StringWriter
-	io.Writer
-}
-
-func Frprint(out w, value interface{}, level int) {
-	if token, ok := value.(sc.Token); ok {
-		// compact formatting for token
-		fmt.Fprintf(out, "%s", token)
-		return
-	}
-	v := reflect.ValueOf(value)
-	switch v.Kind() {
-	case reflect.Ptr:
-		fmt.Fprintf(out, "&")
-		Frprint(out, v.Elem().Interface(), level)
-	case reflect.Slice:
-		indent := strings.Repeat("  ", level+1)
-		fmt.Fprintf(out, "%T[", value)
-		last := v.Len() - 1
-		for i := 0; i < v.Len(); i++ {
-			fmt.Fprintf(out, "\n%s", indent)
-			Frprint(out, v.Index(i).Interface(), level+1)
-			if i != last {
-				out.WriteString(",")
-			}
+func tokensCommand(args []string) int {
+	fs := flag.NewFlagSet("tokens", flag.ExitOnError)
+	fs.Usage = commandUsage(fs, "tokens", "[file]")
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	filename, f, err := open(fs.Arg(0))
+	if err != nil {
+		printError(err)
+		return exitFailure
+	}
+	defer f.Close()
+	s := sc.NewReader(filename, f)
+	var errors []error
+	for {
+		tok, err := s.Next()
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		fmt.Println(tok)
+		if tok.Type == sc.TokenEOF {
+			break
+		}
+	}
+	if errors != nil {
+		printErrors(errors)
+		return exitScan
+	}
+	return exitOK
+}
+
+// checkCommand parses every file, and reports the errors found.
+// The exit status is that of the first file with errors.
+func checkCommand(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	fs.Usage = commandUsage(fs, "check", "file...")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	status := exitOK
+	for _, name := range fs.Args() {
+		if _, _, s := parseFile(name); status == exitOK {
+			status = s
 		}
-		fmt.Fprintf(out, "\n%s]", strings.Repeat("  ", level))
-	case reflect.Struct:
-		indent := strings.Repeat("  ", level+1)
-		fmt.Fprintf(out, "%T{", value)
-		t := reflect.TypeOf(value)
-		for i := 0; i < t.NumField(); i++ {
-			if !t.Field(i).Anonymous {
-				if i > 1 {
-					out.WriteString(",") // from the previous iteration
-				}
-				name := t.Field(i).Name
-				fmt.Fprintf(out, "\n%s%s: ", indent, name)
-				Frprint(out, v.FieldByName(name).Interface(), level+1)
+	}
+	return status
+}
+
+// fmtCommand formats the files, or stdin if there are none, and
+// prints the result unless told otherwise.
+func fmtCommand(args []string) int {
+	var write, check, diff bool
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	fs.BoolVar(&write, "w", false, "write the result to the files instead of stdout")
+	fs.BoolVar(&check, "check", false, "list the files that are not formatted, and fail if there are any")
+	fs.BoolVar(&diff, "diff", false, "print the changes instead of the result")
+	fs.Usage = commandUsage(fs, "fmt", "[-w] [--check] [--diff] [file...]")
+	fs.Parse(args)
+	names := fs.Args()
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	for _, name := range names {
+		if write && isStdin(name) {
+			fmt.Fprintln(os.Stderr, "jingle fmt: cannot use -w with stdin")
+			return exitUsage
+		}
+	}
+	status := exitOK
+	for _, name := range names {
+		if s := fmtFile(name, write, check, diff); status == exitOK {
+			status = s
+		}
+	}
+	return status
+}
+
+// fmtFile formats the file called name, see fmtCommand.
+func fmtFile(name string, write bool, check bool, diff bool) int {
+	filename, f, err := open(name)
+	if err != nil {
+		printError(err)
+		return exitFailure
+	}
+	src, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		printError(err)
+		return exitFailure
+	}
+	formatted, errs := format.Source(filename, string(src))
+	if errs != nil {
+		errors := make([]error, len(errs))
+		for i, err := range errs {
+			errors[i] = err
+		}
+		printErrors(errors)
+		s := sc.New(filename, string(src))
+		s.ScanAll()
+		if s.Errors() != nil {
+			return exitScan
+		}
+		return exitParse
+	}
+	changed := formatted != string(src)
+	status := exitOK
+	if diff {
+		fmt.Print(unifiedDiff(filename+".orig", filename, string(src), formatted))
+	}
+	if check && changed {
+		fmt.Println(filename)
+		status = exitFailure
+	}
+	if write {
+		if changed {
+			if err := os.WriteFile(name, []byte(formatted), 0666); err != nil {
+				printError(err)
+				return exitFailure
 			}
 		}
-		fmt.Fprintf(out, "\n%s}", strings.Repeat("  ", level))
-	default:
-		fmt.Fprint(out, value)
+	} else if !diff && !check {
+		fmt.Print(formatted)
 	}
+	return status
 }
@@ -2,13 +2,25 @@ package parser
 
 import (
 	"fmt"
+	"jingle/ast"
 	"jingle/scanner"
 )
 
 type ParserError struct {
-	Token    scanner.Token
-	Filename string
-	Msg      string
+	Token      scanner.Token
+	Node       ast.Node // the node that the error is about, if any
+	Filename   string
+	Msg        string
+	Incomplete bool // the input ended too soon, and more of it might fix the error
+}
+
+// Span returns the part of the input that the error is about: the
+// node if there is one, and the token otherwise.
+func (pe ParserError) Span() scanner.Span {
+	if pe.Node != nil {
+		return pe.Node.Span()
+	}
+	return pe.Token.Span()
 }
 
 func (pe ParserError) Error() string { return pe.String() }
@@ -21,18 +33,76 @@ func (pe ParserError) String() string {
 	)
 }
 
-func (p *Parser) error(s string, args ...interface{}) {
-	panic(ParserError{
+// addError records pe, unless the last error was reported at the
+// same token. This happens e.g. when an unexpected EOF is reported
+// by every enclosing block.
+func (p *Parser) addError(pe ParserError) {
+	if n := len(p.errors); n > 0 && p.errors[n-1].Token == pe.Token {
+		return
+	}
+	p.errors = append(p.errors, pe)
+}
+
+// sourceError converts an error from the TokenSource into a
+// ParserError.
+func (p *Parser) sourceError(err error) ParserError {
+	if se, ok := err.(scanner.Error); ok {
+		return ParserError{
+			Filename: se.Filename,
+			Token: scanner.Token{
+				Type:      scanner.TokenIllegal,
+				Value:     se.Value,
+				LineNo:    se.LineNo,
+				Column:    se.Column,
+				Offset:    se.Offset,
+				EndLineNo: se.EndLineNo,
+				EndColumn: se.EndColumn,
+				EndOffset: se.EndOffset,
+			},
+			Msg:        se.Message,
+			Incomplete: se.Incomplete,
+		}
+	}
+	return ParserError{
 		Filename: p.filename,
-		Token:    p.previous(),
-		Msg:      fmt.Sprintf(s, args...),
-	})
+		Token:    p.prev,
+		Msg:      err.Error(),
+	}
+}
+
+// Incomplete reports whether errs are all caused by the input ending
+// too soon, as in an unterminated block or string, so that a program
+// could be completed by adding more input.
+func Incomplete(errs []ParserError) bool {
+	for _, err := range errs {
+		if !err.Incomplete {
+			return false
+		}
+	}
+	return len(errs) > 0
+}
+
+// newError returns an error reported at token. Errors at the EOF
+// token are incomplete.
+func (p *Parser) newError(token scanner.Token, s string, args ...interface{}) ParserError {
+	return ParserError{
+		Filename:   p.filename,
+		Token:      token,
+		Msg:        fmt.Sprintf(s, args...),
+		Incomplete: token.Type == scanner.TokenEOF,
+	}
+}
+
+func (p *Parser) error(s string, args ...interface{}) {
+	panic(p.newError(p.previous(), s, args...))
 }
 
 func (p *Parser) errorToken(token scanner.Token, s string, args ...interface{}) {
-	panic(ParserError{
-		Filename: p.filename,
-		Token:    token,
-		Msg:      fmt.Sprintf(s, args...),
-	})
+	panic(p.newError(token, s, args...))
+}
+
+func (p *Parser) errorNode(node ast.Node, s string, args ...interface{}) {
+	pe := p.newError(node.GetToken(), s, args...)
+	pe.Node = node
+	panic(pe)
 }
@@ -1,9 +1,12 @@
 package parser
 
 import (
+	"errors"
 	"jingle/ast"
 	"jingle/scanner"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 type (
@@ -17,61 +20,94 @@ const (
 	PREC_LOWEST     = iota
 	PREC_ASSIGNMENT // assignment
 	PREC_IF         // x if foo else bar
-	PREC_INDEX      // a[b]
-	PREC_EQ         // ==, >=, !=
-	PREC_AND_OR     // and, or
+	PREC_OR         // or
+	PREC_AND        // and
+	PREC_EQ         // ==, !=, <, >, <=, >=
+	PREC_RANGE      // a..b, a...b
+	PREC_BIT_OR     // |
+	PREC_BIT_XOR    // ^
+	PREC_BIT_AND    // &
+	PREC_SHIFT      // <<, >>
 	PREC_ADD        // addition, subtraction
-	PREC_PRODUCT    // multiplication
-	PREC_PREFIX     // ! or -
+	PREC_PRODUCT    // multiplication, division, modulo
+	PREC_PREFIX     // !, - or ~
+	PREC_POWER      // ** (right associative)
+	PREC_INDEX      // a[b]
 	PREC_CALL       // func/method calls, attr get
 )
 
 func (p *Parser) initExpressions() {
 	p.prefixHandlers = map[scanner.TokenType]prefixParseFn{
-		scanner.TokenMinus:    p.parsePrefixExpression,
-		scanner.TokenBang:     p.parsePrefixExpression,
-		scanner.TokenIdent:    p.parseIdentifierLiteral,
-		scanner.TokenNil:      p.parseNullLiteral,
-		scanner.TokenNumber:   p.parseNumberLiteral,
-		scanner.TokenString:   p.parseStringLiteral,
-		scanner.TokenLParen:   p.parseParens,
-		scanner.TokenBoolean:  p.parseBooleanLiteral,
-		scanner.TokenFn:       p.parseFunctionLiteral,
-		scanner.TokenLBracket: p.parseArrayLiteral,
+		scanner.TokenMinus:       p.parsePrefixExpression,
+		scanner.TokenBang:        p.parsePrefixExpression,
+		scanner.TokenTilde:       p.parsePrefixExpression,
+		scanner.TokenIdent:       p.parseIdentifierLiteral,
+		scanner.TokenNil:         p.parseNullLiteral,
+		scanner.TokenInteger:     p.parseIntegerLiteral,
+		scanner.TokenFloat:       p.parseFloatLiteral,
+		scanner.TokenString:      p.parseStringLiteral,
+		scanner.TokenStringStart: p.parseInterpolatedString,
+		scanner.TokenLParen:      p.parseParens,
+		scanner.TokenBoolean:     p.parseBooleanLiteral,
+		scanner.TokenFn:          p.parseFunctionLiteral,
+		scanner.TokenLBracket:    p.parseArrayLiteral,
+		scanner.TokenLBrace:      p.parseMapLiteral,
 	}
 	p.infixHandlers = map[scanner.TokenType]infixParseFn{
-		scanner.TokenPlus:     p.parseInfixExpression,
-		scanner.TokenMinus:    p.parseInfixExpression,
-		scanner.TokenMul:      p.parseInfixExpression,
-		scanner.TokenDiv:      p.parseInfixExpression,
-		scanner.TokenGeq:      p.parseInfixExpression,
-		scanner.TokenLeq:      p.parseInfixExpression,
-		scanner.TokenEq:       p.parseInfixExpression,
-		scanner.TokenNeq:      p.parseInfixExpression,
-		scanner.TokenSet:      p.parseAssignmentExpression,
-		scanner.TokenOr:       p.parseOrExpression,
-		scanner.TokenAnd:      p.parseAndExpression,
-		scanner.TokenDot:      p.parseAttrExpression,
-		scanner.TokenLBracket: p.parseIndexExpression,
-		scanner.TokenLParen:   p.parseCallExpression,
-		scanner.TokenIf:       p.parseIfElseExpression,
+		scanner.TokenPlus:      p.parseInfixExpression,
+		scanner.TokenMinus:     p.parseInfixExpression,
+		scanner.TokenMul:       p.parseInfixExpression,
+		scanner.TokenDiv:       p.parseInfixExpression,
+		scanner.TokenMod:       p.parseInfixExpression,
+		scanner.TokenPow:       p.parseInfixExpression,
+		scanner.TokenAmp:       p.parseInfixExpression,
+		scanner.TokenPipe:      p.parseInfixExpression,
+		scanner.TokenCaret:     p.parseInfixExpression,
+		scanner.TokenShl:       p.parseInfixExpression,
+		scanner.TokenShr:       p.parseInfixExpression,
+		scanner.TokenLt:        p.parseInfixExpression,
+		scanner.TokenGt:        p.parseInfixExpression,
+		scanner.TokenGeq:       p.parseInfixExpression,
+		scanner.TokenLeq:       p.parseInfixExpression,
+		scanner.TokenEq:        p.parseInfixExpression,
+		scanner.TokenNeq:       p.parseInfixExpression,
+		scanner.TokenDotDot:    p.parseInfixExpression,
+		scanner.TokenDotDotDot: p.parseInfixExpression,
+		scanner.TokenSet:       p.parseAssignmentExpression,
+		scanner.TokenOr:        p.parseOrExpression,
+		scanner.TokenAnd:       p.parseAndExpression,
+		scanner.TokenDot:       p.parseAttrExpression,
+		scanner.TokenLBracket:  p.parseIndexExpression,
+		scanner.TokenLParen:    p.parseCallExpression,
+		scanner.TokenIf:        p.parseIfElseExpression,
 	}
 	p.precedence = map[scanner.TokenType]int{
-		scanner.TokenPlus:     PREC_ADD,
-		scanner.TokenMinus:    PREC_ADD,
-		scanner.TokenMul:      PREC_PRODUCT,
-		scanner.TokenDiv:      PREC_PRODUCT,
-		scanner.TokenSet:      PREC_ASSIGNMENT,
-		scanner.TokenOr:       PREC_AND_OR,
-		scanner.TokenAnd:      PREC_AND_OR,
-		scanner.TokenEq:       PREC_EQ,
-		scanner.TokenNeq:      PREC_EQ,
-		scanner.TokenLeq:      PREC_EQ,
-		scanner.TokenGeq:      PREC_EQ,
-		scanner.TokenDot:      PREC_CALL,
-		scanner.TokenLBracket: PREC_INDEX,
-		scanner.TokenLParen:   PREC_CALL,
-		scanner.TokenIf:       PREC_IF,
+		scanner.TokenPlus:      PREC_ADD,
+		scanner.TokenMinus:     PREC_ADD,
+		scanner.TokenMul:       PREC_PRODUCT,
+		scanner.TokenDiv:       PREC_PRODUCT,
+		scanner.TokenMod:       PREC_PRODUCT,
+		scanner.TokenPow:       PREC_POWER,
+		scanner.TokenAmp:       PREC_BIT_AND,
+		scanner.TokenPipe:      PREC_BIT_OR,
+		scanner.TokenCaret:     PREC_BIT_XOR,
+		scanner.TokenShl:       PREC_SHIFT,
+		scanner.TokenShr:       PREC_SHIFT,
+		scanner.TokenSet:       PREC_ASSIGNMENT,
+		scanner.TokenOr:        PREC_OR,
+		scanner.TokenAnd:       PREC_AND,
+		scanner.TokenEq:        PREC_EQ,
+		scanner.TokenNeq:       PREC_EQ,
+		scanner.TokenLt:        PREC_EQ,
+		scanner.TokenGt:        PREC_EQ,
+		scanner.TokenLeq:       PREC_EQ,
+		scanner.TokenGeq:       PREC_EQ,
+		scanner.TokenDotDot:    PREC_RANGE,
+		scanner.TokenDotDotDot: PREC_RANGE,
+		scanner.TokenDot:       PREC_CALL,
+		scanner.TokenLBracket:  PREC_INDEX,
+		scanner.TokenLParen:    PREC_CALL,
+		scanner.TokenIf:        PREC_IF,
 	}
 }
 
@@ -83,11 +119,12 @@ func (p *Parser) parseExpression() ast.Expression { return p.parsePrecedence(PRE
 func (p *Parser) parsePrecedence(precedence int) ast.Expression {
 	// must have a matching prefix parser, otherwise we cannot
 	// parse anything!
-	tok := p.consume()
+	tok := p.peek()
 	prefixParser, ok := p.prefixHandlers[tok.Type]
 	if !ok {
-		p.error("expected expression, got %s", tok.Type)
+		p.errorToken(tok, "expected expression, got %s", tok.Type)
 	}
+	p.consume()
 	left := prefixParser()
 	for precedence < p.getPrecedence(p.peek().Type) {
 		// note -- we will never come here if p.getPrecedence()
@@ -112,7 +149,7 @@ func (p *Parser) getPrecedence(tok scanner.TokenType) int {
 // ===========
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
-	// prefix → ("!" | "-") expr
+	// prefix → ("!" | "-" | "~") expr
 	opToken := p.previous()
 	right := p.parsePrecedence(PREC_PREFIX)
 	return &ast.PrefixExpression{
@@ -123,9 +160,15 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
-	// infix → expr ("*" | "/" | "+" | "-" | ">" | "<" | "==" | "!=" | "<=" | ">=") expr
+	// infix → expr ("*" | "/" | "%" | "**" | "+" | "-" | ">" | "<" | "==" | "!="
+	//               | "<=" | ">=" | ".." | "..." | "&" | "|" | "^" | "<<" | ">>") expr
 	opToken := p.previous()
-	right := p.parsePrecedence(p.precedence[opToken.Type])
+	precedence := p.precedence[opToken.Type]
+	if opToken.Type == scanner.TokenPow {
+		// a ** b ** c == a ** (b ** c)
+		precedence--
+	}
+	right := p.parsePrecedence(precedence)
 	return &ast.InfixExpression{
 		Token: opToken,
 		Op:    opToken.Value,
@@ -137,8 +180,7 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
 	// assignment → expr "=" expr
 	if reason, ok := ast.Assignable(left, false); !ok {
-		p.errorToken(reason.GetToken(),
-			"cannot assign to %s", left.Type())
+		p.errorNode(reason, "cannot assign to %s", left.Type())
 	}
 	return &ast.AssignmentExpression{
 		Token: p.previous(), // the '=' token
@@ -149,9 +191,12 @@ func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
 
 func (p *Parser) parseParens() ast.Expression {
 	// parens → "(" expr ")"
-	expr := p.parseExpression()
+	node := &ast.ParenExpression{Token: p.previous()}
+	defer p.nest()()
+	node.Expr = p.parseExpression()
 	p.expect(scanner.TokenRParen)
-	return expr
+	node.RParen = p.previous()
+	return node
 }
 
 func (p *Parser) parseOrExpression(left ast.Expression) ast.Expression {
@@ -193,15 +238,18 @@ func (p *Parser) parseAttrExpression(left ast.Expression) ast.Expression {
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	// index → expr "[" expr ("]" | "," args("]"))
 	tok := p.previous()
+	defer p.nest()()
 	args := []ast.Expression{p.parseExpression()}
 	if !p.match(scanner.TokenRBracket) {
 		// more to come?
+		p.expect(scanner.TokenComma)
 		args = append(args, p.parseArgs(scanner.TokenRBracket)...)
 	}
 	return &ast.IndexExpression{
-		Token:  tok,
-		Target: left,
-		Args:   args,
+		Token:    tok,
+		Target:   left,
+		Args:     args,
+		RBracket: p.previous(),
 	}
 }
 
@@ -222,11 +270,11 @@ func (p *Parser) parseArgs(terminal scanner.TokenType) []ast.Expression {
 
 func (p *Parser) parseCallExpression(left ast.Expression) ast.Expression {
 	// call → expr "(" args(")")
-	return &ast.CallExpression{
-		Token:  p.previous(),
-		Target: left,
-		Args:   p.parseArgs(scanner.TokenRParen),
-	}
+	node := &ast.CallExpression{Token: p.previous(), Target: left}
+	defer p.nest()()
+	node.Args = p.parseArgs(scanner.TokenRParen)
+	node.RParen = p.previous()
+	return node
 }
 
 func (p *Parser) parseIfElseExpression(left ast.Expression) ast.Expression {
@@ -259,13 +307,39 @@ func (p *Parser) parseNullLiteral() ast.Expression {
 	return &ast.NilLiteral{Token: p.previous()}
 }
 
-func (p *Parser) parseNumberLiteral() ast.Expression {
+func (p *Parser) parseIntegerLiteral() ast.Expression {
 	tok := p.previous()
-	val, err := strconv.ParseFloat(tok.Value, 64)
-	if err != nil {
-		p.error("invalid number: %e", err)
+	digits := strings.Replace(tok.Value, "_", "", -1)
+	base := 10
+	if len(digits) > 2 && digits[0] == '0' {
+		switch digits[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+	}
+	if base != 10 {
+		digits = digits[2:]
+	}
+	val, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		p.error("invalid integer %s", tok.Value)
 	}
-	return &ast.NumberLiteral{Token: tok, Value: val}
+	return &ast.IntegerLiteral{Token: tok, Value: val}
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	tok := p.previous()
+	val, err := strconv.ParseFloat(strings.Replace(tok.Value, "_", "", -1), 64)
+	if errors.Is(err, strconv.ErrRange) {
+		p.error("number %s is out of range", tok.Value)
+	} else if err != nil {
+		p.error("invalid number %s", tok.Value)
+	}
+	return &ast.FloatLiteral{Token: tok, Value: val}
 }
 
 func (p *Parser) parseStringLiteral() ast.Expression {
@@ -275,8 +349,30 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	}
 }
 
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	// interp → stringStart expr (stringMiddle expr)* stringEnd
+	node := &ast.InterpolatedString{Token: p.previous()}
+	node.Strings = []string{p.previous().Value}
+	node.Exprs = []ast.Expression{}
+	for {
+		node.Exprs = append(node.Exprs, p.parseExpression())
+		tok := p.consume()
+		switch tok.Type {
+		case scanner.TokenStringMiddle:
+			node.Strings = append(node.Strings, tok.Value)
+		case scanner.TokenStringEnd:
+			node.Strings = append(node.Strings, tok.Value)
+			node.End = tok
+			return node
+		default:
+			p.errorToken(tok, "expected } after interpolated expression, got %s", tok.Type)
+		}
+	}
+}
+
 func (p *Parser) parseParams() []*ast.IdentifierLiteral {
 	// params → nothing | "ident" ("," | "," params)?
+	defer p.nest()()
 	params := []*ast.IdentifierLiteral{}
 	for !p.match(scanner.TokenRParen) {
 		p.expect(scanner.TokenIdent)
@@ -294,6 +390,7 @@ func (p *Parser) parseParams() []*ast.IdentifierLiteral {
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	// fn → "fn" "(" params ")" stmt* "end"
 	fn := &ast.FunctionLiteral{Token: p.previous()}
+	defer p.hideLoops()()
 	p.expect(scanner.TokenLParen)
 	fn.Params = p.parseParams()
 	fn.Body = p.parseBlock(false, true, scanner.TokenEnd)
@@ -302,8 +399,43 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	// list → "[" args "]"
-	return &ast.ArrayLiteral{
-		Token: p.previous(),
-		Elems: p.parseArgs(scanner.TokenRBracket),
+	node := &ast.ArrayLiteral{Token: p.previous()}
+	defer p.nest()()
+	node.Elems = p.parseArgs(scanner.TokenRBracket)
+	node.RBracket = p.previous()
+	return node
+}
+
+func (p *Parser) parseMapLiteral() ast.Expression {
+	// map → "{" entries "}"
+	// entries → nothing | entry ("," | "," entries)?
+	// entry → (ident | string | "[" expr "]") ":" expr
+	node := &ast.MapLiteral{Token: p.previous()}
+	defer p.nest()()
+	node.Entries = []*ast.MapEntry{}
+	for !p.match(scanner.TokenRBrace) {
+		entry := &ast.MapEntry{}
+		switch {
+		case p.match(scanner.TokenIdent):
+			entry.Key = p.parseIdentifierLiteral()
+		case p.match(scanner.TokenString):
+			entry.Key = p.parseStringLiteral()
+		case p.match(scanner.TokenLBracket):
+			entry.Key = p.parseExpression()
+			entry.Computed = true
+			p.expect(scanner.TokenRBracket)
+		default:
+			p.errorToken(p.peek(), "expected map key, got %s", p.peek().Type)
+		}
+		p.expect(scanner.TokenColon)
+		entry.Value = p.parseExpression()
+		node.Entries = append(node.Entries, entry)
+		if !p.match(scanner.TokenComma) {
+			// dont have a comma -- must be an RBRACE
+			p.expect(scanner.TokenRBrace)
+			break
+		}
 	}
+	node.RBrace = p.previous()
+	return node
 }
@@ -4,69 +4,182 @@ package parser
 import (
 	"jingle/ast"
 	"jingle/scanner"
+	"strings"
 )
 
-// Parser parses the given slice of tokens, and produces either a
-// usable AST or an error.
+// Parser parses the tokens from a TokenSource, and produces an AST
+// together with any errors encountered along the way.
 type Parser struct {
 	filename string
-	tokens   []scanner.Token // list of tokens from the scanner
-	consumed int             // number of tokens consumed.
-	errors   []error         // parser errors encountered.
+	src      TokenSource
+	ahead    []scanner.Token // tokens read from src, but not yet consumed
+	prev     scanner.Token   // the last token consumed
+	eof      *scanner.Token  // the EOF token, once src has returned it
+	nesting  int             // open brackets, inside which newlines are ignored
+	errors   []ParserError   // parser errors encountered.
+	inFunc   bool            // are we inside a function body?
+	loops    []string        // labels of the enclosing loops, innermost last
 	// precedences
 	prefixHandlers map[scanner.TokenType]prefixParseFn
 	infixHandlers  map[scanner.TokenType]infixParseFn
 	precedence     map[scanner.TokenType]int
 }
 
+// TokenSource is a source of tokens for the parser, such as a
+// scanner created by scanner.NewReader. Next returns either the next
+// token or an error; it can be called again after an error.
+type TokenSource interface {
+	Next() (scanner.Token, error)
+}
+
+// sliceSource is a TokenSource for a slice of scanned tokens.
+type sliceSource struct{ tokens []scanner.Token }
+
+func (s *sliceSource) Next() (scanner.Token, error) {
+	if len(s.tokens) == 0 {
+		return scanner.Token{Type: scanner.TokenEOF}, nil
+	}
+	tok := s.tokens[0]
+	if len(s.tokens) > 1 || tok.Type == scanner.TokenEOF {
+		// keep the EOF token, to be returned again.
+		s.tokens = s.tokens[1:]
+	}
+	return tok, nil
+}
+
+// New returns a parser for the tokens of a scanner that has
+// scanned all of its input.
 func New(filename string, tokens []scanner.Token) *Parser {
+	return NewSource(filename, &sliceSource{tokens})
+}
+
+// NewSource returns a parser that reads its tokens from src as it
+// needs them. Errors returned by src are reported as ParserErrors.
+func NewSource(filename string, src TokenSource) *Parser {
 	p := &Parser{
 		filename: filename,
-		tokens:   tokens,
-		consumed: 0,
+		src:      src,
 	}
 	p.initExpressions()
 	return p
 }
 
 func (p *Parser) MustParse() *ast.Program {
-	prog, err := p.Parse()
-	if err != nil {
-		panic(err)
+	prog, errs := p.Parse()
+	if errs != nil {
+		panic(errs[0])
 	}
 	return prog
 }
 
-// Program is the main entry point into the parser.
-func (p *Parser) Parse() (program *ast.Program, err error) {
+// Parse is the main entry point into the parser. It always returns
+// a program; statements that could not be parsed are replaced by
+// *ast.ErrorStatement nodes, and the errors are returned in the
+// order they were found. errs is nil if there were no errors.
+func (p *Parser) Parse() (program *ast.Program, errs []ParserError) {
 	// Internally, we use panic(ParserError) to signal that
-	// there has been a parsing error.
+	// there has been a parsing error. Most of them are recovered
+	// from in parseBlock; this is only a last line of defence.
 	defer func() {
 		if r := recover(); r != nil {
-			if pe, ok := r.(ParserError); ok {
-				err = pe
-				return
+			pe, ok := r.(ParserError)
+			if !ok {
+				panic(r)
+			}
+			p.addError(pe)
+			if program == nil {
+				program = &ast.Program{
+					Filename:   p.filename,
+					Statements: []ast.Statement{},
+				}
 			}
-			panic(r)
 		}
+		errs = p.Errors()
 	}()
 	program = p.parseProgram()
 	return
 }
 
+// Errors returns the list of errors found while parsing,
+// or nil if there were none.
+func (p *Parser) Errors() []ParserError {
+	if len(p.errors) == 0 {
+		return nil
+	}
+	return p.errors
+}
+
 // ===============
 // Utility methods
 // ===============
 
-// peek returns the current token we have yet to consume
-func (p *Parser) peek() scanner.Token { return p.tokens[p.consumed] }
-func (p *Parser) isAtEnd() bool       { return p.peek().Type == scanner.TokenEOF }
+// lookahead makes sure that there are n tokens ahead of us, reading
+// them from the source if needed. Once the source has returned EOF,
+// the EOF token is repeated. Inside brackets, separators are skipped.
+func (p *Parser) lookahead(n int) {
+	for len(p.ahead) < n || p.nesting > 0 && p.dropSeparators(n) {
+		if p.eof != nil {
+			p.ahead = append(p.ahead, *p.eof)
+			continue
+		}
+		tok, err := p.src.Next()
+		if err != nil {
+			p.addError(p.sourceError(err))
+			continue
+		}
+		if tok.Type == scanner.TokenEOF {
+			p.eof = &tok
+		}
+		p.ahead = append(p.ahead, tok)
+	}
+}
+
+// dropSeparators removes the separators from the first n tokens
+// ahead, and reports whether there were any.
+func (p *Parser) dropSeparators(n int) bool {
+	if n > len(p.ahead) {
+		n = len(p.ahead)
+	}
+	kept := p.ahead[:0]
+	for i, tok := range p.ahead {
+		if i >= n || tok.Type != scanner.TokenSeparator {
+			kept = append(kept, tok)
+		}
+	}
+	dropped := len(kept) < len(p.ahead)
+	p.ahead = kept
+	return dropped
+}
+
+// nest is called after an opening bracket, so that newlines are
+// ignored until the matching closing bracket.
+func (p *Parser) nest() func() {
+	p.nesting++
+	return func() { p.nesting-- }
+}
+
+// peek returns the current token we have yet to consume. Once the
+// EOF token has been consumed, peek keeps on returning it.
+func (p *Parser) peek() scanner.Token {
+	p.lookahead(1)
+	return p.ahead[0]
+}
+
+// peekNext returns the token after the current one.
+func (p *Parser) peekNext() scanner.Token {
+	p.lookahead(2)
+	return p.ahead[1]
+}
+
+func (p *Parser) isAtEnd() bool { return p.peek().Type == scanner.TokenEOF }
 
 // previous returns the previously consumed token
-func (p *Parser) previous() scanner.Token { return p.tokens[p.consumed-1] }
+func (p *Parser) previous() scanner.Token { return p.prev }
 func (p *Parser) consume() scanner.Token {
-	p.consumed++
-	return p.previous()
+	p.lookahead(1)
+	p.prev = p.ahead[0]
+	p.ahead = append(p.ahead[:0], p.ahead[1:]...)
+	return p.prev
 }
 
 // match looks ahead at the token stream, and consumes 1
@@ -85,8 +198,28 @@ func (p *Parser) match(types ...scanner.TokenType) bool {
 // expect is like match, but raises an error.
 func (p *Parser) expect(t scanner.TokenType) {
 	if !p.match(t) {
-		p.error("expected %s, got %s instead", t, p.peek().Type)
+		pe := p.newError(p.previous(), "expected %s, got %s instead", t, p.peek().Type)
+		pe.Incomplete = p.isAtEnd()
+		panic(pe)
+	}
+}
+
+func containsType(types []scanner.TokenType, t scanner.TokenType) bool {
+	for _, typ := range types {
+		if typ == t {
+			return true
+		}
+	}
+	return false
+}
+
+// joinTypes formats a list of token types for error messages.
+func joinTypes(types []scanner.TokenType) string {
+	names := make([]string, len(types))
+	for i, typ := range types {
+		names[i] = typ.String()
 	}
+	return strings.Join(names, " or ")
 }
 
 // ===============
@@ -94,22 +227,40 @@ func (p *Parser) expect(t scanner.TokenType) {
 // ===============
 
 func (p *Parser) parseProgram() *ast.Program {
-	prog := &ast.Program{}
+	prog := &ast.Program{Filename: p.filename}
 	block := p.parseBlock(false, false, scanner.TokenEOF)
 	prog.Statements = block.Statements
 	return prog
 }
 
 func (p *Parser) parseStatement() ast.Statement {
-	// stmt → let | for | if | exprstmt
+	// stmt → let | for | while | labeled | if | class | try | raise
+	//        | break | continue | exprstmt
 	// exprstmt → expr
 	switch p.peek().Type {
+	case scanner.TokenLet:
+		return p.parseLetStatement()
 	case scanner.TokenFor:
-		return p.parseForStatement()
+		return p.parseForStatement(nil)
+	case scanner.TokenWhile:
+		return p.parseWhileStatement(nil)
+	case scanner.TokenIdent:
+		if p.peekNext().Type == scanner.TokenColon {
+			return p.parseLabeledStatement()
+		}
+		return &ast.ExpressionStatement{Expr: p.parseExpression()}
 	case scanner.TokenIf:
 		return p.parseIfStatement()
 	case scanner.TokenClass:
 		return p.parseClassStatement()
+	case scanner.TokenTry:
+		return p.parseTryStatement()
+	case scanner.TokenRaise:
+		return p.parseRaiseStatement()
+	case scanner.TokenBreak:
+		return p.parseBreakStatement()
+	case scanner.TokenContinue:
+		return p.parseContinueStatement()
 	default:
 		return &ast.ExpressionStatement{Expr: p.parseExpression()}
 	}
@@ -122,45 +273,124 @@ func (p *Parser) parseBlock(
 ) *ast.Block {
 	// block → ("sep")? blockStmts <terminal>
 	// blockStmts → nothing | stmt ("sep" blockStmts)?
+	outerInFunc, outerNesting := p.inFunc, p.nesting
+	p.inFunc = isFunc
+	p.nesting = 0 // newlines separate statements, even inside brackets
+	defer func() { p.inFunc, p.nesting = outerInFunc, outerNesting }()
+
 	lastHasSeparator := true
 	block := &ast.Block{}
 	block.Statements = []ast.Statement{}
 	p.match(scanner.TokenSeparator) // initial whitespace -- ignore
 	for !p.match(terminal...) {
-		if !lastHasSeparator {
-			p.error("expected newline or semicolon after statement")
+		if p.isAtEnd() {
+			// not recoverable within this block: let the
+			// enclosing block deal with it.
+			p.errorToken(p.peek(), "expected %s, got %s instead",
+				joinTypes(terminal), scanner.TokenEOF)
 		}
 		var stmt ast.Statement
-		switch p.peek().Type {
-		case scanner.TokenDef:
-			if !isClass {
-				p.consume()
-				p.error("method declaration outside of class")
-			}
-			stmt = p.parseMethodDeclaration()
-		case scanner.TokenReturn:
-			if !isFunc {
-				p.consume()
-				p.error("return statement outside of function")
-			}
-			stmt = p.parseReturnStatement()
-		default:
-			stmt = p.parseStatement()
+		if !lastHasSeparator {
+			stmt = p.recoverStatement(terminal, func() ast.Statement {
+				p.errorToken(p.peek(), "expected newline or semicolon after statement")
+				return nil
+			})
+		} else {
+			stmt = p.recoverStatement(terminal, func() ast.Statement {
+				return p.parseBlockStatement(isClass, isFunc)
+			})
 		}
 		block.Statements = append(block.Statements, stmt)
-		lastHasSeparator = p.match(scanner.TokenSeparator)
+		// after an error, synchronize may stop at the start of a
+		// statement (class or def): parse it without a separator.
+		_, failed := stmt.(*ast.ErrorStatement)
+		lastHasSeparator = p.match(scanner.TokenSeparator) || failed
 	}
 	block.Terminal = p.previous()
 	return block
 }
 
+func (p *Parser) parseBlockStatement(isClass bool, isFunc bool) ast.Statement {
+	switch p.peek().Type {
+	case scanner.TokenDef:
+		if !isClass {
+			p.consume()
+			p.error("method declaration outside of class")
+		}
+		return p.parseMethodDeclaration()
+	case scanner.TokenReturn:
+		if !isFunc {
+			p.consume()
+			p.error("return statement outside of function")
+		}
+		return p.parseReturnStatement()
+	default:
+		return p.parseStatement()
+	}
+}
+
+// recoverStatement runs parse, and if it raises a ParserError,
+// records the error and skips ahead to the start of the next
+// statement (panic-mode recovery). In that case an
+// *ast.ErrorStatement is returned in place of the statement.
+func (p *Parser) recoverStatement(
+	terminal []scanner.TokenType,
+	parse func() ast.Statement,
+) (stmt ast.Statement) {
+	start := p.peek()
+	defer func() {
+		if r := recover(); r != nil {
+			pe, ok := r.(ParserError)
+			if !ok {
+				panic(r)
+			}
+			p.addError(pe)
+			p.synchronize(terminal)
+			stmt = &ast.ErrorStatement{Token: start, Msg: pe.Msg}
+		}
+	}()
+	return parse()
+}
+
+// synchronize discards tokens until we reach something that
+// looks like a statement boundary: a separator, one of the
+// 'end', 'def' or 'class' keywords, or something that terminates
+// the current block.
+func (p *Parser) synchronize(terminal []scanner.TokenType) {
+	for {
+		if containsType(terminal, p.peek().Type) {
+			return
+		}
+		switch p.peek().Type {
+		case scanner.TokenEnd:
+			// if this block cannot be closed by an 'end', then it
+			// most likely belongs to the statement we just gave up on.
+			if !containsType(terminal, scanner.TokenEnd) {
+				p.consume()
+			}
+			return
+		case scanner.TokenSeparator,
+			scanner.TokenDef,
+			scanner.TokenClass,
+			scanner.TokenEOF:
+			return
+		}
+		p.consume()
+	}
+}
+
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	// let → "let" expr
 	node := &ast.LetStatement{Token: p.consume()}
 	node.Binding = p.parseExpression()
 	if reason, ok := ast.Assignable(node.Binding, true); !ok {
-		p.errorToken(reason.GetToken(),
-			"cannot assign to %s", reason.Type())
+		p.errorNode(reason, "cannot assign to %s", reason.Type())
+	}
+	// let f = fn() ... end takes the doc comment of the let.
+	if assign, ok := node.Binding.(*ast.AssignmentExpression); ok {
+		if fn, ok := assign.Right.(*ast.FunctionLiteral); ok {
+			fn.Doc = docComment(node.Token)
+		}
 	}
 	return node
 }
@@ -170,31 +400,128 @@ func (p *Parser) parseIfStatement() *ast.IfStatement {
 	node := &ast.IfStatement{Token: p.consume()}
 	node.Cond = p.parseExpression()
 	p.expect(scanner.TokenThen)
-	thenBlock := p.parseBlock(false, false, scanner.TokenEnd, scanner.TokenElse)
+	thenBlock := p.parseBlock(false, p.inFunc, scanner.TokenEnd, scanner.TokenElse)
 	node.Then = thenBlock
 	if thenBlock.Terminal.Type == scanner.TokenElse {
-		elseBlock := p.parseBlock(false, false, scanner.TokenEnd)
+		elseBlock := p.parseBlock(false, p.inFunc, scanner.TokenEnd)
 		node.Else = elseBlock
 	}
 	return node
 }
 
-func (p *Parser) parseForStatement() *ast.ForStatement {
-	// for → "for" expr "in" expr "do" stmts... "end"
+func (p *Parser) parseLabeledStatement() ast.Statement {
+	// labeled → ident ":" (for | while)
+	p.consume()
+	label := p.parseIdentifierLiteral().(*ast.IdentifierLiteral)
+	p.consume() // the ':'
+	for _, name := range p.loops {
+		if name == label.Name() {
+			p.errorToken(label.Token, "label %s is already in use", name)
+		}
+	}
+	switch p.peek().Type {
+	case scanner.TokenFor:
+		return p.parseForStatement(label)
+	case scanner.TokenWhile:
+		return p.parseWhileStatement(label)
+	}
+	p.errorToken(p.peek(), "expected loop after label %s, got %s", label.Name(), p.peek().Type)
+	return nil
+}
+
+// parseLoopBody parses the body of a loop, within which break and
+// continue can refer to label.
+func (p *Parser) parseLoopBody(label *ast.IdentifierLiteral) *ast.Block {
+	name := ""
+	if label != nil {
+		name = label.Name()
+	}
+	p.loops = append(p.loops, name)
+	defer func() { p.loops = p.loops[:len(p.loops)-1] }()
+	return p.parseBlock(false, p.inFunc, scanner.TokenEnd)
+}
+
+// hideLoops hides the enclosing loops until the returned function is
+// called, so that break and continue cannot cross into the body of
+// a function or class.
+func (p *Parser) hideLoops() func() {
+	outer := p.loops
+	p.loops = nil
+	return func() { p.loops = outer }
+}
+
+func (p *Parser) parseForStatement(label *ast.IdentifierLiteral) *ast.ForStatement {
+	// for → "for" expr ("," expr)* "in" expr "do" stmts... "end"
 	// note: expr has to be assignable
-	node := &ast.ForStatement{Token: p.consume()}
+	node := &ast.ForStatement{Token: p.consume(), Label: label}
 	node.Binding = p.parseExpression()
+	if p.peek().Type == scanner.TokenComma {
+		// `for a, b in c` is short for `for [a, b] in c`, with
+		// empty brackets at either end.
+		elems := []ast.Expression{node.Binding}
+		for p.match(scanner.TokenComma) {
+			elems = append(elems, p.parseExpression())
+		}
+		start, end := elems[0].Span().Start, elems[len(elems)-1].Span().End
+		node.Binding = &ast.ArrayLiteral{
+			Token:    emptyToken(scanner.TokenLBracket, "[", start),
+			Elems:    elems,
+			RBracket: emptyToken(scanner.TokenRBracket, "]", end),
+		}
+	}
 	if reason, ok := ast.Assignable(node.Binding, true); !ok {
-		p.errorToken(reason.GetToken(),
-			"cannot assign to %s", reason.Type())
+		p.errorNode(reason, "cannot assign to %s", reason.Type())
 	}
 	p.expect(scanner.TokenIn)
 	node.Iterable = p.parseExpression()
 	p.expect(scanner.TokenDo)
-	node.Body = p.parseBlock(false, false, scanner.TokenEnd)
+	node.Body = p.parseLoopBody(label)
+	return node
+}
+
+func (p *Parser) parseWhileStatement(label *ast.IdentifierLiteral) *ast.WhileStatement {
+	// while → "while" expr "do" stmts... "end"
+	node := &ast.WhileStatement{Token: p.consume(), Label: label}
+	node.Condition = p.parseExpression()
+	p.expect(scanner.TokenDo)
+	node.Body = p.parseLoopBody(label)
+	return node
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	// break → "break" ident?
+	node := &ast.BreakStatement{Token: p.consume()}
+	node.Label = p.parseLoopLabel()
 	return node
 }
 
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	// continue → "continue" ident?
+	node := &ast.ContinueStatement{Token: p.consume()}
+	node.Label = p.parseLoopLabel()
+	return node
+}
+
+// parseLoopLabel parses the optional label after a break or continue
+// keyword, checking that we are inside a (suitably labeled) loop.
+func (p *Parser) parseLoopLabel() *ast.IdentifierLiteral {
+	keyword := p.previous()
+	if len(p.loops) == 0 {
+		p.error("%s statement outside of loop", keyword.Value)
+	}
+	if !p.match(scanner.TokenIdent) {
+		return nil
+	}
+	label := p.parseIdentifierLiteral().(*ast.IdentifierLiteral)
+	for _, name := range p.loops {
+		if name == label.Name() {
+			return label
+		}
+	}
+	p.error("undefined label %s", label.Name())
+	return nil
+}
+
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	// return → "return" expr
 	node := &ast.ReturnStatement{Token: p.consume()}
@@ -202,10 +529,48 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return node
 }
 
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	// try → "try" tryBlock catch* ("finally" block)? "end"
+	// catch → "catch" (ident (":" expr)?)? tryBlock
+	// tryBlock → block terminated by "catch", "finally" or "end"
+	node := &ast.TryStatement{Token: p.consume()}
+	node.Catches = []*ast.CatchClause{}
+	terminals := []scanner.TokenType{scanner.TokenCatch, scanner.TokenFinally, scanner.TokenEnd}
+	node.Body = p.parseBlock(false, p.inFunc, terminals...)
+	block := node.Body
+	for block.Terminal.Type == scanner.TokenCatch {
+		clause := &ast.CatchClause{Token: block.Terminal}
+		if p.match(scanner.TokenIdent) {
+			clause.Binding = p.parseIdentifierLiteral().(*ast.IdentifierLiteral)
+		}
+		if p.match(scanner.TokenColon) {
+			clause.Class = p.parseExpression()
+		}
+		clause.Body = p.parseBlock(false, p.inFunc, terminals...)
+		node.Catches = append(node.Catches, clause)
+		block = clause.Body
+	}
+	if block.Terminal.Type == scanner.TokenFinally {
+		node.Finally = p.parseBlock(false, p.inFunc, scanner.TokenEnd)
+	} else if len(node.Catches) == 0 {
+		p.errorToken(node.Token, "try statement without catch or finally")
+	}
+	return node
+}
+
+func (p *Parser) parseRaiseStatement() *ast.RaiseStatement {
+	// raise → "raise" expr
+	node := &ast.RaiseStatement{Token: p.consume()}
+	node.Expr = p.parseExpression()
+	return node
+}
+
 func (p *Parser) parseClassStatement() *ast.ClassStatement {
 	// class → "class" ident ( "<" expr )? classDecls "end"
 	// classDecls → nothing | "sep" | (methodDecl | stmt) ( "sep" | "sep" classDecls )?
 	class := &ast.ClassStatement{Token: p.consume()}
+	class.Doc = docComment(class.Token)
+	defer p.hideLoops()()
 	p.expect(scanner.TokenIdent)
 	class.Name = p.parseIdentifierLiteral().(*ast.IdentifierLiteral)
 	if p.match(scanner.TokenLt) {
@@ -220,6 +585,8 @@ func (p *Parser) parseMethodDeclaration() *ast.MethodDeclaration {
 	// methodDecl → "def" methodName "(" params ")" block "end"
 	// we're on top of a 'def' token.
 	meth := &ast.MethodDeclaration{Token: p.consume()}
+	meth.Doc = docComment(meth.Token)
+	defer p.hideLoops()()
 	meth.MethodName = p.parseMethodName()
 	p.expect(scanner.TokenLParen)
 	meth.Params = p.parseParams()
@@ -227,11 +594,43 @@ func (p *Parser) parseMethodDeclaration() *ast.MethodDeclaration {
 	return meth
 }
 
+// emptyToken returns a token that stands for the empty input at pos.
+func emptyToken(typ scanner.TokenType, value string, pos scanner.Pos) scanner.Token {
+	return scanner.Token{
+		Type:      typ,
+		Value:     value,
+		LineNo:    pos.LineNo,
+		Column:    pos.Column,
+		Offset:    pos.Offset,
+		EndLineNo: pos.LineNo,
+		EndColumn: pos.Column,
+		EndOffset: pos.Offset,
+	}
+}
+
+// docComment returns the text of the /// comments before tok, without
+// the slashes, or "" if there are none.
+func docComment(tok scanner.Token) string {
+	if tok.Trivia == nil {
+		return ""
+	}
+	var lines []string
+	for _, c := range tok.Trivia.Comments {
+		if c.Type == scanner.TokenDocComment {
+			line := strings.TrimPrefix(c.Value, "///")
+			lines = append(lines, strings.TrimPrefix(line, " "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (p *Parser) parseMethodName() ast.MethodName {
 	// methodName → (ident
 	//               | "[" "]" ("=")?
-	//               | "+" | "-" | "*" | "/"
-	//               | ">" | ">=" | "<" | "<=" | "==" | "!=")
+	//               | "+" | "-" | "*" | "/" | "%" | "**"
+	//               | "&" | "|" | "^" | "~" | "<<" | ">>"
+	//               | ">" | ">=" | "<" | "<=" | "==" | "!=" | "!"
+	//               | ".." | "...")
 	var name string
 	tok := p.consume()
 	switch tok.Type {
@@ -252,6 +651,22 @@ func (p *Parser) parseMethodName() ast.MethodName {
 		name = "*"
 	case scanner.TokenDiv:
 		name = "/"
+	case scanner.TokenMod:
+		name = "%"
+	case scanner.TokenPow:
+		name = "**"
+	case scanner.TokenAmp:
+		name = "&"
+	case scanner.TokenPipe:
+		name = "|"
+	case scanner.TokenCaret:
+		name = "^"
+	case scanner.TokenTilde:
+		name = "~"
+	case scanner.TokenShl:
+		name = "<<"
+	case scanner.TokenShr:
+		name = ">>"
 	case scanner.TokenGt:
 		name = ">"
 	case scanner.TokenGeq:
@@ -264,6 +679,12 @@ func (p *Parser) parseMethodName() ast.MethodName {
 		name = "=="
 	case scanner.TokenNeq:
 		name = "!="
+	case scanner.TokenBang:
+		name = "!"
+	case scanner.TokenDotDot:
+		name = ".."
+	case scanner.TokenDotDotDot:
+		name = "..."
 	default:
 		p.error("invalid method name")
 	}
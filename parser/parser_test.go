@@ -5,13 +5,410 @@ import (
 	"jingle/parser"
 	"jingle/scanner"
 	ut "jingle/test_utils"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 // ========================
 // Statements
 // ========================
 
+func TestParseLetStatement(t *testing.T) {
+	tests := []struct {
+		input   string
+		binding interface{}
+	}{
+		{"let x", ut.ASTIdent{Name: "x"}},
+		{"let x = 1", ut.ASTAssign{Left: ut.ASTIdent{Name: "x"}, Right: ut.ASTInteger{Value: 1}}},
+		{"let [a, b] = c", ut.ASTAssign{
+			Left:  ut.ASTArray{ut.ASTIdent{Name: "a"}, ut.ASTIdent{Name: "b"}},
+			Right: ut.ASTIdent{Name: "c"},
+		}},
+	}
+	for i, tt := range tests {
+		node, ok := checkParseOneline(t, tt.input)
+		if !ok {
+			t.Fatalf("test[%d] failed", i)
+		}
+		if !ut.TestLetStatement(t, node, tt.binding) {
+			t.Fatalf("test[%d] failed", i)
+		}
+	}
+	for i, input := range []string{"let a.b = 1", "let a[0] = 1", "let 1"} {
+		if checkParseError(t, input) == nil {
+			t.Fatalf("invalid[%d] expected an error", i)
+		}
+	}
+}
+
+func TestParseWhileStatement(t *testing.T) {
+	tests := []struct {
+		input   string
+		cond    interface{}
+		bodyLen int
+	}{
+		{"while x do end", ut.ASTIdent{Name: "x"}, 0},
+		{"while x < 10 do x = x + 1 end", ut.ASTInfix{
+			Left:  ut.ASTIdent{Name: "x"},
+			Op:    "<",
+			Right: ut.ASTInteger{Value: 10},
+		}, 1},
+		{"while true do\n  a\n  b\nend", ut.ASTBoolean{Value: true}, 2},
+	}
+	for i, tt := range tests {
+		node, ok := checkParseOneline(t, tt.input)
+		if !ok {
+			t.Fatalf("test[%d] failed", i)
+		}
+		if !ut.TestWhileStatement(t, node, tt.cond, tt.bodyLen) {
+			t.Fatalf("test[%d] failed", i)
+		}
+	}
+	for i, input := range []string{"while x end", "while do end", "while x do"} {
+		if checkParseError(t, input) == nil {
+			t.Fatalf("invalid[%d] expected an error", i)
+		}
+	}
+}
+
+func TestParseForStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		binding  interface{}
+		iterable interface{}
+		bodyLen  int
+	}{
+		{"for x in y do end", ut.ASTIdent{Name: "x"}, ut.ASTIdent{Name: "y"}, 0},
+		{"for x in [1] do\n  f(x)\nend", ut.ASTIdent{Name: "x"}, ut.ASTArray{ut.ASTInteger{Value: 1}}, 1},
+		{"for [a, b] in y do end", ut.ASTArray{ut.ASTIdent{Name: "a"}, ut.ASTIdent{Name: "b"}}, ut.ASTIdent{Name: "y"}, 0},
+		{"for k, v in y do end", ut.ASTArray{ut.ASTIdent{Name: "k"}, ut.ASTIdent{Name: "v"}}, ut.ASTIdent{Name: "y"}, 0},
+		{"for a, [b, c] in y do end", ut.ASTArray{
+			ut.ASTIdent{Name: "a"},
+			ut.ASTArray{ut.ASTIdent{Name: "b"}, ut.ASTIdent{Name: "c"}},
+		}, ut.ASTIdent{Name: "y"}, 0},
+	}
+	for i, tt := range tests {
+		node, ok := checkParseOneline(t, tt.input)
+		if !ok {
+			t.Fatalf("test[%d] failed", i)
+		}
+		if !ut.TestForStatement(t, node, tt.binding, tt.iterable, tt.bodyLen) {
+			t.Fatalf("test[%d] failed", i)
+		}
+	}
+	for i, input := range []string{"for a.b in c do end", "for 1 in c do end", "for x y do end", "for a, 1 in c do end", "for a, in c do end"} {
+		if checkParseError(t, input) == nil {
+			t.Fatalf("invalid[%d] expected an error", i)
+		}
+	}
+}
+
+func TestParseIfStatement(t *testing.T) {
+	tests := []struct {
+		input   string
+		cond    interface{}
+		thenLen int
+		elseLen int
+	}{
+		{"if x then end", ut.ASTIdent{Name: "x"}, 0, -1},
+		{"if x then a; b end", ut.ASTIdent{Name: "x"}, 2, -1},
+		{"if x then a else b end", ut.ASTIdent{Name: "x"}, 1, 1},
+		{"if x > 1 then\n  a\nelse\nend", ut.ASTInfix{
+			Left:  ut.ASTIdent{Name: "x"},
+			Op:    ">",
+			Right: ut.ASTInteger{Value: 1},
+		}, 1, 0},
+	}
+	for i, tt := range tests {
+		node, ok := checkParseOneline(t, tt.input)
+		if !ok {
+			t.Fatalf("test[%d] failed", i)
+		}
+		if !ut.TestIfStatement(t, node, tt.cond, tt.thenLen, tt.elseLen) {
+			t.Fatalf("test[%d] failed", i)
+		}
+	}
+}
+
+func TestParseClassStatement(t *testing.T) {
+	tests := []struct {
+		input   string
+		name    string
+		super   interface{}
+		methods []string
+	}{
+		{"class A end", "A", nil, []string{}},
+		{"class A < B end", "A", ut.ASTIdent{Name: "B"}, []string{}},
+		{`class A < B
+			x = 1
+			def init(a, b) end
+			def [](i) end
+			def []=(i, v) end
+			def +(x) end
+			def <(x) end
+			def !() end
+		end`, "A", ut.ASTIdent{Name: "B"}, []string{"init", "[]", "[]=", "+", "<", "!"}},
+	}
+	for i, tt := range tests {
+		node, ok := checkParseOneline(t, tt.input)
+		if !ok {
+			t.Fatalf("test[%d] failed", i)
+		}
+		if !ut.TestClassStatement(t, node, tt.name, tt.super, tt.methods) {
+			t.Fatalf("test[%d] failed", i)
+		}
+	}
+	for i, input := range []string{"class end", "class A def end end", "class A def f( end end"} {
+		if checkParseError(t, input) == nil {
+			t.Fatalf("invalid[%d] expected an error", i)
+		}
+	}
+}
+
+func TestParseReturnStatement(t *testing.T) {
+	node, ok := checkParseOneline(t, "fn() return x end")
+	if !ok {
+		t.FailNow()
+	}
+	fn := node.(*ast.FunctionLiteral)
+	if !ut.TestBlock(t, fn.Body, 1) {
+		t.FailNow()
+	}
+	if !ut.TestReturnStatement(t, fn.Body.Statements[0], ut.ASTIdent{Name: "x"}) {
+		t.FailNow()
+	}
+}
+
+func TestParseTryStatement(t *testing.T) {
+	tests := []struct {
+		input   string
+		catches []struct {
+			binding string
+			class   interface{}
+		}
+		finally bool
+	}{
+		{input: "try a catch\nb end", catches: []struct {
+			binding string
+			class   interface{}
+		}{{}}},
+		{input: "try a catch e: IOError b catch e c end", catches: []struct {
+			binding string
+			class   interface{}
+		}{{"e", ut.ASTIdent{Name: "IOError"}}, {"e", nil}}},
+		{input: "try a finally b end", finally: true},
+		{input: "try\n  a\ncatch e\n  b\nfinally\n  c\nend", catches: []struct {
+			binding string
+			class   interface{}
+		}{{"e", nil}}, finally: true},
+	}
+	for i, tt := range tests {
+		node, ok := checkParseOneline(t, tt.input)
+		if !ok {
+			t.Fatalf("test[%d] failed", i)
+		}
+		if !ut.TestNodeType(t, node, ast.TRY_STATEMENT) {
+			t.Fatalf("test[%d] failed", i)
+		}
+		try := node.(*ast.TryStatement)
+		if !ut.TestBlock(t, try.Body, 1) {
+			t.Fatalf("test[%d] failed", i)
+		}
+		if len(try.Catches) != len(tt.catches) {
+			t.Fatalf("test[%d] expected %d catches, got=%d", i, len(tt.catches), len(try.Catches))
+		}
+		for j, expected := range tt.catches {
+			clause := try.Catches[j]
+			if expected.binding == "" {
+				if clause.Binding != nil {
+					t.Fatalf("test[%d] catch[%d] expected no binding, got=%s", i, j, clause.Binding)
+				}
+			} else if !ut.TestNode(t, clause.Binding, ut.ASTIdent{Name: expected.binding}) {
+				t.Fatalf("test[%d] catch[%d] failed", i, j)
+			}
+			if expected.class == nil {
+				if clause.Class != nil {
+					t.Fatalf("test[%d] catch[%d] expected no class, got=%s", i, j, clause.Class)
+				}
+			} else if !ut.TestNode(t, clause.Class, expected.class) {
+				t.Fatalf("test[%d] catch[%d] failed", i, j)
+			}
+		}
+		if (try.Finally != nil) != tt.finally {
+			t.Fatalf("test[%d] expected finally=%t, got=%v", i, tt.finally, try.Finally)
+		}
+	}
+	for i, input := range []string{"try a end", "try a catch b", "try a finally b catch c end"} {
+		if checkParseError(t, input) == nil {
+			t.Fatalf("invalid[%d] expected an error", i)
+		}
+	}
+}
+
+func TestParseRaiseStatement(t *testing.T) {
+	node, ok := checkParseOneline(t, `raise Error.new("x")`)
+	if !ok {
+		t.FailNow()
+	}
+	if !ut.TestNodeType(t, node, ast.RAISE_STATEMENT) {
+		t.FailNow()
+	}
+	if checkParseError(t, "raise") == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestParseBreakContinue(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{"while x do break end", true},
+		{"for x in y do if x then continue end end", true},
+		{"while x do try break finally continue end end", true},
+		{"outer: while x do for y in z do break outer end end", true},
+		{"outer: for x in y do inner: while z do continue outer end end", true},
+		{"a: while x do end; b: while y do break a end", false},
+		{"break", false},
+		{"if x then continue end", false},
+		{"while x do f = fn() break end end", false},
+		{"while x do class A break end end", false},
+		{"while x do break y end", false},
+		{"a: while x do a: while y do end end", false},
+		{"a: 1", false},
+	}
+	for i, tt := range tests {
+		s := scanner.New("", tt.input)
+		s.ScanAll()
+		_, errs := parser.New("", s.Tokens()).Parse()
+		if (errs == nil) != tt.ok {
+			t.Fatalf("test[%d] expected ok=%t, got errors=%v", i, tt.ok, errs)
+		}
+	}
+
+	node, ok := checkParseOneline(t, "outer: while x do for y in z do break outer end; continue end")
+	if !ok {
+		t.FailNow()
+	}
+	loop := node.(*ast.WhileStatement)
+	if !ut.TestNode(t, loop.Label, ut.ASTIdent{Name: "outer"}) || !ut.TestBlock(t, loop.Body, 2) {
+		t.FailNow()
+	}
+	inner := loop.Body.Statements[0].(*ast.ForStatement)
+	if inner.Label != nil {
+		t.Fatalf("expected no label, got=%s", inner.Label)
+	}
+	brk := inner.Body.Statements[0]
+	if !ut.TestNodeType(t, brk, ast.BREAK_STATEMENT) ||
+		!ut.TestNode(t, brk.(*ast.BreakStatement).Label, ut.ASTIdent{Name: "outer"}) {
+		t.FailNow()
+	}
+	cont := loop.Body.Statements[1]
+	if !ut.TestNodeType(t, cont, ast.CONTINUE_STATEMENT) || cont.(*ast.ContinueStatement).Label != nil {
+		t.FailNow()
+	}
+	if got := inner.String(); got != "for y in z do break outer end" {
+		t.Fatalf("unexpected String(): %q", got)
+	}
+}
+
+func TestParseErrorRecovery(t *testing.T) {
+	input := `a = 1 +
+b = 2
+if x y
+  c = 3
+fn(x
+class A
+  def f(x) return x end
+  return 1
+end
+d = )
+e = 4`
+	s := scanner.New("", input)
+	s.ScanAll()
+	if s.Errors() != nil {
+		t.Fatalf("unexpected scanner errors: %v", s.Errors())
+	}
+	program, errs := parser.New("", s.Tokens()).Parse()
+	if program == nil {
+		t.Fatalf("expected a partial program, got nil")
+	}
+	expected := []struct {
+		lineNo int
+		msg    string
+	}{
+		{1, "expected expression, got TokenSeparator"},
+		{3, "expected TokenThen, got TokenIdent instead"},
+		{5, "expected TokenRParen, got TokenClass instead"},
+		{8, "return statement outside of function"},
+		{10, "expected expression, got TokenRParen"},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got=%d: %v", len(expected), len(errs), errs)
+	}
+	for i, tt := range expected {
+		if errs[i].Token.LineNo != tt.lineNo || errs[i].Msg != tt.msg {
+			t.Errorf("errs[%d] expected=%d:%q, got=%d:%q",
+				i, tt.lineNo, tt.msg, errs[i].Token.LineNo, errs[i].Msg)
+		}
+	}
+	types := []ast.NodeType{
+		ast.ERROR_STATEMENT,      // a = 1 +
+		ast.EXPRESSION_STATEMENT, // b = 2
+		ast.ERROR_STATEMENT,      // if x y
+		ast.EXPRESSION_STATEMENT, // c = 3
+		ast.ERROR_STATEMENT,      // fn(x
+		ast.CLASS_STATEMENT,
+		ast.ERROR_STATEMENT,      // d = )
+		ast.EXPRESSION_STATEMENT, // e = 4
+	}
+	if len(program.Statements) != len(types) {
+		t.Fatalf("expected %d statements, got=%d", len(types), len(program.Statements))
+	}
+	for i, typ := range types {
+		if !ut.TestNodeType(t, program.Statements[i], typ) {
+			t.Fatalf("statement[%d] failed", i)
+		}
+	}
+	class := program.Statements[5].(*ast.ClassStatement)
+	if !ut.TestNodeType(t, class.Body.Statements[1], ast.ERROR_STATEMENT) {
+		t.Fatalf("class body[1] failed")
+	}
+}
+
+func TestParseReturnInNestedBlock(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{"fn() if a then return 1 end end", true},
+		{"fn() for x in y do if x then return x else return 1 end end end", true},
+		{"if a then return 1 end", false},
+		{"fn() class A return 1 end end", false},
+	}
+	for i, tt := range tests {
+		s := scanner.New("", tt.input)
+		s.ScanAll()
+		_, errs := parser.New("", s.Tokens()).Parse()
+		if (errs == nil) != tt.ok {
+			t.Fatalf("test[%d] expected ok=%t, got errors=%v", i, tt.ok, errs)
+		}
+	}
+}
+
+func TestParseErrorUnexpectedEOF(t *testing.T) {
+	s := scanner.New("", "if a then\n  for x in b do\n    fn()")
+	s.ScanAll()
+	_, errs := parser.New("", s.Tokens()).Parse()
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got=%d: %v", len(errs), errs)
+	}
+	if errs[0].Token.Type != scanner.TokenEOF {
+		t.Fatalf("expected error at EOF, got=%s", errs[0].Token)
+	}
+}
+
 // ========================
 // Literals
 // ========================
@@ -21,14 +418,32 @@ func TestParseLiterals(t *testing.T) {
 		input    string
 		expected interface{}
 	}{
-		{"foobar'", ut.ASTIdent{"foobar'"}},
+		{"foobar'", ut.ASTIdent{Name: "foobar'"}},
 		{"nil", ut.ASTNil{}},
-		{"100", ut.ASTNumber{100}},
-		{"5.5", ut.ASTNumber{5.5}},
-		{`"hello"`, ut.ASTString{"hello"}},
-		{`true`, ut.ASTBoolean{true}},
-		{`false`, ut.ASTBoolean{false}},
-		{`[1,true,nil]`, ut.ASTArray{ut.ASTNumber{1}, ut.ASTBoolean{true}, ut.ASTNil{}}},
+		{"100", ut.ASTInteger{Value: 100}},
+		{"5.5", ut.ASTFloat{Value: 5.5}},
+		{"1_000_000", ut.ASTInteger{Value: 1000000}},
+		{"0xFF", ut.ASTInteger{Value: 255}},
+		{"0x7fff_ffff_ffff_ffff", ut.ASTInteger{Value: 1<<63 - 1}},
+		{"0o17", ut.ASTInteger{Value: 15}},
+		{"0b1010", ut.ASTInteger{Value: 10}},
+		{"1.5e-3", ut.ASTFloat{Value: 0.0015}},
+		{"2E2", ut.ASTFloat{Value: 200}},
+		{`"hello"`, ut.ASTString{Value: "hello"}},
+		{`true`, ut.ASTBoolean{Value: true}},
+		{`false`, ut.ASTBoolean{Value: false}},
+		{`[1,true,nil]`, ut.ASTArray{ut.ASTInteger{Value: 1}, ut.ASTBoolean{Value: true}, ut.ASTNil{}}},
+		{`{}`, ut.ASTMap{}},
+		{`{a: 1, "b c": x, [1 + 2]: nil,}`, ut.ASTMap{
+			{Key: ut.ASTIdent{Name: "a"}, Value: ut.ASTInteger{Value: 1}},
+			{Key: ut.ASTString{Value: "b c"}, Value: ut.ASTIdent{Name: "x"}},
+			{Key: ut.ASTInfix{Left: ut.ASTInteger{Value: 1}, Op: "+", Right: ut.ASTInteger{Value: 2}}, Value: ut.ASTNil{}, Computed: true},
+		}},
+		{`{a: {b: [c]}}`, ut.ASTMap{
+			{Key: ut.ASTIdent{Name: "a"}, Value: ut.ASTMap{
+				{Key: ut.ASTIdent{Name: "b"}, Value: ut.ASTArray{ut.ASTIdent{Name: "c"}}},
+			}},
+		}},
 	}
 	for i, tt := range tests {
 		node, ok := checkParseOneline(t, tt.input)
@@ -39,6 +454,81 @@ func TestParseLiterals(t *testing.T) {
 			t.Fatalf("test[%d] failed", i)
 		}
 	}
+	for i, input := range []string{"{a}", "{1: 2}", "{a: 1 b: 2}", "{[a: 1}", "{a: }"} {
+		if checkParseError(t, input) == nil {
+			t.Fatalf("invalid[%d] expected an error", i)
+		}
+	}
+}
+
+func TestParseNumberErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		msg   string
+	}{
+		{"1e400", "number 1e400 is out of range"},
+		{"2.5e999", "number 2.5e999 is out of range"},
+	}
+	for i, tt := range tests {
+		errs := checkParseError(t, tt.input)
+		if len(errs) != 1 || errs[0].Msg != tt.msg {
+			t.Fatalf("test[%d] expected error %q, got=%v", i, tt.msg, errs)
+		}
+	}
+}
+
+func TestParseInterpolatedString(t *testing.T) {
+	tests := []struct {
+		input   string
+		strings []string
+		exprs   []interface{}
+	}{
+		{`"a ${b} c"`, []string{"a ", " c"}, []interface{}{ut.ASTIdent{Name: "b"}}},
+		{`"${1 + 2}${x}"`, []string{"", "", ""}, []interface{}{
+			ut.ASTInfix{Left: ut.ASTInteger{Value: 1}, Op: "+", Right: ut.ASTInteger{Value: 2}},
+			ut.ASTIdent{Name: "x"},
+		}},
+		{`"${"x"}"`, []string{"", ""}, []interface{}{ut.ASTString{Value: "x"}}},
+	}
+	for i, tt := range tests {
+		node, ok := checkParseOneline(t, tt.input)
+		if !ok {
+			t.Fatalf("test[%d] failed", i)
+		}
+		if !ut.TestNodeType(t, node, ast.INTERPOLATED_STRING) {
+			t.Fatalf("test[%d] failed", i)
+		}
+		str := node.(*ast.InterpolatedString)
+		if len(str.Strings) != len(tt.strings) || len(str.Exprs) != len(tt.exprs) {
+			t.Fatalf("test[%d] expected %d strings and %d exprs, got=%q and %v",
+				i, len(tt.strings), len(tt.exprs), str.Strings, str.Exprs)
+		}
+		for j := range tt.strings {
+			if str.Strings[j] != tt.strings[j] {
+				t.Fatalf("test[%d] strings[%d] expected=%q, got=%q", i, j, tt.strings[j], str.Strings[j])
+			}
+		}
+		for j := range tt.exprs {
+			if !ut.TestNode(t, str.Exprs[j], tt.exprs[j]) {
+				t.Fatalf("test[%d] exprs[%d] failed", i, j)
+			}
+		}
+	}
+
+	node, _ := checkParseOneline(t, `"a\n${"b" + "${c}"}\${d}"`)
+	if got := node.String(); got != `"a\n${("b" + "${c}")}\${d}"` {
+		t.Fatalf("unexpected String(): %s", got)
+	}
+
+	errs := checkParseError(t, "x = 1\n\"a ${\n  b c}\"")
+	if errs[0].Token.LineNo != 3 || errs[0].Token.Column != 5 {
+		t.Fatalf("expected error at 3:5, got=%v", errs[0])
+	}
+	for i, input := range []string{`"${}"`, `"${a b}"`, `"${a +}"`} {
+		if checkParseError(t, input) == nil {
+			t.Fatalf("invalid[%d] expected an error", i)
+		}
+	}
 }
 
 // ========================
@@ -52,8 +542,8 @@ func TestParseInfixExpression(t *testing.T) {
 		op    string
 		right interface{}
 	}{
-		{"1 + 1", ut.ASTNumber{1}, "+", ut.ASTNumber{1}},
-		{"\"abc\" * nil", ut.ASTString{"abc"}, "*", ut.ASTNil{}},
+		{"1 + 1", ut.ASTInteger{Value: 1}, "+", ut.ASTInteger{Value: 1}},
+		{"\"abc\" * nil", ut.ASTString{Value: "abc"}, "*", ut.ASTNil{}},
 	}
 	for i, tt := range tests {
 		node, ok := checkParseOneline(t, tt.input)
@@ -73,9 +563,9 @@ func TestParseShortCiruiting(t *testing.T) {
 		left  interface{}
 		right interface{}
 	}{
-		{"1 or 1", "or", ut.ASTNumber{1}, ut.ASTNumber{1}},
-		{"\"abc\" or nil", "or", ut.ASTString{"abc"}, ut.ASTNil{}},
-		{"def and nil", "and", ut.ASTIdent{"def"}, ut.ASTNil{}},
+		{"1 or 1", "or", ut.ASTInteger{Value: 1}, ut.ASTInteger{Value: 1}},
+		{"\"abc\" or nil", "or", ut.ASTString{Value: "abc"}, ut.ASTNil{}},
+		{"abc and nil", "and", ut.ASTIdent{Name: "abc"}, ut.ASTNil{}},
 	}
 	for i, tt := range tests {
 		node, ok := checkParseOneline(t, tt.input)
@@ -100,11 +590,11 @@ func TestParseAssignExpression(t *testing.T) {
 		expected string
 		test     ut.ASTAssign
 	}{
-		{"u = 1", "(u = 1)", ut.ASTAssign{ut.ASTIdent{"u"}, ut.ASTNumber{1}}},
-		{"a = b = c", "(a = (b = c))", ut.ASTAssign{ut.ASTIdent{"a"}, ut.ASTAssign{ut.ASTIdent{"b"}, ut.ASTIdent{"c"}}}},
+		{"u = 1", "(u = 1)", ut.ASTAssign{Left: ut.ASTIdent{Name: "u"}, Right: ut.ASTInteger{Value: 1}}},
+		{"a = b = c", "(a = (b = c))", ut.ASTAssign{Left: ut.ASTIdent{Name: "a"}, Right: ut.ASTAssign{Left: ut.ASTIdent{Name: "b"}, Right: ut.ASTIdent{Name: "c"}}}},
 		{"[a=b] = [c]", "([(a = b)] = [c])", ut.ASTAssign{
-			ut.ASTArray{ut.ASTAssign{ut.ASTIdent{"a"}, ut.ASTIdent{"b"}}},
-			ut.ASTArray{ut.ASTIdent{"c"}},
+			Left:  ut.ASTArray{ut.ASTAssign{Left: ut.ASTIdent{Name: "a"}, Right: ut.ASTIdent{Name: "b"}}},
+			Right: ut.ASTArray{ut.ASTIdent{Name: "c"}},
 		}},
 	}
 	for i, tt := range tests {
@@ -136,6 +626,30 @@ func TestPrecedence(t *testing.T) {
 		{"a = b = c", "(a = (b = c))"},
 		{"a.b.c", "((a).b).c"},
 		{"d = a.b.c", "(d = ((a).b).c)"},
+		{"a < b == c > d", "(((a < b) == c) > d)"},
+		{"a == b and c != d", "((a == b) and (c != d))"},
+		{"a or b and c", "(a or (b and c))"},
+		{"a and b or c and d", "((a and b) or (c and d))"},
+		{"a <= b + 1", "(a <= (b + 1))"},
+		{"x == a[1]", "(x == (a)[1])"},
+		{"-a[1]", "(-(a)[1])"},
+		{"a.b[1].c", "(((a).b)[1]).c"},
+		{"a = b if c else d", "(a = (b if c else d))"},
+		{"a[b, c]", "(a)[b,c]"},
+		{"a[b][c] = d", "(((a)[b])[c] = d)"},
+		{"1..n + 1", "(1 .. (n + 1))"},
+		{"a...b == c", "((a ... b) == c)"},
+		{"x = a[1..-1]", "(x = (a)[(1 .. (-1))])"},
+		{"(1..2).len()", "((1 .. 2)).len()"},
+		{"a % b * c", "((a % b) * c)"},
+		{"a ** b ** c", "(a ** (b ** c))"},
+		{"-a ** 2", "(-(a ** 2))"},
+		{"a ** -b", "(a ** (-b))"},
+		{"2 * a ** b[1]", "(2 * (a ** (b)[1]))"},
+		{"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+		{"a & b << 1 + c", "(a & (b << (1 + c)))"},
+		{"a >> 1 == b | c", "((a >> 1) == (b | c))"},
+		{"~a & b", "((~a) & b)"},
 	}
 	for i, tt := range tests {
 		node, ok := checkParseOneline(t, tt.input)
@@ -152,6 +666,194 @@ func TestPrecedence(t *testing.T) {
 // Utils
 // ====================
 
+// checkParseError parses input, and returns the parser errors.
+func TestParseDocComments(t *testing.T) {
+	node, ok := checkParseOneline(t, `/// A point.
+///
+///   x and y are Numbers.
+class Point
+	/// Creates a point.
+	def init(x, y) end
+	// not a doc comment
+	def norm() end
+end`)
+	if !ok {
+		t.FailNow()
+	}
+	class := node.(*ast.ClassStatement)
+	if class.Doc != "A point.\n\n  x and y are Numbers." {
+		t.Fatalf("class.Doc=%q", class.Doc)
+	}
+	var docs []string
+	for _, stmt := range class.Body.Statements {
+		if meth, ok := stmt.(*ast.MethodDeclaration); ok {
+			docs = append(docs, meth.Doc)
+		}
+	}
+	if len(docs) != 2 || docs[0] != "Creates a point." || docs[1] != "" {
+		t.Fatalf("method docs=%q", docs)
+	}
+
+	node, ok = checkParseOneline(t, "/// Adds one.\nlet f = fn(x) x + 1 end")
+	if !ok {
+		t.FailNow()
+	}
+	fn := node.(*ast.LetStatement).Binding.(*ast.AssignmentExpression).Right.(*ast.FunctionLiteral)
+	if fn.Doc != "Adds one." {
+		t.Fatalf("fn.Doc=%q", fn.Doc)
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	input := `class A
+	def f(x) [x, {a: x}] end
+end
+for i in 0..10 do
+	if i % 2 == 0 then print("${i} is even") end
+end`
+	s := scanner.New("", input)
+	s.ScanAll()
+	expected := parser.New("", s.Tokens()).MustParse()
+	r := iotest.OneByteReader(strings.NewReader(input))
+	program, errs := parser.NewSource("", scanner.NewReader("", r)).Parse()
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if program.String() != expected.String() {
+		t.Fatalf("expected=%s, got=%s", expected, program)
+	}
+
+	// scanner errors are reported by the parser.
+	_, errs = parser.NewSource("a.jg", scanner.NewReader("a.jg", strings.NewReader("a = 1 @ 2\nb"))).Parse()
+	if len(errs) == 0 || errs[0].String() != "a.jg:1:7:unrecognised character U+0040: '@'" {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if tok := errs[0].Token; tok.Type != scanner.TokenIllegal || tok.Value != "@" {
+		t.Fatalf("expected the illegal token '@', got %s", tok)
+	}
+}
+
+func TestParseSpans(t *testing.T) {
+	input := `let x = (a + b) * c.d[1, 2]
+for k, v in f(x, "${y}") do
+	g({a: [1]}, -k if v else 0)
+end`
+	s := scanner.New("", input)
+	s.ScanAll()
+	program := parser.New("", s.Tokens()).MustParse()
+	text := func(node ast.Node) string {
+		span := node.Span()
+		return input[span.Start.Offset:span.End.Offset]
+	}
+	let := program.Statements[0].(*ast.LetStatement)
+	product := let.Binding.(*ast.AssignmentExpression).Right.(*ast.InfixExpression)
+	loop := program.Statements[1].(*ast.ForStatement)
+	call := loop.Body.Statements[0].(*ast.ExpressionStatement).Expr.(*ast.CallExpression)
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{program, input},
+		{let, "let x = (a + b) * c.d[1, 2]"},
+		{product, "(a + b) * c.d[1, 2]"},
+		{product.Left, "(a + b)"},
+		{product.Left.(*ast.ParenExpression).Expr, "a + b"},
+		{product.Right, "c.d[1, 2]"},
+		{loop, input[strings.Index(input, "for"):]},
+		{loop.Binding, "k, v"},
+		{loop.Iterable, `f(x, "${y}")`},
+		{loop.Iterable.(*ast.CallExpression).Args[1], `"${y}"`},
+		{call, "g({a: [1]}, -k if v else 0)"},
+		{call.Args[0], "{a: [1]}"},
+		{call.Args[1], "-k if v else 0"},
+	}
+	for i, tt := range tests {
+		if got := text(tt.node); got != tt.expected {
+			t.Fatalf("test[%d] expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+	if span := call.Span(); span.Start.LineNo != 3 || span.Start.Column != 2 ||
+		span.End.LineNo != 3 || span.End.Column != 29 {
+		t.Fatalf("unexpected span: %+v", span)
+	}
+
+	// errors about a node cover the whole node.
+	errs := checkParseError(t, "x + 1 = 2")
+	if len(errs) != 1 {
+		t.Fatalf("expected an error, got=%v", errs)
+	}
+	if span := errs[0].Span(); span.Start.Offset != 0 || span.End.Offset != 5 {
+		t.Fatalf("unexpected error span: %+v", span)
+	}
+}
+
+func TestParseIncomplete(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+	}{
+		{"class Foo", true},
+		{"class Foo\n  def f(x)\n", true},
+		{"fn(x)", true},
+		{"f(1,", true},
+		{"f(1,\n2", true},
+		{"[1, 2", true},
+		{"{a: 1,\n", true},
+		{"x = (1 +", true},
+		{"if x then\n  y\nelse", true},
+		{"try\n  x\ncatch e", true},
+		{"class A\n  def", true},
+		{`"abc`, true},
+		{`"a ${x`, true},
+		{"/* a /* b */", true},
+		{"x = 1", false},
+		{"f(1,\n2)", false},
+		{"end", false},
+		{"x + 1 = 2", false},
+		{"f(1 2", false},
+		{"class Foo\n  def f(x) ) end", false},
+		{"x = @", false},
+	}
+	for i, tt := range tests {
+		_, errs := parser.NewSource("", scanner.New("", tt.input)).Parse()
+		if parser.Incomplete(errs) != tt.incomplete {
+			t.Fatalf("test[%d] %q expected incomplete=%v, got errors %v", i, tt.input, tt.incomplete, errs)
+		}
+	}
+}
+
+func TestParseNewlinesInBrackets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(\n  1,\n  2\n)", "f(1,2)"},
+		{"[1,\n2][\n0\n]", "([1, 2])[0]"},
+		{"{\n  a: 1,\n  b: (2\n    + 3)\n}", "{a: 1, b: (2 + 3)}"},
+		// newlines separate statements in a function, even in brackets.
+		{"f(fn(x)\n  y = x\n  y\nend, 2)", "f(fn(x) (y = x);y; end,2)"},
+	}
+	for i, tt := range tests {
+		node, ok := checkParseOneline(t, tt.input)
+		if !ok {
+			t.Fatalf("test[%d] failed", i)
+		}
+		if got := node.String(); got != tt.expected {
+			t.Fatalf("test[%d] expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
+
+func checkParseError(t *testing.T, input string) []parser.ParserError {
+	s := scanner.New("", input)
+	s.ScanAll()
+	if s.Errors() != nil {
+		t.Fatalf("cannot scan %q: %v", input, s.Errors())
+	}
+	_, errs := parser.New("", s.Tokens()).Parse()
+	return errs
+}
+
 func checkParseOneline(t *testing.T, input string) (ast.Node, bool) {
 	s := scanner.New("", input)
 	s.ScanAll()
@@ -164,15 +866,20 @@ func checkParseOneline(t *testing.T, input string) (ast.Node, bool) {
 		return nil, false
 	}
 	p := parser.New("", s.Tokens())
-	program, err := p.Parse()
-	if err != nil {
+	program, errs := p.Parse()
+	if errs != nil {
 		t.Errorf("cannot parse:\n\t%q", input)
-		t.Errorf("failed with error:\n\t%s", err)
+		for _, err := range errs {
+			t.Errorf("failed with error:\n\t%s", err)
+		}
 		return nil, false
 	}
 	if len(program.Statements) != 1 {
 		t.Errorf("expected len(program.Statements)=1, got=%d", len(program.Statements))
 		return nil, false
 	}
+	if stmt, ok := program.Statements[0].(*ast.ExpressionStatement); ok {
+		return stmt.Expr, true
+	}
 	return program.Statements[0], true
 }
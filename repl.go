@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"jingle/ast"
+	"jingle/eval"
+	"jingle/parser"
+	"jingle/scanner"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// The REPL. On a terminal, lines can be edited, the history is browsed
+// with the arrow keys and searched with Ctrl-R, and Tab completes
+// names. The history is kept in ~/.jingle_history, or in
+// $JINGLE_HISTORY if set. Lines starting with a colon are
+// meta-commands, see replHelp.
+
+const replHelp = `:ast expr     print the syntax tree of expr
+:tokens expr  print the tokens of expr
+:load file    run file
+:reset        forget all the definitions
+:time expr    evaluate expr, and print how long it took
+:help         print this help
+:quit         exit
+`
+
+// showError prints an error in the REPL.
+func showError(str string) {
+	fmt.Printf("%s%s%s\n", ERR_FORMAT, str, ERR_RESET)
+}
+
+// lineReader reads the lines of input, showing a prompt.
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// plainReader reads lines when the input is not a terminal.
+type plainReader struct{ sc *bufio.Scanner }
+
+func (r plainReader) readLine(prompt string) (string, error) {
+	fmt.Fprint(os.Stdout, prompt)
+	if !r.sc.Scan() {
+		if err := r.sc.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.sc.Text(), nil
+}
+
+type repl struct {
+	ev *eval.Context
+	n  int // number of the next entry
+}
+
+func replCommand(args []string) int {
+	fs := flag.NewFlagSet("repl", flag.ExitOnError)
+	fs.Usage = commandUsage(fs, "repl", "")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	r := &repl{ev: eval.NewContext(), n: 1}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		r.run(plainReader{bufio.NewScanner(os.Stdin)})
+		return exitOK
+	}
+	ed := newEditor(fd, os.Stdin, os.Stdout)
+	ed.complete = r.complete
+	if path := historyFile(); path != "" {
+		if err := ed.loadHistory(path); err != nil {
+			showError(err.Error())
+		}
+	}
+	defer ed.close()
+	r.run(ed)
+	return exitOK
+}
+
+// historyFile returns the file the history is kept in.
+func historyFile() string {
+	if path, ok := os.LookupEnv("JINGLE_HISTORY"); ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".jingle_history")
+}
+
+func (r *repl) run(in lineReader) {
+	var lines []string // lines of the current entry
+	for {
+		prompt := "> "
+		if len(lines) > 0 {
+			prompt = "... "
+		}
+		line, err := in.readLine(prompt)
+		if err == errInterrupt {
+			lines = nil
+			continue
+		} else if err != nil {
+			if err != io.EOF {
+				showError(err.Error())
+			}
+			return
+		}
+		if len(lines) == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			if !r.command(strings.TrimSpace(line)) {
+				return
+			}
+			continue
+		}
+		lines = append(lines, line)
+		src := strings.Join(lines, "\n")
+		// every entry gets its own filename, so that tracebacks
+		// can show the right source lines.
+		fn := fmt.Sprintf("<stdin#%d>", r.n)
+		prog, errs := parseEntry(fn, src)
+		// keep on reading lines while the entry is incomplete, unless
+		// it ends in two blank lines, which give up on it.
+		giveUp := len(lines) >= 3 && strings.TrimSpace(strings.Join(lines[len(lines)-2:], "")) == ""
+		if parser.Incomplete(errs) && !giveUp {
+			continue
+		}
+		lines = nil
+		r.n++
+		if errs != nil {
+			showErrors(errs)
+			continue
+		}
+		r.eval(fn, src, prog)
+	}
+}
+
+func parseEntry(fn string, src string) (*ast.Program, []parser.ParserError) {
+	return parser.NewSource(fn, scanner.New(fn, src)).Parse()
+}
+
+func showErrors(errs []parser.ParserError) {
+	for _, err := range errs {
+		showError(err.Error())
+	}
+}
+
+// eval evaluates prog, parsed from src, and prints the result.
+func (r *repl) eval(fn string, src string, prog *ast.Program) {
+	r.ev.AddSource(fn, src)
+	r.print(r.ev.Eval(prog))
+}
+
+// print prints val as given by its inspect method, or the error
+// it is.
+func (r *repl) print(val eval.Value) {
+	if err, ok := val.(*eval.Error); ok {
+		showError(r.ev.FormatError(err))
+		return
+	}
+	str, err := r.ev.Inspect(val)
+	if err != nil {
+		showError(r.ev.FormatError(err))
+		return
+	}
+	fmt.Println(str)
+}
+
+// command runs a meta-command, and returns false if the REPL
+// should exit.
+func (r *repl) command(line string) bool {
+	name, arg := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		name, arg = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	switch name {
+	case ":ast":
+		r.printAST(arg)
+	case ":tokens":
+		r.printTokens(arg)
+	case ":load":
+		r.load(arg)
+	case ":reset":
+		r.ev = eval.NewContext()
+	case ":time":
+		r.time(arg)
+	case ":help":
+		fmt.Print(replHelp)
+	case ":quit":
+		return false
+	default:
+		showError(fmt.Sprintf("unknown command %s, see :help", name))
+	}
+	return true
+}
+
+// filename returns the filename of the next entry.
+func (r *repl) filename() string {
+	fn := fmt.Sprintf("<stdin#%d>", r.n)
+	r.n++
+	return fn
+}
+
+func (r *repl) printAST(src string) {
+	prog, errs := parseEntry(r.filename(), src)
+	if errs != nil {
+		showErrors(errs)
+		return
+	}
+	w := bufio.NewWriter(os.Stdout)
+	for _, stmt := range prog.Statements {
+		ast.Frprint(w, stmt, 0)
+		w.WriteString("\n")
+	}
+	w.Flush()
+}
+
+func (r *repl) printTokens(src string) {
+	s := scanner.New(r.filename(), src)
+	for {
+		tok, err := s.Next()
+		if err != nil {
+			showError(err.Error())
+			continue
+		}
+		fmt.Println(tok)
+		if tok.Type == scanner.TokenEOF {
+			return
+		}
+	}
+}
+
+func (r *repl) load(path string) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		showError(err.Error())
+		return
+	}
+	prog, errs := parseEntry(path, string(src))
+	if errs != nil {
+		showErrors(errs)
+		return
+	}
+	r.ev.AddSource(path, string(src))
+	if err, ok := r.ev.Eval(prog).(*eval.Error); ok {
+		showError(r.ev.FormatError(err))
+	}
+}
+
+func (r *repl) time(src string) {
+	fn := r.filename()
+	prog, errs := parseEntry(fn, src)
+	if errs != nil {
+		showErrors(errs)
+		return
+	}
+	r.ev.AddSource(fn, src)
+	start := time.Now()
+	val := r.ev.Eval(prog)
+	elapsed := time.Since(start)
+	r.print(val)
+	fmt.Printf("took %s\n", elapsed)
+}
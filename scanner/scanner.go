@@ -4,16 +4,36 @@ package scanner
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"strings"
+	"unicode"
 	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/unicode/rangetable"
 )
 
 type Error struct {
-	Filename string
-	Message  string
-	Value    string
-	LineNo   int
-	Column   int
+	Filename  string
+	Message   string
+	Value     string
+	LineNo    int
+	Column    int
+	Offset    int
+	EndLineNo int
+	EndColumn int
+	EndOffset int
+	// Incomplete is true if the input ended too soon, as in an
+	// unterminated string, so that more input might fix the error.
+	Incomplete bool
+}
+
+// Span returns the part of the input that the error is about.
+func (e Error) Span() Span {
+	return Span{
+		Start: Pos{Offset: e.Offset, LineNo: e.LineNo, Column: e.Column},
+		End:   Pos{Offset: e.EndOffset, LineNo: e.EndLineNo, Column: e.EndColumn},
+	}
 }
 
 func (e Error) Error() string { return e.String() }
@@ -22,22 +42,42 @@ func (e Error) String() string {
 }
 
 type Scanner struct {
-	filename  string // filename
-	input     string // input
-	ch        rune   // current rune under inspection
-	pos       int    // how many bytes we've consumed
-	line      int    // our current positions in the input
+	filename  string    // filename
+	input     string    // input, or the part of it that we still need
+	r         io.Reader // the rest of the input, for NewReader
+	buf       []byte    // buffer for reading from r
+	base      int       // offset of input[0] in the whole input
+	ch        rune      // current rune under inspection
+	pos       int       // position in input after the current rune
+	line      int       // our current positions in the input
 	col       int
 	start     int // starting position where we began reading the token
 	startLine int
 	startCol  int
-	err       bool
+	interp    []int   // open braces in each enclosing ${...}, innermost last
+	comments  bool    // keep ordinary comments as trivia
+	trivia    []Token // comments waiting for the next token
+	last      Token   // the last token added
 	tokens    []Token // list of tokens
 	errors    []error // list of errors encountered
 }
 
-func New(filename string, input string) *Scanner {
-	return &Scanner{
+// readSize is the minimum number of bytes that NewReader scanners
+// read at a time.
+const readSize = 4096
+
+// Option configures a Scanner.
+type Option func(*Scanner)
+
+// KeepComments makes the scanner keep // and /* */ comments, attaching
+// them as Trivia to the token that follows. Doc comments are always kept.
+func KeepComments() Option {
+	return func(s *Scanner) { s.comments = true }
+}
+
+// New returns a scanner for the given input.
+func New(filename string, input string, opts ...Option) *Scanner {
+	s := &Scanner{
 		filename:  filename,
 		input:     input,
 		line:      1,
@@ -47,6 +87,19 @@ func New(filename string, input string) *Scanner {
 		tokens:    []Token{},
 		errors:    []error{},
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// NewReader returns a scanner that reads its input from r as it
+// goes, only keeping the input of the token that it is scanning.
+// Use Next to read tokens from it, so that they are not kept either.
+func NewReader(filename string, r io.Reader, opts ...Option) *Scanner {
+	s := New(filename, "", opts...)
+	s.r = r
+	return s
 }
 
 // More() returns true if there is more input to be read
@@ -55,7 +108,7 @@ func New(filename string, input string) *Scanner {
 func (s *Scanner) More() bool {
 	// we have to use <= here, so that the token stream
 	// produces one EOF
-	return s.pos <= len(s.input) && !s.err && len(s.errors) <= 10
+	return s.pos <= len(s.input) && len(s.errors) <= 10
 }
 
 func (s *Scanner) Tokens() []Token { return s.tokens }
@@ -72,17 +125,102 @@ func (s *Scanner) ScanAll() {
 	}
 }
 
+// Next returns the next token, scanning more of the input if needed.
+// Errors are returned as they are found, before the token that they
+// were found in, and scanning can carry on after them. Once the input
+// runs out, Next keeps on returning an EOF token. Tokens and errors
+// returned by Next are no longer returned by Tokens and Errors.
+func (s *Scanner) Next() (Token, error) {
+	for len(s.tokens) == 0 && len(s.errors) == 0 {
+		if s.pos <= len(s.input) {
+			s.Scan()
+		} else {
+			// we are past the end of the input, possibly after an
+			// error in the middle of a token.
+			s.ignore()
+			s.addTokenWithValue(TokenEOF, "")
+		}
+	}
+	if len(s.errors) > 0 {
+		err := s.errors[0]
+		s.errors = append(s.errors[:0], s.errors[1:]...)
+		return Token{}, err
+	}
+	tok := s.tokens[0]
+	s.tokens = append(s.tokens[:0], s.tokens[1:]...)
+	return tok, nil
+}
+
+// fill makes sure that there are at least n bytes of input after
+// pos, unless the reader runs out first. Input before the start of
+// the current token is thrown away.
+func (s *Scanner) fill(n int) {
+	for s.r != nil && len(s.input)-s.pos < n {
+		keep := s.input[s.start:]
+		// read at least as much as we keep, so that scanning a long
+		// token takes linear time.
+		size := readSize
+		if len(keep) > size {
+			size = len(keep)
+		}
+		if cap(s.buf) < size {
+			s.buf = make([]byte, size)
+		}
+		m, err := s.r.Read(s.buf[:size])
+		s.input = keep + string(s.buf[:m])
+		s.base += s.start
+		s.pos -= s.start
+		s.start = 0
+		if err != nil {
+			if err != io.EOF {
+				s.errors = append(s.errors, err)
+			}
+			s.r = nil
+		}
+	}
+}
+
+// offset returns the offset in the whole input of input[i].
+func (s *Scanner) offset(i int) int {
+	if i > len(s.input) {
+		i = len(s.input) // we have advanced past EOF
+	}
+	return s.base + i
+}
+
+// here returns the position after the current rune.
+func (s *Scanner) here() Pos {
+	return Pos{Offset: s.offset(s.pos), LineNo: s.line, Column: s.col}
+}
+
+// token returns a token for the current input.
+func (s *Scanner) token(typ TokenType, value string) Token {
+	end := s.here()
+	return Token{
+		Type:      typ,
+		Value:     value,
+		LineNo:    s.startLine,
+		Column:    s.startCol,
+		Offset:    s.offset(s.start),
+		EndLineNo: end.LineNo,
+		EndColumn: end.Column,
+		EndOffset: end.Offset,
+	}
+}
+
 // addToken adds a token under the current input.
 func (s *Scanner) addToken(typ TokenType) { s.addTokenWithValue(typ, s.input[s.start:s.pos]) }
 
 // addTokenWithValue adds a token with a specified Value.
 func (s *Scanner) addTokenWithValue(typ TokenType, value string) {
-	s.tokens = append(s.tokens, Token{
-		Type:   typ,
-		Value:  value,
-		LineNo: s.startLine,
-		Column: s.startCol,
-	})
+	tok := s.token(typ, value)
+	// comments belong to the next token that is not a separator.
+	if len(s.trivia) > 0 && typ != TokenSeparator {
+		tok.Trivia = &Trivia{Comments: s.trivia}
+		s.trivia = nil
+	}
+	s.tokens = append(s.tokens, tok)
+	s.last = tok
 	s.start = s.pos
 	s.startLine = s.line
 	s.startCol = s.col
@@ -90,27 +228,61 @@ func (s *Scanner) addTokenWithValue(typ TokenType, value string) {
 
 // addError adds an error under the current input.
 func (s *Scanner) addError(f string, args ...interface{}) {
+	start := Pos{Offset: s.offset(s.start), LineNo: s.startLine, Column: s.startCol}
+	s.addErrorAt(start, f, args...)
+}
+
+// addErrorAt adds an error under the current input, reported from
+// the given position.
+func (s *Scanner) addErrorAt(start Pos, f string, args ...interface{}) {
+	end := s.here()
 	s.errors = append(s.errors, Error{
-		Filename: s.filename,
-		Message:  fmt.Sprintf(f, args...),
-		Value:    s.input[s.start:s.pos],
-		LineNo:   s.startLine,
-		Column:   s.startCol,
+		Filename:  s.filename,
+		Message:   fmt.Sprintf(f, args...),
+		Value:     s.input[s.start : end.Offset-s.base],
+		LineNo:    start.LineNo,
+		Column:    start.Column,
+		Offset:    start.Offset,
+		EndLineNo: end.LineNo,
+		EndColumn: end.Column,
+		EndOffset: end.Offset,
 	})
 	s.start = s.pos
 	s.startLine = s.line
 	s.startCol = s.col
 }
 
+// addEOFError adds an error for input that ended in the middle of
+// what, which is incomplete.
+func (s *Scanner) addEOFError(what string) {
+	s.addError("unexpected EOF in %s", what)
+	err := s.errors[len(s.errors)-1].(Error)
+	err.Incomplete = true
+	s.errors[len(s.errors)-1] = err
+}
+
 func (s *Scanner) advance() rune {
+	s.fill(utf8.UTFMax)
 	if s.pos == len(s.input) {
 		s.pos++ // increment here to signal to More() we're done.
 		s.ch = 0
 		return s.ch
 	}
 	r, w := utf8.DecodeRuneInString(s.input[s.pos:])
-	if r == utf8.RuneError {
-		s.err = true
+	if r == utf8.RuneError && w == 1 {
+		// report the error without disturbing the token that we are
+		// scanning, which just gets a U+FFFD in place of the byte.
+		s.errors = append(s.errors, Error{
+			Filename:  s.filename,
+			Message:   fmt.Sprintf("invalid UTF-8 byte %#x", s.input[s.pos]),
+			Value:     s.input[s.pos : s.pos+1],
+			LineNo:    s.line,
+			Column:    s.col,
+			Offset:    s.offset(s.pos),
+			EndLineNo: s.line,
+			EndColumn: s.col + 1,
+			EndOffset: s.offset(s.pos + 1),
+		})
 	}
 	s.pos += w
 	s.ch = r
@@ -123,6 +295,7 @@ func (s *Scanner) advance() rune {
 }
 
 func (s *Scanner) peek() rune {
+	s.fill(utf8.UTFMax)
 	if s.pos == len(s.input) {
 		return 0
 	}
@@ -130,9 +303,24 @@ func (s *Scanner) peek() rune {
 	return r
 }
 
+// peekNext returns the rune after the one returned by peek.
+func (s *Scanner) peekNext() rune {
+	s.fill(2 * utf8.UTFMax)
+	if s.pos == len(s.input) {
+		return 0
+	}
+	_, w := utf8.DecodeRuneInString(s.input[s.pos:])
+	if s.pos+w == len(s.input) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(s.input[s.pos+w:])
+	return r
+}
+
 // match advances the scanner if the lookahead runes
 // match the given runes.
 func (s *Scanner) match(prefix ...rune) bool {
+	s.fill(len(prefix) * utf8.UTFMax)
 	d := 0
 	for _, p := range prefix {
 		r, w := utf8.DecodeRuneInString(s.input[s.pos+d:])
@@ -152,24 +340,48 @@ func (s *Scanner) Scan() {
 	s.advance()
 	switch s.ch {
 	case 0:
-		s.tokens = append(s.tokens, Token{TokenEOF, "", s.line, s.col})
+		if len(s.interp) > 0 {
+			s.addEOFError("string interpolation")
+		}
+		s.ignore()
+		s.addTokenWithValue(TokenEOF, "")
 	case ' ', '\t':
 		s.munchWhitespace()
 	case '\r', '\n', ';':
 		s.matchRun("\n\r \t;")
-		s.addToken(TokenSeparator)
+		if len(s.interp) > 0 {
+			// an interpolated expression is a single expression,
+			// so there is nothing to separate.
+			s.ignore()
+		} else if s.last.Type == TokenSeparator {
+			// only a comment came between the two separators.
+			s.ignore()
+		} else {
+			s.addToken(TokenSeparator)
+		}
 	case '/':
-		if s.match('/') {
-			// a comment -- match up to newline or EOF
-			for s.ch != '\n' && s.ch != 0 {
+		switch {
+		case s.match('/'):
+			// a comment -- match up to, but not including, the newline
+			// so that it still separates statements.
+			for p := s.peek(); p != '\n' && p != 0; p = s.peek() {
 				s.advance()
 			}
-			s.ignore()
-		} else {
+			text := s.input[s.start:s.pos]
+			if strings.HasPrefix(text, "///") && !strings.HasPrefix(text, "////") {
+				s.addComment(TokenDocComment)
+			} else {
+				s.addComment(TokenComment)
+			}
+		case s.match('*'):
+			s.scanBlockComment()
+		default:
 			s.addToken(TokenDiv)
 		}
 	case ',':
 		s.addToken(TokenComma)
+	case ':':
+		s.addToken(TokenColon)
 	case '=':
 		if s.match('=') {
 			s.addToken(TokenEq)
@@ -183,7 +395,21 @@ func (s *Scanner) Scan() {
 			s.addToken(TokenBang)
 		}
 	case '*':
-		s.addToken(TokenMul)
+		if s.match('*') {
+			s.addToken(TokenPow)
+		} else {
+			s.addToken(TokenMul)
+		}
+	case '%':
+		s.addToken(TokenMod)
+	case '&':
+		s.addToken(TokenAmp)
+	case '|':
+		s.addToken(TokenPipe)
+	case '^':
+		s.addToken(TokenCaret)
+	case '~':
+		s.addToken(TokenTilde)
 	case '+':
 		s.addToken(TokenPlus)
 	case '-':
@@ -191,36 +417,60 @@ func (s *Scanner) Scan() {
 	case '<':
 		if s.match('=') {
 			s.addToken(TokenLeq)
+		} else if s.match('<') {
+			s.addToken(TokenShl)
 		} else {
 			s.addToken(TokenLt)
 		}
 	case '>':
 		if s.match('=') {
 			s.addToken(TokenGeq)
+		} else if s.match('>') {
+			s.addToken(TokenShr)
 		} else {
 			s.addToken(TokenGt)
 		}
 	case '.':
-		s.addToken(TokenDot)
+		if s.match('.', '.') {
+			s.addToken(TokenDotDotDot)
+		} else if s.match('.') {
+			s.addToken(TokenDotDot)
+		} else {
+			s.addToken(TokenDot)
+		}
 	case '(':
 		s.addToken(TokenLParen)
 	case ')':
 		s.addToken(TokenRParen)
 	case '{':
+		if len(s.interp) > 0 {
+			s.interp[len(s.interp)-1]++
+		}
 		s.addToken(TokenLBrace)
 	case '}':
+		if n := len(s.interp); n > 0 {
+			if s.interp[n-1] == 0 {
+				// end of the interpolated expression
+				s.interp = s.interp[:n-1]
+				s.scanString(true)
+				return
+			}
+			s.interp[n-1]--
+		}
 		s.addToken(TokenRBrace)
 	case '[':
 		s.addToken(TokenLBracket)
 	case ']':
 		s.addToken(TokenRBracket)
 	case '"':
-		s.scanString()
+		s.scanString(false)
 	default:
 		if isDigit(s.ch) {
 			s.scanNumber()
 		} else if isLetter(s.ch) {
 			s.scanIdent()
+		} else if s.ch == utf8.RuneError && s.pos-s.start == 1 {
+			s.ignore() // an invalid byte, which advance() has reported
 		} else {
 			s.addError("unrecognised character %U: %q", s.ch, s.ch)
 		}
@@ -234,6 +484,35 @@ func (s *Scanner) ignore() {
 	s.startCol = s.col
 }
 
+// addComment records the comment under the current input, to be
+// attached to the next token.
+func (s *Scanner) addComment(typ TokenType) {
+	if typ == TokenDocComment || s.comments {
+		s.trivia = append(s.trivia, s.token(typ, s.input[s.start:s.pos]))
+	}
+	s.ignore()
+}
+
+// scanBlockComment scans a /* ... */ comment, after the opening /*.
+// Block comments nest.
+func (s *Scanner) scanBlockComment() {
+	for depth := 1; depth > 0; {
+		switch {
+		case s.peek() == 0:
+			s.advance()
+			s.addEOFError("block comment")
+			return
+		case s.match('/', '*'):
+			depth++
+		case s.match('*', '/'):
+			depth--
+		default:
+			s.advance()
+		}
+	}
+	s.addComment(TokenComment)
+}
+
 func (s *Scanner) munchWhitespace() {
 	// whitespace tokens are '\t' and ' ', all ignored!
 	s.matchRun("\t ")
@@ -241,38 +520,130 @@ func (s *Scanner) munchWhitespace() {
 }
 
 func (s *Scanner) scanIdent() {
-	// Idents match [a-zA-Z_][a-zA-Z_0-9]*'*
+	// Idents match XID_Start XID_Continue* '*, where XID_Start also
+	// includes '_'. They are normalized to NFC, so that names which
+	// look the same are the same.
 	for isAlphaNumeric(s.peek()) {
 		s.advance()
 	}
 	s.matchRun("'")
 	word := s.input[s.start:s.pos]
+	if !isASCII(word) {
+		word = norm.NFC.String(word)
+	}
 	if typ, ok := keywords[word]; ok {
-		s.addToken(typ)
+		s.addTokenWithValue(typ, word)
 	} else {
-		s.addToken(TokenIdent)
+		s.addTokenWithValue(TokenIdent, word)
 	}
 }
 
+// scanNumber scans a number literal. Integers are either decimal, or
+// have a 0x, 0o or 0b prefix. Floats are decimal numbers with a
+// fraction or an exponent (1.5, 1e10, 1.5e-3). Digits may be separated by underscores,
+// as in 1_000_000. A "." is only part of a number if it is followed
+// by a digit, so "2.foo" is a method call on 2.
 func (s *Scanner) scanNumber() {
 	// we're currently on top of a digit.
-	digits := "0123456789"
-	s.matchRun(digits)
-	if s.matchSet(".") {
-		// this means that stuff like "2." are accepted.
-		s.matchRun(digits)
+	base, kind := 10, "number"
+	typ := TokenInteger
+	if s.ch == '0' {
+		switch s.peek() {
+		case 'x', 'X':
+			base, kind = 16, "hexadecimal"
+		case 'o', 'O':
+			base, kind = 8, "octal"
+		case 'b', 'B':
+			base, kind = 2, "binary"
+		}
 	}
-	s.addToken(TokenNumber)
+	if base != 10 {
+		s.advance()
+		if n, ok := s.scanDigits(base, 0); !ok {
+			return
+		} else if n == 0 && !isAlphaNumeric(s.peek()) {
+			s.numberError(s.here(), "%s literal has no digits", kind)
+			return
+		}
+	} else {
+		if _, ok := s.scanDigits(10, 1); !ok {
+			return
+		}
+		if s.peek() == '.' && isDigit(s.peekNext()) {
+			s.advance()
+			typ = TokenFloat
+			if _, ok := s.scanDigits(10, 0); !ok {
+				return
+			}
+		}
+		if s.matchSet("eE") {
+			s.matchSet("+-")
+			typ = TokenFloat
+			if n, ok := s.scanDigits(10, 0); !ok {
+				return
+			} else if n == 0 {
+				s.numberError(s.here(), "exponent has no digits")
+				return
+			}
+		}
+	}
+	if r := s.peek(); isAlphaNumeric(r) {
+		if isDigit(r) || (base != 10 && isDigitIn(r, 16)) {
+			s.numberError(s.here(), "invalid digit %q in %s literal", r, kind)
+		} else {
+			s.numberError(s.here(), "invalid character %q in %s literal", r, kind)
+		}
+		return
+	}
+	s.addToken(typ)
 }
 
-func (s *Scanner) scanString() {
-	// we're on top of a " char
+// scanDigits consumes a run of digits in the given base, which may be
+// separated by single underscores, and returns the number of digits
+// in the run. n is the number of digits that were already consumed.
+// If an underscore is misplaced, it reports an error and returns false.
+func (s *Scanner) scanDigits(base int, n int) (int, bool) {
+	for {
+		r := s.peek()
+		if r == '_' {
+			if n == 0 || !isDigitIn(s.peekNext(), base) {
+				s.numberError(s.here(), "'_' must separate successive digits")
+				return n, false
+			}
+			s.advance()
+			continue
+		}
+		if !isDigitIn(r, base) {
+			return n, true
+		}
+		s.advance()
+		n++
+	}
+}
+
+// numberError reports an error in a number literal from the given
+// position, skipping over the rest of the literal.
+func (s *Scanner) numberError(at Pos, f string, args ...interface{}) {
+	for isAlphaNumeric(s.peek()) || s.peek() == '.' && isDigit(s.peekNext()) {
+		s.advance()
+	}
+	s.addErrorAt(at, f, args...)
+}
+
+// scanString scans a string literal. Strings containing `${expr}`
+// are split up into a TokenStringStart, the tokens of expr, any
+// number of TokenStringMiddle tokens followed by their expressions,
+// and a TokenStringEnd. The values of these tokens are the literal
+// parts of the string. cont is true if we are continuing a string
+// after the '}' of an interpolated expression.
+func (s *Scanner) scanString(cont bool) {
+	// we're on top of a " or } char
 	var buf bytes.Buffer
 	escape := false // are we in escape mode?
 	for {
 		s.advance()
 		if s.ch == 0 {
-			s.addError("unexpected EOF in string literal")
+			s.addEOFError("string literal")
 			return
 		}
 		if escape {
@@ -290,6 +661,8 @@ func (s *Scanner) scanString() {
 				buf.WriteByte('\t')
 			case '\\':
 				buf.WriteByte('\\')
+			case '$':
+				buf.WriteByte('$')
 			default:
 				// Actually the error is one char back. This won't produce s.col < 0,
 				// since we've incremented col prior to coming here.
@@ -302,7 +675,23 @@ func (s *Scanner) scanString() {
 			case '\\':
 				escape = true
 			case '"':
-				s.addTokenWithValue(TokenString, buf.String())
+				if cont {
+					s.addTokenWithValue(TokenStringEnd, buf.String())
+				} else {
+					s.addTokenWithValue(TokenString, buf.String())
+				}
+				return
+			case '$':
+				if !s.match('{') {
+					buf.WriteRune(s.ch)
+					continue
+				}
+				if cont {
+					s.addTokenWithValue(TokenStringMiddle, buf.String())
+				} else {
+					s.addTokenWithValue(TokenStringStart, buf.String())
+				}
+				s.interp = append(s.interp, 0)
 				return
 			default:
 				buf.WriteRune(s.ch)
@@ -311,16 +700,83 @@ func (s *Scanner) scanString() {
 	}
 }
 
+// isAlphaNumeric returns true if ch can continue an identifier.
 func isAlphaNumeric(ch rune) bool {
-	return isDigit(ch) || isLetter(ch)
+	if ch < utf8.RuneSelf {
+		return isDigit(ch) || isLetter(ch)
+	}
+	return unicode.In(ch, idStart, idContinue) &&
+		!unicode.In(ch, unicode.Pattern_Syntax, unicode.Pattern_White_Space, notXIDContinue)
 }
 
 func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+// isDigitIn returns true if ch is a digit in the given base.
+func isDigitIn(ch rune, base int) bool {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch-'0') < base
+	case 'a' <= ch && ch <= 'f':
+		return base == 16
+	case 'A' <= ch && ch <= 'F':
+		return base == 16
+	}
+	return false
+}
+
+// isLetter returns true if ch can start an identifier.
 func isLetter(ch rune) bool {
-	return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ch == '_'
+	if ch < utf8.RuneSelf {
+		return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ch == '_'
+	}
+	return unicode.In(ch, idStart) &&
+		!unicode.In(ch, unicode.Pattern_Syntax, unicode.Pattern_White_Space, notXIDStart)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// The unicode package does not have tables for XID_Start and
+// XID_Continue, so they are derived from their definitions in UAX #31:
+// ID_Start is the letters, letter numbers and Other_ID_Start, and
+// ID_Continue adds marks, digits, connector punctuation and
+// Other_ID_Continue; neither contain Pattern_Syntax or
+// Pattern_White_Space. The XID variants leave out a few characters
+// that are not closed under NFKC normalization.
+var (
+	idStart    = rangetable.Merge(unicode.L, unicode.Nl, unicode.Other_ID_Start)
+	idContinue = rangetable.Merge(unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue)
+)
+
+var notXIDStart = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x037a, Hi: 0x037a, Stride: 1},
+		{Lo: 0x0e33, Hi: 0x0e33, Stride: 1},
+		{Lo: 0x0eb3, Hi: 0x0eb3, Stride: 1},
+		{Lo: 0x309b, Hi: 0x309c, Stride: 1},
+		{Lo: 0xfc5e, Hi: 0xfc63, Stride: 1},
+		{Lo: 0xfdfa, Hi: 0xfdfb, Stride: 1},
+		{Lo: 0xfe70, Hi: 0xfe7e, Stride: 2},
+		{Lo: 0xff9e, Hi: 0xff9f, Stride: 1},
+	},
+}
+
+var notXIDContinue = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x037a, Hi: 0x037a, Stride: 1},
+		{Lo: 0x309b, Hi: 0x309c, Stride: 1},
+		{Lo: 0xfc5e, Hi: 0xfc63, Stride: 1},
+		{Lo: 0xfdfa, Hi: 0xfdfb, Stride: 1},
+		{Lo: 0xfe70, Hi: 0xfe7e, Stride: 2},
+	},
 }
 
 func (s *Scanner) matchSet(set string) bool {
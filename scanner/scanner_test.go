@@ -1,13 +1,18 @@
 package scanner_test
 
 import (
+	"errors"
+	"io"
 	"jingle/scanner"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestScanner(t *testing.T) {
 	s := scanner.New("", `a let foobar = 1.5
-	ged.b = 12.; gab[f]=3
+	ged.b = 1.2; gab[f]=3
 
 
 !===*/
@@ -26,49 +31,522 @@ nil == false`)
 	}
 	t.Logf("%v", s.Tokens())
 	expected := []scanner.Token{
-		{scanner.TokenIdent, "a", 1, 1},
-		{scanner.TokenLet, "let", 1, 3},
-		{scanner.TokenIdent, "foobar", 1, 7},
-		{scanner.TokenSet, "=", 1, 14},
-		{scanner.TokenNumber, "1.5", 1, 16},
-		{scanner.TokenSeparator, "\n\t", 1, 19},
-		{scanner.TokenIdent, "ged", 2, 2},
-		{scanner.TokenDot, ".", 2, 5},
-		{scanner.TokenIdent, "b", 2, 6},
-		{scanner.TokenSet, "=", 2, 8},
-		{scanner.TokenNumber, "12.", 2, 10},
-		{scanner.TokenSeparator, "; ", 2, 13},
-		{scanner.TokenIdent, "gab", 2, 15},
-		{scanner.TokenLBracket, "[", 2, 18},
-		{scanner.TokenIdent, "f", 2, 19},
-		{scanner.TokenRBracket, "]", 2, 20},
-		{scanner.TokenSet, "=", 2, 21},
-		{scanner.TokenNumber, "3", 2, 22},
-		{scanner.TokenSeparator, "\n\n\n", 2, 23},
-		{scanner.TokenNeq, "!=", 5, 1},
-		{scanner.TokenEq, "==", 5, 3},
-		{scanner.TokenMul, "*", 5, 5},
-		{scanner.TokenDiv, "/", 5, 6},
-		{scanner.TokenSeparator, "\n", 5, 7},
-		{scanner.TokenFn, "fn", 6, 1},
-		{scanner.TokenLParen, "(", 6, 3},
-		{scanner.TokenRParen, ")", 6, 4},
-		{scanner.TokenEnd, "end", 6, 6},
-		{scanner.TokenSeparator, "\n", 6, 9},
-		{scanner.TokenString, "abcdef", 7, 1},
-		{scanner.TokenString, "ghi", 7, 9},
-		{scanner.TokenString, "jkl\n\t\r\u0000", 7, 14},
-		{scanner.TokenSeparator, "\n", 7, 27},
-		{scanner.TokenNil, "nil", 8, 1},
-		{scanner.TokenEq, "==", 8, 5},
-		{scanner.TokenBoolean, "false", 8, 8},
-		{scanner.TokenEOF, "", 8, 13},
+		{Type: scanner.TokenIdent, Value: "a", LineNo: 1, Column: 1},
+		{Type: scanner.TokenLet, Value: "let", LineNo: 1, Column: 3},
+		{Type: scanner.TokenIdent, Value: "foobar", LineNo: 1, Column: 7},
+		{Type: scanner.TokenSet, Value: "=", LineNo: 1, Column: 14},
+		{Type: scanner.TokenFloat, Value: "1.5", LineNo: 1, Column: 16},
+		{Type: scanner.TokenSeparator, Value: "\n\t", LineNo: 1, Column: 19},
+		{Type: scanner.TokenIdent, Value: "ged", LineNo: 2, Column: 2},
+		{Type: scanner.TokenDot, Value: ".", LineNo: 2, Column: 5},
+		{Type: scanner.TokenIdent, Value: "b", LineNo: 2, Column: 6},
+		{Type: scanner.TokenSet, Value: "=", LineNo: 2, Column: 8},
+		{Type: scanner.TokenFloat, Value: "1.2", LineNo: 2, Column: 10},
+		{Type: scanner.TokenSeparator, Value: "; ", LineNo: 2, Column: 13},
+		{Type: scanner.TokenIdent, Value: "gab", LineNo: 2, Column: 15},
+		{Type: scanner.TokenLBracket, Value: "[", LineNo: 2, Column: 18},
+		{Type: scanner.TokenIdent, Value: "f", LineNo: 2, Column: 19},
+		{Type: scanner.TokenRBracket, Value: "]", LineNo: 2, Column: 20},
+		{Type: scanner.TokenSet, Value: "=", LineNo: 2, Column: 21},
+		{Type: scanner.TokenInteger, Value: "3", LineNo: 2, Column: 22},
+		{Type: scanner.TokenSeparator, Value: "\n\n\n", LineNo: 2, Column: 23},
+		{Type: scanner.TokenNeq, Value: "!=", LineNo: 5, Column: 1},
+		{Type: scanner.TokenEq, Value: "==", LineNo: 5, Column: 3},
+		{Type: scanner.TokenMul, Value: "*", LineNo: 5, Column: 5},
+		{Type: scanner.TokenDiv, Value: "/", LineNo: 5, Column: 6},
+		{Type: scanner.TokenSeparator, Value: "\n", LineNo: 5, Column: 7},
+		{Type: scanner.TokenFn, Value: "fn", LineNo: 6, Column: 1},
+		{Type: scanner.TokenLParen, Value: "(", LineNo: 6, Column: 3},
+		{Type: scanner.TokenRParen, Value: ")", LineNo: 6, Column: 4},
+		{Type: scanner.TokenEnd, Value: "end", LineNo: 6, Column: 6},
+		{Type: scanner.TokenSeparator, Value: "\n", LineNo: 6, Column: 9},
+		{Type: scanner.TokenString, Value: "abcdef", LineNo: 7, Column: 1},
+		{Type: scanner.TokenString, Value: "ghi", LineNo: 7, Column: 9},
+		{Type: scanner.TokenString, Value: "jkl\n\t\r\u0000", LineNo: 7, Column: 14},
+		{Type: scanner.TokenSeparator, Value: "\n", LineNo: 7, Column: 27},
+		{Type: scanner.TokenNil, Value: "nil", LineNo: 8, Column: 1},
+		{Type: scanner.TokenEq, Value: "==", LineNo: 8, Column: 5},
+		{Type: scanner.TokenBoolean, Value: "false", LineNo: 8, Column: 8},
+		{Type: scanner.TokenEOF, Value: "", LineNo: 8, Column: 13},
 	}
 	tokens := s.Tokens()
 	for i, tok := range expected {
-		if tok != tokens[i] {
+		if tok != position(tokens[i]) {
 			t.Logf("expected=%+v, got=%+v", tok, tokens[i])
 			t.Fatalf("failed at index=%d", i)
 		}
 	}
 }
+
+func TestScanRanges(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []scanner.TokenType
+	}{
+		{"1..2", []scanner.TokenType{scanner.TokenInteger, scanner.TokenDotDot, scanner.TokenInteger}},
+		{"1...2", []scanner.TokenType{scanner.TokenInteger, scanner.TokenDotDotDot, scanner.TokenInteger}},
+		{"1.5..a", []scanner.TokenType{scanner.TokenFloat, scanner.TokenDotDot, scanner.TokenIdent}},
+		{"a..b.c", []scanner.TokenType{scanner.TokenIdent, scanner.TokenDotDot, scanner.TokenIdent, scanner.TokenDot, scanner.TokenIdent}},
+	}
+	for i, tt := range tests {
+		s := scanner.New("", tt.input)
+		s.ScanAll()
+		if s.Errors() != nil {
+			t.Fatalf("test[%d] unexpected errors: %v", i, s.Errors())
+		}
+		tokens := s.Tokens()
+		if len(tokens) != len(tt.expected)+1 {
+			t.Fatalf("test[%d] expected %d tokens, got=%v", i, len(tt.expected)+1, tokens)
+		}
+		for j, typ := range tt.expected {
+			if tokens[j].Type != typ {
+				t.Fatalf("test[%d] tokens[%d] expected=%s, got=%s", i, j, typ, tokens[j])
+			}
+		}
+	}
+}
+
+func TestScanNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"0 007 1_000_000 1.5", []string{"0", "007", "1_000_000", "1.5"}},
+		{"0xFF 0Xab_cd 0o17 0b1010", []string{"0xFF", "0Xab_cd", "0o17", "0b1010"}},
+		{"1e10 1.5e-3 2E+2 1_0e1_0", []string{"1e10", "1.5e-3", "2E+2", "1_0e1_0"}},
+		{"2.foo", []string{"2", ".", "foo"}},
+		{"2.5.foo", []string{"2.5", ".", "foo"}},
+		{"2.e5", []string{"2", ".", "e5"}},
+		{"1..2", []string{"1", "..", "2"}},
+	}
+	for i, tt := range tests {
+		s := scanner.New("", tt.input)
+		s.ScanAll()
+		if s.Errors() != nil {
+			t.Fatalf("test[%d] unexpected errors: %v", i, s.Errors())
+		}
+		tokens := s.Tokens()
+		if len(tokens) != len(tt.expected)+1 {
+			t.Fatalf("test[%d] expected %d tokens, got=%v", i, len(tt.expected)+1, tokens)
+		}
+		for j, value := range tt.expected {
+			if tokens[j].Value != value {
+				t.Fatalf("test[%d] tokens[%d] expected=%q, got=%q", i, j, value, tokens[j].Value)
+			}
+		}
+	}
+}
+
+func TestScanNumberErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		msg    string
+		column int
+	}{
+		{"x = 0x", "hexadecimal literal has no digits", 7},
+		{"0o", "octal literal has no digits", 3},
+		{"0b102", "invalid digit '2' in binary literal", 5},
+		{"0o8", "invalid digit '8' in octal literal", 3},
+		{"0b1f", "invalid digit 'f' in binary literal", 4},
+		{"0xfg", "invalid character 'g' in hexadecimal literal", 4},
+		{"12abc", "invalid character 'a' in number literal", 3},
+		{"0x_1", "'_' must separate successive digits", 3},
+		{"1__000", "'_' must separate successive digits", 2},
+		{"1000_", "'_' must separate successive digits", 5},
+		{"1_.5", "'_' must separate successive digits", 2},
+		{"1e", "exponent has no digits", 3},
+		{"1.5e+x", "exponent has no digits", 6},
+	}
+	for i, tt := range tests {
+		s := scanner.New("", tt.input)
+		s.ScanAll()
+		errs := s.Errors()
+		if len(errs) != 1 {
+			t.Fatalf("test[%d] expected 1 error, got=%v", i, errs)
+		}
+		err := errs[0].(scanner.Error)
+		if err.Message != tt.msg || err.Column != tt.column {
+			t.Fatalf("test[%d] expected=%d:%q, got=%d:%q", i, tt.column, tt.msg, err.Column, err.Message)
+		}
+	}
+}
+
+func TestScanIdentifiers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"größe = 1", []string{"größe", "=", "1"}},
+		{"имя_2 δx' _x", []string{"имя_2", "δx'", "_x"}},
+		{"名前.長さ", []string{"名前", ".", "長さ"}},
+		{"x\u0301", []string{"x\u0301"}},
+		// "cafe" followed by a combining acute accent is normalized to "café".
+		{"cafe\u0301 café", []string{"café", "café"}},
+		{"\u212bngstr\u00f6m", []string{"\u00c5ngstr\u00f6m"}},
+	}
+	for i, tt := range tests {
+		s := scanner.New("", unquote(t, tt.input))
+		s.ScanAll()
+		if s.Errors() != nil {
+			t.Fatalf("test[%d] unexpected errors: %v", i, s.Errors())
+		}
+		tokens := s.Tokens()
+		if len(tokens) != len(tt.expected)+1 {
+			t.Fatalf("test[%d] expected %d tokens, got=%v", i, len(tt.expected)+1, tokens)
+		}
+		for j, value := range tt.expected {
+			if tokens[j].Value != unquote(t, value) {
+				t.Fatalf("test[%d] tokens[%d] expected=%q, got=%q", i, j, unquote(t, value), tokens[j].Value)
+			}
+		}
+	}
+	for i, input := range []string{"\u00b7x", "x\u037a", "\u2160\u2161"} {
+		s := scanner.New("", unquote(t, input))
+		s.ScanAll()
+		tokens := s.Tokens()
+		ok := s.Errors() == nil && len(tokens) == 2 && tokens[0].Type == scanner.TokenIdent
+		if (i == 2) != ok {
+			t.Fatalf("invalid[%d] got=%v, %v", i, tokens, s.Errors())
+		}
+	}
+}
+
+func TestScanInvalidUTF8(t *testing.T) {
+	s := scanner.New("", "a = 1\nb \xff c \"d\xfee\"")
+	s.ScanAll()
+	expected := []scanner.Error{
+		{Message: "invalid UTF-8 byte 0xff", Value: "\xff", LineNo: 2, Column: 3, Offset: 8,
+			EndLineNo: 2, EndColumn: 4, EndOffset: 9},
+		{Message: "invalid UTF-8 byte 0xfe", Value: "\xfe", LineNo: 2, Column: 9, Offset: 14,
+			EndLineNo: 2, EndColumn: 10, EndOffset: 15},
+	}
+	errs := s.Errors()
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got=%v", len(expected), errs)
+	}
+	for i, err := range errs {
+		if err.(scanner.Error) != expected[i] {
+			t.Fatalf("errors[%d] expected=%+v, got=%+v", i, expected[i], err)
+		}
+	}
+	// scanning carries on after an invalid byte.
+	tokens := s.Tokens()
+	if n := len(tokens); n != 8 || tokens[n-2].Value != "d\ufffde" {
+		t.Fatalf("unexpected tokens: %v", tokens)
+	}
+}
+
+func TestScanInterpolatedString(t *testing.T) {
+	s := scanner.New("", `"a ${x + "${y}"} b ${{c: 1}["c"]}\${d}"
+"${
+  e
+}"`)
+	s.ScanAll()
+	if s.Errors() != nil {
+		t.Fatalf("unexpected errors: %v", s.Errors())
+	}
+	expected := []scanner.Token{
+		{Type: scanner.TokenStringStart, Value: "a ", LineNo: 1, Column: 1},
+		{Type: scanner.TokenIdent, Value: "x", LineNo: 1, Column: 6},
+		{Type: scanner.TokenPlus, Value: "+", LineNo: 1, Column: 8},
+		{Type: scanner.TokenStringStart, Value: "", LineNo: 1, Column: 10},
+		{Type: scanner.TokenIdent, Value: "y", LineNo: 1, Column: 13},
+		{Type: scanner.TokenStringEnd, Value: "", LineNo: 1, Column: 14},
+		{Type: scanner.TokenStringMiddle, Value: " b ", LineNo: 1, Column: 16},
+		{Type: scanner.TokenLBrace, Value: "{", LineNo: 1, Column: 22},
+		{Type: scanner.TokenIdent, Value: "c", LineNo: 1, Column: 23},
+		{Type: scanner.TokenColon, Value: ":", LineNo: 1, Column: 24},
+		{Type: scanner.TokenInteger, Value: "1", LineNo: 1, Column: 26},
+		{Type: scanner.TokenRBrace, Value: "}", LineNo: 1, Column: 27},
+		{Type: scanner.TokenLBracket, Value: "[", LineNo: 1, Column: 28},
+		{Type: scanner.TokenString, Value: "c", LineNo: 1, Column: 29},
+		{Type: scanner.TokenRBracket, Value: "]", LineNo: 1, Column: 32},
+		{Type: scanner.TokenStringEnd, Value: "${d}", LineNo: 1, Column: 33},
+		{Type: scanner.TokenSeparator, Value: "\n", LineNo: 1, Column: 40},
+		{Type: scanner.TokenStringStart, Value: "", LineNo: 2, Column: 1},
+		{Type: scanner.TokenIdent, Value: "e", LineNo: 3, Column: 3},
+		{Type: scanner.TokenStringEnd, Value: "", LineNo: 4, Column: 1},
+		{Type: scanner.TokenEOF, Value: "", LineNo: 4, Column: 3},
+	}
+	tokens := s.Tokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got=%v", len(expected), tokens)
+	}
+	for i, tok := range expected {
+		if tok != position(tokens[i]) {
+			t.Fatalf("tokens[%d] expected=%+v, got=%+v", i, tok, tokens[i])
+		}
+	}
+}
+
+func TestScanInterpolatedStringErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		msg    string
+		lineNo int
+		column int
+	}{
+		{`"a ${b`, "unexpected EOF in string interpolation", 1, 7},
+		{`"a ${b}`, "unexpected EOF in string literal", 1, 7},
+		{"\"a\n ${ b @ }\"", "unrecognised character U+0040: '@'", 2, 7},
+	}
+	for i, tt := range tests {
+		s := scanner.New("", tt.input)
+		s.ScanAll()
+		if s.Errors() == nil {
+			t.Fatalf("test[%d] expected an error", i)
+		}
+		err := s.Errors()[0].(scanner.Error)
+		if err.Message != tt.msg || err.LineNo != tt.lineNo || err.Column != tt.column {
+			t.Fatalf("test[%d] expected=%d:%d:%q, got=%d:%d:%q",
+				i, tt.lineNo, tt.column, tt.msg, err.LineNo, err.Column, err.Message)
+		}
+	}
+}
+
+// unquote interprets the escape sequences in s.
+func unquote(t *testing.T, s string) string {
+	u, err := strconv.Unquote(`"` + s + `"`)
+	if err != nil {
+		t.Fatalf("cannot unquote %q: %v", s, err)
+	}
+	return u
+}
+
+func TestScanComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []scanner.TokenType
+	}{
+		// a line comment does not swallow its newline.
+		{"a // c\nb", []scanner.TokenType{scanner.TokenIdent, scanner.TokenSeparator, scanner.TokenIdent}},
+		{"a // c\n// d\n\nb", []scanner.TokenType{scanner.TokenIdent, scanner.TokenSeparator, scanner.TokenIdent}},
+		{"a /* c */ b", []scanner.TokenType{scanner.TokenIdent, scanner.TokenIdent}},
+		{"a /* /* c */ d */ / b", []scanner.TokenType{scanner.TokenIdent, scanner.TokenDiv, scanner.TokenIdent}},
+		{"a /* c\n */ b", []scanner.TokenType{scanner.TokenIdent, scanner.TokenIdent}},
+		{"a /**/ * b", []scanner.TokenType{scanner.TokenIdent, scanner.TokenMul, scanner.TokenIdent}},
+	}
+	for i, tt := range tests {
+		s := scanner.New("", tt.input)
+		s.ScanAll()
+		if s.Errors() != nil {
+			t.Fatalf("test[%d] unexpected errors: %v", i, s.Errors())
+		}
+		tokens := s.Tokens()
+		if len(tokens) != len(tt.expected)+1 {
+			t.Fatalf("test[%d] expected %d tokens, got=%v", i, len(tt.expected)+1, tokens)
+		}
+		for j, typ := range tt.expected {
+			if tokens[j].Type != typ {
+				t.Fatalf("test[%d] tokens[%d] expected=%s, got=%s", i, j, typ, tokens[j])
+			}
+		}
+	}
+
+	s := scanner.New("", "a /* b /* c */\n")
+	s.ScanAll()
+	errs := s.Errors()
+	if len(errs) != 1 || errs[0].(scanner.Error).Message != "unexpected EOF in block comment" {
+		t.Fatalf("expected an unterminated comment error, got=%v", errs)
+	}
+}
+
+func TestScanTrivia(t *testing.T) {
+	input := `/// Doc
+//// not doc
+a // c
+/* d */ b
+/// e`
+	tests := []struct {
+		opts     []scanner.Option
+		expected map[int][]scanner.Token // token index -> comments
+	}{
+		{nil, map[int][]scanner.Token{
+			1: {{Type: scanner.TokenDocComment, Value: "/// Doc", LineNo: 1, Column: 1}},
+			5: {{Type: scanner.TokenDocComment, Value: "/// e", LineNo: 5, Column: 1}},
+		}},
+		{[]scanner.Option{scanner.KeepComments()}, map[int][]scanner.Token{
+			1: {
+				{Type: scanner.TokenDocComment, Value: "/// Doc", LineNo: 1, Column: 1},
+				{Type: scanner.TokenComment, Value: "//// not doc", LineNo: 2, Column: 1},
+			},
+			3: {
+				{Type: scanner.TokenComment, Value: "// c", LineNo: 3, Column: 3},
+				{Type: scanner.TokenComment, Value: "/* d */", LineNo: 4, Column: 1},
+			},
+			5: {{Type: scanner.TokenDocComment, Value: "/// e", LineNo: 5, Column: 1}},
+		}},
+	}
+	for i, tt := range tests {
+		s := scanner.New("", input, tt.opts...)
+		s.ScanAll()
+		if s.Errors() != nil {
+			t.Fatalf("test[%d] unexpected errors: %v", i, s.Errors())
+		}
+		// sep, a, sep, b, sep, EOF
+		tokens := s.Tokens()
+		if len(tokens) != 6 {
+			t.Fatalf("test[%d] expected 6 tokens, got=%v", i, tokens)
+		}
+		for j, tok := range tokens {
+			expected := tt.expected[j]
+			if tok.Trivia == nil {
+				if expected != nil {
+					t.Fatalf("test[%d] tokens[%d] expected trivia %v", i, j, expected)
+				}
+				continue
+			}
+			comments := tok.Trivia.Comments
+			if len(comments) != len(expected) {
+				t.Fatalf("test[%d] tokens[%d] expected=%v, got=%v", i, j, expected, comments)
+			}
+			for k, c := range comments {
+				if position(c) != expected[k] {
+					t.Fatalf("test[%d] tokens[%d] expected=%v, got=%v", i, j, expected, comments)
+				}
+			}
+		}
+	}
+}
+
+func TestScanReader(t *testing.T) {
+	input := `/// doc
+class A < B
+	def init(x) self.x = x end /* a /* nested */ comment */
+end
+let s = "a ${x + "${y}"} b \n"
+0x_ff 1.5e3 héllo
+"unterminated`
+	s := scanner.New("", input)
+	s.ScanAll()
+	expected := s.Tokens()
+	expectedErrs := s.Errors()
+
+	readers := map[string]func() io.Reader{
+		"one byte": func() io.Reader { return iotest.OneByteReader(strings.NewReader(input)) },
+		"half":     func() io.Reader { return iotest.HalfReader(strings.NewReader(input)) },
+		"data err": func() io.Reader { return iotest.DataErrReader(strings.NewReader(input)) },
+	}
+	for name, reader := range readers {
+		s := scanner.NewReader("", reader())
+		var tokens []scanner.Token
+		var errs []error
+		for {
+			tok, err := s.Next()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			tokens = append(tokens, tok)
+			if tok.Type == scanner.TokenEOF {
+				break
+			}
+		}
+		// ScanAll stops at the unterminated string, while Next
+		// carries on to give an EOF.
+		if len(tokens) != len(expected)+1 || tokens[len(tokens)-1].Type != scanner.TokenEOF {
+			t.Fatalf("%s: expected %d tokens, got=%v", name, len(expected)+1, tokens)
+		}
+		for i, tok := range expected {
+			if tok.Type != tokens[i].Type || tok.Value != tokens[i].Value ||
+				tok.LineNo != tokens[i].LineNo || tok.Column != tokens[i].Column {
+				t.Fatalf("%s: tokens[%d] expected=%v, got=%v", name, i, tok, tokens[i])
+			}
+		}
+		if len(errs) != len(expectedErrs) {
+			t.Fatalf("%s: expected errors %v, got=%v", name, expectedErrs, errs)
+		}
+		for i, err := range expectedErrs {
+			if err != errs[i] {
+				t.Fatalf("%s: errs[%d] expected=%v, got=%v", name, i, err, errs[i])
+			}
+		}
+		if tok, err := s.Next(); err != nil || tok.Type != scanner.TokenEOF {
+			t.Fatalf("%s: expected EOF again, got=%v, %v", name, tok, err)
+		}
+	}
+
+	// errors from the reader are passed on.
+	readErr := errors.New("read error")
+	s = scanner.NewReader("", io.MultiReader(strings.NewReader("a b"), iotest.ErrReader(readErr)))
+	var got []interface{}
+	for {
+		tok, err := s.Next()
+		if err != nil {
+			got = append(got, err)
+			continue
+		}
+		got = append(got, tok.Value)
+		if tok.Type == scanner.TokenEOF {
+			break
+		}
+	}
+	if len(got) != 4 || got[0] != readErr || got[1] != "a" || got[2] != "b" || got[3] != "" {
+		t.Fatalf("unexpected tokens and errors: %v", got)
+	}
+}
+
+func TestScanSpans(t *testing.T) {
+	input := "let s = \"a\\n${x}é\"\n\t/* c */ 1.5e3 >= héllo // d\n"
+	s := scanner.New("", input)
+	s.ScanAll()
+	if s.Errors() != nil {
+		t.Fatalf("unexpected errors: %v", s.Errors())
+	}
+	expected := []string{"let", "s", "=", "\"a\\n${", "x", "}é\"", "\n\t", "1.5e3", ">=", "héllo", "\n", ""}
+	tokens := s.Tokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got=%v", len(expected), tokens)
+	}
+	for i, tok := range tokens {
+		span := tok.Span()
+		if text := input[span.Start.Offset:span.End.Offset]; text != expected[i] {
+			t.Fatalf("tokens[%d] expected=%q, got=%q", i, expected[i], text)
+		}
+		for _, pos := range []scanner.Pos{span.Start, span.End} {
+			if lineNo, column := lineColumn(input, pos.Offset); pos.LineNo != lineNo || pos.Column != column {
+				t.Fatalf("tokens[%d] expected %d:%d at offset %d, got=%+v", i, lineNo, column, pos.Offset, pos)
+			}
+		}
+	}
+
+	// spans are the same when reading a little at a time.
+	r := scanner.NewReader("", iotest.OneByteReader(strings.NewReader(input)))
+	for i := range tokens {
+		tok, err := r.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tok.Span() != tokens[i].Span() {
+			t.Fatalf("tokens[%d] expected=%+v, got=%+v", i, tokens[i].Span(), tok.Span())
+		}
+	}
+
+	s = scanner.New("", "x = 0x1g2 + y")
+	s.ScanAll()
+	errs := s.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("expected an error, got=%v", errs)
+	}
+	if span := errs[0].(scanner.Error).Span(); span.Start.Offset != 7 || span.End.Offset != 9 || span.End.Column != 10 {
+		t.Fatalf("unexpected error span: %+v", span)
+	}
+}
+
+// position returns tok without its span, apart from its line and column.
+func position(tok scanner.Token) scanner.Token {
+	return scanner.Token{Type: tok.Type, Value: tok.Value, LineNo: tok.LineNo, Column: tok.Column}
+}
+
+// lineColumn returns the line and column of the given offset in input.
+func lineColumn(input string, offset int) (int, int) {
+	lineNo, column := 1, 1
+	for _, r := range input[:offset] {
+		if r == '\n' {
+			lineNo++
+			column = 0
+		}
+		column++
+	}
+	return lineNo, column
+}
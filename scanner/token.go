@@ -4,45 +4,103 @@ import "fmt"
 
 // Token represents a token returned from the scanner.
 type Token struct {
-	Type   TokenType
-	Value  string
+	Type      TokenType
+	Value     string
+	LineNo    int
+	Column    int
+	Offset    int
+	EndLineNo int
+	EndColumn int
+	EndOffset int
+	Trivia    *Trivia // comments before the token, if any
+}
+
+// Span returns the part of the input that the token was scanned from.
+func (t Token) Span() Span {
+	return Span{
+		Start: Pos{Offset: t.Offset, LineNo: t.LineNo, Column: t.Column},
+		End:   Pos{Offset: t.EndOffset, LineNo: t.EndLineNo, Column: t.EndColumn},
+	}
+}
+
+// Pos is a position in the input. Offset is a byte offset from 0,
+// while LineNo and Column count from 1; Column counts runes.
+type Pos struct {
+	Offset int
 	LineNo int
 	Column int
 }
 
+// Span is a part of the input, from Start up to but not including End.
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+// Join returns the smallest span that covers both s and other.
+func (s Span) Join(other Span) Span {
+	if other.Start.Offset < s.Start.Offset {
+		s.Start = other.Start
+	}
+	if other.End.Offset > s.End.Offset {
+		s.End = other.End
+	}
+	return s
+}
+
+// Trivia holds the comments that precede a token. Doc comments
+// (starting with ///) are always kept; other comments only if the
+// scanner was created with KeepComments.
+type Trivia struct {
+	Comments []Token // TokenComment or TokenDocComment tokens
+}
+
 func (t Token) String() string {
 	return fmt.Sprintf("%s(%d:%d:%q)", t.Type, t.LineNo, t.Column, t.Value)
 }
 
-//go:generate stringer -type=TokenType
+//go:generate go run golang.org/x/tools/cmd/stringer@v0.47.0 -type=TokenType
 type TokenType int
 
 const (
 	TokenEOF = TokenType(iota) // signals that EOF is reached.
 	// Keywords
-	TokenOr     // 'or'
-	TokenAnd    // 'and'
-	TokenFn     // 'fn'
-	TokenEnd    // 'end'
-	TokenFor    // 'for'
-	TokenWhile  // 'while'
-	TokenIn     // 'in'
-	TokenDo     // 'do'
-	TokenIf     // 'if'
-	TokenThen   // 'then'
-	TokenElse   // 'else'
-	TokenLet    // 'let'
-	TokenClass  // 'class'
-	TokenDef    // 'def'
-	TokenReturn // 'return'
+	TokenOr       // 'or'
+	TokenAnd      // 'and'
+	TokenFn       // 'fn'
+	TokenEnd      // 'end'
+	TokenFor      // 'for'
+	TokenWhile    // 'while'
+	TokenIn       // 'in'
+	TokenDo       // 'do'
+	TokenIf       // 'if'
+	TokenThen     // 'then'
+	TokenElse     // 'else'
+	TokenLet      // 'let'
+	TokenClass    // 'class'
+	TokenDef      // 'def'
+	TokenReturn   // 'return'
+	TokenTry      // 'try'
+	TokenCatch    // 'catch'
+	TokenFinally  // 'finally'
+	TokenRaise    // 'raise'
+	TokenBreak    // 'break'
+	TokenContinue // 'continue'
 	// Literals
 	TokenNil     // nil
 	TokenBoolean // true or false
 	TokenString  // a string literal
-	TokenNumber  // floating point number (yuck)
+	// Interpolated strings: "a${x}b${y}c" is scanned as StringStart("a"),
+	// x, StringMiddle("b"), y, StringEnd("c").
+	TokenStringStart
+	TokenStringMiddle
+	TokenStringEnd
+	TokenInteger // integer literal
+	TokenFloat   // floating point literal
 	TokenIdent   // identifier
 	// Delimiters
 	TokenComma     // ','
+	TokenColon     // ':'
 	TokenSeparator // any newlines (we are whitespace sensitive)
 	TokenLParen    // '('
 	TokenRParen    // ')'
@@ -51,38 +109,59 @@ const (
 	TokenLBracket  // '['
 	TokenRBracket  // ']'
 	// Operators
-	TokenBang  // '!'
-	TokenDot   // '.'
-	TokenPlus  // '+'
-	TokenMinus // '-'
-	TokenMul   // '*'
-	TokenDiv   // '/'
-	TokenSet   // '='
-	TokenEq    // '=='
-	TokenNeq   // '!='
-	TokenLt    // '<'
-	TokenGt    // '>'
-	TokenLeq   // '<='
-	TokenGeq   // '>='
+	TokenBang      // '!'
+	TokenDot       // '.'
+	TokenDotDot    // '..'
+	TokenDotDotDot // '...'
+	TokenPlus      // '+'
+	TokenMinus     // '-'
+	TokenMul       // '*'
+	TokenDiv       // '/'
+	TokenMod       // '%'
+	TokenPow       // '**'
+	TokenAmp       // '&'
+	TokenPipe      // '|'
+	TokenCaret     // '^'
+	TokenTilde     // '~'
+	TokenShl       // '<<'
+	TokenShr       // '>>'
+	TokenSet       // '='
+	TokenEq        // '=='
+	TokenNeq       // '!='
+	TokenLt        // '<'
+	TokenGt        // '>'
+	TokenLeq       // '<='
+	TokenGeq       // '>='
+	// Comments, which are only found in Trivia
+	TokenComment    // '//' or '/* */' comment
+	TokenDocComment // '///' comment
+	// Text that could not be scanned, only found in errors
+	TokenIllegal
 )
 
 var keywords = map[string]TokenType{
-	"or":     TokenOr,
-	"and":    TokenAnd,
-	"fn":     TokenFn,
-	"end":    TokenEnd,
-	"for":    TokenFor,
-	"while":  TokenWhile,
-	"in":     TokenIn,
-	"do":     TokenDo,
-	"if":     TokenIf,
-	"then":   TokenThen,
-	"else":   TokenElse,
-	"let":    TokenLet,
-	"class":  TokenClass,
-	"def":    TokenDef,
-	"return": TokenReturn,
-	"nil":    TokenNil,
-	"true":   TokenBoolean,
-	"false":  TokenBoolean,
+	"or":       TokenOr,
+	"and":      TokenAnd,
+	"fn":       TokenFn,
+	"end":      TokenEnd,
+	"for":      TokenFor,
+	"while":    TokenWhile,
+	"in":       TokenIn,
+	"do":       TokenDo,
+	"if":       TokenIf,
+	"then":     TokenThen,
+	"else":     TokenElse,
+	"let":      TokenLet,
+	"class":    TokenClass,
+	"def":      TokenDef,
+	"return":   TokenReturn,
+	"try":      TokenTry,
+	"catch":    TokenCatch,
+	"finally":  TokenFinally,
+	"raise":    TokenRaise,
+	"break":    TokenBreak,
+	"continue": TokenContinue,
+	"nil":      TokenNil,
+	"true":     TokenBoolean,
+	"false":    TokenBoolean,
 }
@@ -24,41 +24,66 @@ func _() {
 	_ = x[TokenClass-13]
 	_ = x[TokenDef-14]
 	_ = x[TokenReturn-15]
-	_ = x[TokenNil-16]
-	_ = x[TokenBoolean-17]
-	_ = x[TokenString-18]
-	_ = x[TokenNumber-19]
-	_ = x[TokenIdent-20]
-	_ = x[TokenComma-21]
-	_ = x[TokenSeparator-22]
-	_ = x[TokenLParen-23]
-	_ = x[TokenRParen-24]
-	_ = x[TokenLBrace-25]
-	_ = x[TokenRBrace-26]
-	_ = x[TokenLBracket-27]
-	_ = x[TokenRBracket-28]
-	_ = x[TokenBang-29]
-	_ = x[TokenDot-30]
-	_ = x[TokenPlus-31]
-	_ = x[TokenMinus-32]
-	_ = x[TokenMul-33]
-	_ = x[TokenDiv-34]
-	_ = x[TokenSet-35]
-	_ = x[TokenEq-36]
-	_ = x[TokenNeq-37]
-	_ = x[TokenLt-38]
-	_ = x[TokenGt-39]
-	_ = x[TokenLeq-40]
-	_ = x[TokenGeq-41]
+	_ = x[TokenTry-16]
+	_ = x[TokenCatch-17]
+	_ = x[TokenFinally-18]
+	_ = x[TokenRaise-19]
+	_ = x[TokenBreak-20]
+	_ = x[TokenContinue-21]
+	_ = x[TokenNil-22]
+	_ = x[TokenBoolean-23]
+	_ = x[TokenString-24]
+	_ = x[TokenStringStart-25]
+	_ = x[TokenStringMiddle-26]
+	_ = x[TokenStringEnd-27]
+	_ = x[TokenInteger-28]
+	_ = x[TokenFloat-29]
+	_ = x[TokenIdent-30]
+	_ = x[TokenComma-31]
+	_ = x[TokenColon-32]
+	_ = x[TokenSeparator-33]
+	_ = x[TokenLParen-34]
+	_ = x[TokenRParen-35]
+	_ = x[TokenLBrace-36]
+	_ = x[TokenRBrace-37]
+	_ = x[TokenLBracket-38]
+	_ = x[TokenRBracket-39]
+	_ = x[TokenBang-40]
+	_ = x[TokenDot-41]
+	_ = x[TokenDotDot-42]
+	_ = x[TokenDotDotDot-43]
+	_ = x[TokenPlus-44]
+	_ = x[TokenMinus-45]
+	_ = x[TokenMul-46]
+	_ = x[TokenDiv-47]
+	_ = x[TokenMod-48]
+	_ = x[TokenPow-49]
+	_ = x[TokenAmp-50]
+	_ = x[TokenPipe-51]
+	_ = x[TokenCaret-52]
+	_ = x[TokenTilde-53]
+	_ = x[TokenShl-54]
+	_ = x[TokenShr-55]
+	_ = x[TokenSet-56]
+	_ = x[TokenEq-57]
+	_ = x[TokenNeq-58]
+	_ = x[TokenLt-59]
+	_ = x[TokenGt-60]
+	_ = x[TokenLeq-61]
+	_ = x[TokenGeq-62]
+	_ = x[TokenComment-63]
+	_ = x[TokenDocComment-64]
+	_ = x[TokenIllegal-65]
 }
 
-const _TokenType_name = "TokenEOFTokenOrTokenAndTokenFnTokenEndTokenForTokenWhileTokenInTokenDoTokenIfTokenThenTokenElseTokenLetTokenClassTokenDefTokenReturnTokenNilTokenBooleanTokenStringTokenNumberTokenIdentTokenCommaTokenSeparatorTokenLParenTokenRParenTokenLBraceTokenRBraceTokenLBracketTokenRBracketTokenBangTokenDotTokenPlusTokenMinusTokenMulTokenDivTokenSetTokenEqTokenNeqTokenLtTokenGtTokenLeqTokenGeq"
+const _TokenType_name = "TokenEOFTokenOrTokenAndTokenFnTokenEndTokenForTokenWhileTokenInTokenDoTokenIfTokenThenTokenElseTokenLetTokenClassTokenDefTokenReturnTokenTryTokenCatchTokenFinallyTokenRaiseTokenBreakTokenContinueTokenNilTokenBooleanTokenStringTokenStringStartTokenStringMiddleTokenStringEndTokenIntegerTokenFloatTokenIdentTokenCommaTokenColonTokenSeparatorTokenLParenTokenRParenTokenLBraceTokenRBraceTokenLBracketTokenRBracketTokenBangTokenDotTokenDotDotTokenDotDotDotTokenPlusTokenMinusTokenMulTokenDivTokenModTokenPowTokenAmpTokenPipeTokenCaretTokenTildeTokenShlTokenShrTokenSetTokenEqTokenNeqTokenLtTokenGtTokenLeqTokenGeqTokenCommentTokenDocCommentTokenIllegal"
 
-var _TokenType_index = [...]uint16{0, 8, 15, 23, 30, 38, 46, 56, 63, 70, 77, 86, 95, 103, 113, 121, 132, 140, 152, 163, 174, 184, 194, 208, 219, 230, 241, 252, 265, 278, 287, 295, 304, 314, 322, 330, 338, 345, 353, 360, 367, 375, 383}
+var _TokenType_index = [...]uint16{0, 8, 15, 23, 30, 38, 46, 56, 63, 70, 77, 86, 95, 103, 113, 121, 132, 140, 150, 162, 172, 182, 195, 203, 215, 226, 242, 259, 273, 285, 295, 305, 315, 325, 339, 350, 361, 372, 383, 396, 409, 418, 426, 437, 451, 460, 470, 478, 486, 494, 502, 510, 519, 529, 539, 547, 555, 563, 570, 578, 585, 592, 600, 608, 620, 635, 647}
 
 func (i TokenType) String() string {
-	if i < 0 || i >= TokenType(len(_TokenType_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_TokenType_index)-1 {
 		return "TokenType(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _TokenType_name[_TokenType_index[i]:_TokenType_index[i+1]]
+	return _TokenType_name[_TokenType_index[idx]:_TokenType_index[idx+1]]
 }
@@ -10,14 +10,26 @@ import (
 
 type ASTIdent struct{ Name string }
 type ASTNil struct{}
-type ASTNumber struct{ Value float64 }
+type ASTInteger struct{ Value int64 }
+type ASTFloat struct{ Value float64 }
 type ASTString struct{ Value string }
 type ASTBoolean struct{ Value bool }
 type ASTArray []interface{}
+type ASTMap []ASTMapEntry
+type ASTMapEntry struct {
+	Key      interface{}
+	Value    interface{}
+	Computed bool
+}
 type ASTAssign struct {
 	Left  interface{}
 	Right interface{}
 }
+type ASTInfix struct {
+	Left  interface{}
+	Op    string
+	Right interface{}
+}
 
 func TestNode(t *testing.T, node ast.Node, v interface{}) bool {
 	switch v := v.(type) {
@@ -25,8 +37,10 @@ func TestNode(t *testing.T, node ast.Node, v interface{}) bool {
 		return TestIdentifierLiteral(t, node, v)
 	case ASTNil:
 		return TestNullLiteral(t, node)
-	case ASTNumber:
-		return TestNumberLiteral(t, node, v)
+	case ASTInteger:
+		return TestIntegerLiteral(t, node, v)
+	case ASTFloat:
+		return TestFloatLiteral(t, node, v)
 	case ASTString:
 		return TestStringLiteral(t, node, v)
 	case ASTBoolean:
@@ -35,6 +49,10 @@ func TestNode(t *testing.T, node ast.Node, v interface{}) bool {
 		return TestAssignmentExpression(t, node, v)
 	case ASTArray:
 		return TestArrayLiteral(t, node, v)
+	case ASTMap:
+		return TestMapLiteral(t, node, v)
+	case ASTInfix:
+		return TestInfixExpression(t, node, v.Left, v.Op, v.Right)
 	}
 	panic("unhandled type")
 }
@@ -43,6 +61,132 @@ func TestNode(t *testing.T, node ast.Node, v interface{}) bool {
 // Statements
 // ==========
 
+func TestLetStatement(t *testing.T, node ast.Node, binding interface{}) bool {
+	if !TestNodeType(t, node, ast.LET_STATEMENT) {
+		return false
+	}
+	stmt := node.(*ast.LetStatement)
+	if !testTokenType(t, stmt.Token, scanner.TokenLet) {
+		return false
+	}
+	return TestNode(t, stmt.Binding, binding)
+}
+
+func TestWhileStatement(t *testing.T, node ast.Node, cond interface{}, bodyLen int) bool {
+	if !TestNodeType(t, node, ast.WHILE_STATEMENT) {
+		return false
+	}
+	stmt := node.(*ast.WhileStatement)
+	if !testTokenType(t, stmt.Token, scanner.TokenWhile) {
+		return false
+	}
+	return TestNode(t, stmt.Condition, cond) && TestBlock(t, stmt.Body, bodyLen)
+}
+
+func TestForStatement(t *testing.T, node ast.Node, binding interface{}, iterable interface{}, bodyLen int) bool {
+	if !TestNodeType(t, node, ast.FOR_STATEMENT) {
+		return false
+	}
+	stmt := node.(*ast.ForStatement)
+	if !testTokenType(t, stmt.Token, scanner.TokenFor) {
+		return false
+	}
+	return TestNode(t, stmt.Binding, binding) &&
+		TestNode(t, stmt.Iterable, iterable) &&
+		TestBlock(t, stmt.Body, bodyLen)
+}
+
+// TestIfStatement tests an if statement. elseLen should be -1
+// if the statement is not expected to have an else block.
+func TestIfStatement(t *testing.T, node ast.Node, cond interface{}, thenLen int, elseLen int) bool {
+	if !TestNodeType(t, node, ast.IF_STATEMENT) {
+		return false
+	}
+	stmt := node.(*ast.IfStatement)
+	if !testTokenType(t, stmt.Token, scanner.TokenIf) {
+		return false
+	}
+	if !TestNode(t, stmt.Cond, cond) || !TestBlock(t, stmt.Then, thenLen) {
+		return false
+	}
+	if elseLen < 0 {
+		if stmt.Else != nil {
+			t.Errorf("expected no else block, got=%s", stmt.Else)
+			return false
+		}
+		return true
+	}
+	if stmt.Else == nil {
+		t.Errorf("expected an else block, got nil")
+		return false
+	}
+	return TestBlock(t, stmt.Else, elseLen)
+}
+
+func TestReturnStatement(t *testing.T, node ast.Node, expr interface{}) bool {
+	if !TestNodeType(t, node, ast.RETURN_STATEMENT) {
+		return false
+	}
+	stmt := node.(*ast.ReturnStatement)
+	if !testTokenType(t, stmt.Token, scanner.TokenReturn) {
+		return false
+	}
+	return TestNode(t, stmt.Expr, expr)
+}
+
+// TestClassStatement tests a class statement. super should be nil
+// if the class is not expected to have a superclass.
+func TestClassStatement(t *testing.T, node ast.Node, name string, super interface{}, methods []string) bool {
+	if !TestNodeType(t, node, ast.CLASS_STATEMENT) {
+		return false
+	}
+	stmt := node.(*ast.ClassStatement)
+	if !testTokenType(t, stmt.Token, scanner.TokenClass) {
+		return false
+	}
+	if !TestIdentifierLiteral(t, stmt.Name, ASTIdent{Name: name}) {
+		return false
+	}
+	if super == nil {
+		if stmt.SuperClass != nil {
+			t.Errorf("expected no superclass, got=%s", stmt.SuperClass)
+			return false
+		}
+	} else if !TestNode(t, stmt.SuperClass, super) {
+		return false
+	}
+	i := 0
+	for _, s := range stmt.Body.Statements {
+		meth, ok := s.(*ast.MethodDeclaration)
+		if !ok {
+			continue
+		}
+		if i >= len(methods) || meth.MethodName.Name != methods[i] {
+			t.Errorf("unexpected method %q, expected=%q", meth.MethodName.Name, methods)
+			return false
+		}
+		i++
+	}
+	if i != len(methods) {
+		t.Errorf("expected methods=%q, got %d of them", methods, i)
+		return false
+	}
+	return true
+}
+
+func TestBlock(t *testing.T, node ast.Node, length int) bool {
+	if !TestNodeType(t, node, ast.BLOCK_STATEMENT) {
+		return false
+	}
+	block := node.(*ast.Block)
+	if len(block.Statements) != length {
+		t.Errorf("invalid no. of statements. expected=%d, got=%d",
+			length, len(block.Statements))
+		return false
+	}
+	return true
+}
+
 // ===========
 // Expressions
 // ===========
@@ -122,16 +266,31 @@ func TestNullLiteral(t *testing.T, node ast.Node) bool {
 	return true
 }
 
-func TestNumberLiteral(t *testing.T, node ast.Node, v ASTNumber) bool {
-	if !TestNodeType(t, node, ast.NUMBER_LITERAL) {
+func TestIntegerLiteral(t *testing.T, node ast.Node, v ASTInteger) bool {
+	if !TestNodeType(t, node, ast.INTEGER_LITERAL) {
 		return false
 	}
-	number := node.(*ast.NumberLiteral)
-	if !testTokenType(t, number.Token, scanner.TokenNumber) {
+	number := node.(*ast.IntegerLiteral)
+	if !testTokenType(t, number.Token, scanner.TokenInteger) {
+		return false
+	}
+	if !number.Value.IsInt64() || number.Value.Int64() != v.Value {
+		t.Errorf("invalid node.Value. expected=%d, got=%s", v.Value, number.Value)
+		return false
+	}
+	return true
+}
+
+func TestFloatLiteral(t *testing.T, node ast.Node, v ASTFloat) bool {
+	if !TestNodeType(t, node, ast.FLOAT_LITERAL) {
+		return false
+	}
+	number := node.(*ast.FloatLiteral)
+	if !testTokenType(t, number.Token, scanner.TokenFloat) {
 		return false
 	}
 	if number.Value != v.Value {
-		t.Errorf("invalid node.Value. expected=%f, got=%f", v, number.Value)
+		t.Errorf("invalid node.Value. expected=%f, got=%f", v.Value, number.Value)
 		return false
 	}
 	return true
@@ -190,6 +349,34 @@ func TestArrayLiteral(t *testing.T, node ast.Node, v ASTArray) bool {
 	return true
 }
 
+func TestMapLiteral(t *testing.T, node ast.Node, v ASTMap) bool {
+	if !TestNodeType(t, node, ast.MAP_LITERAL) {
+		return false
+	}
+	expr := node.(*ast.MapLiteral)
+	if !testTokenType(t, expr.Token, scanner.TokenLBrace) {
+		return false
+	}
+	if len(v) != len(expr.Entries) {
+		t.Errorf("invalid no. of entries. expected=%d, got=%d",
+			len(v), len(expr.Entries))
+		return false
+	}
+	for i, entry := range expr.Entries {
+		if entry.Computed != v[i].Computed {
+			t.Errorf("entries[%d].Computed expected=%t, got=%t",
+				i, v[i].Computed, entry.Computed)
+			return false
+		}
+		if !TestNode(t, entry.Key, v[i].Key) || !TestNode(t, entry.Value, v[i].Value) {
+			t.Errorf("entries[%d] expected=%+v, got=%s",
+				i, v[i], entry)
+			return false
+		}
+	}
+	return true
+}
+
 // ===============
 // utils for utils
 // ===============